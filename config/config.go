@@ -15,6 +15,8 @@ type Config struct {
 	Database DatabaseConfig `yaml:"database"`
 	Redis    RedisConfig    `yaml:"redis"`
 	Logging  LoggingConfig  `yaml:"logging"`
+	Fare     FareConfig     `yaml:"fare"`
+	GTFS     GTFSConfig     `yaml:"gtfs"`
 }
 
 type ServerConfig struct {
@@ -49,6 +51,35 @@ type LoggingConfig struct {
 	Format string `yaml:"format"`
 }
 
+// FareConfig 计费配置
+type FareConfig struct {
+	RoundingMode string `yaml:"rounding_mode"` // 默认舍入模式：down(向下), half_up(四舍五入), half_even(银行家舍入)
+	RoundingUnit int64  `yaml:"rounding_unit"` // 默认实收金额舍入单位（分），如10表示舍入到角，默认1
+
+	Operators map[string]OperatorFareConfig `yaml:"operators"` // 按运营方配置的舍入规则（键为线路的operator，未配置的运营方使用默认值）
+
+	DefaultMaxRideMinutes int  `yaml:"default_max_ride_minutes"` // single_tap线路默认最长乘车时间（分钟，用于估算下车时间），默认60
+	InferAlightStation    bool `yaml:"infer_alight_station"`     // single_tap上一程是否推断下车站点（换乘上车站在上一程线路上时视为在该站下车）
+
+	MaxPassengersPerRecord int `yaml:"max_passengers_per_record"` // 网关单条乘车记录最多乘客人数（含持卡人），默认10
+}
+
+// OperatorFareConfig 运营方计费配置
+type OperatorFareConfig struct {
+	RoundingMode string `yaml:"rounding_mode"` // 舍入模式（为空时使用默认舍入模式）
+	RoundingUnit int64  `yaml:"rounding_unit"` // 实收金额舍入单位（分，为0时使用默认舍入单位）
+}
+
+// GTFSConfig GTFS导出的运营机构信息
+type GTFSConfig struct {
+	AgencyID       string `yaml:"agency_id"`       // 运营机构ID，默认"TapTransit"
+	AgencyName     string `yaml:"agency_name"`     // 运营机构名称
+	AgencyURL      string `yaml:"agency_url"`      // 运营机构网址
+	AgencyTimezone string `yaml:"agency_timezone"` // 时区，默认Asia/Shanghai
+	AgencyLang     string `yaml:"agency_lang"`     // 语言，默认zh
+	Currency       string `yaml:"currency"`        // 票价币种，默认CNY
+}
+
 var AppConfig *Config
 
 // LoadConfig 加载配置文件
@@ -81,13 +112,13 @@ func (r *RedisConfig) GetRedisAddr() string {
 // InitDB 初始化数据库连接
 func InitDB(config *DatabaseConfig) (*gorm.DB, error) {
 	dsn := config.GetDSN()
-	
+
 	// 配置GORM选项
 	gormConfig := &gorm.Config{
 		// 禁用外键约束检查（迁移时），让GORM自动处理外键创建顺序
 		DisableForeignKeyConstraintWhenMigrating: false, // 保持为false，让GORM处理
 	}
-	
+
 	db, err := gorm.Open(postgres.Open(dsn), gormConfig)
 	if err != nil {
 		return nil, fmt.Errorf("连接数据库失败: %w", err)
@@ -100,7 +131,7 @@ func InitDB(config *DatabaseConfig) (*gorm.DB, error) {
 
 	sqlDB.SetMaxOpenConns(config.MaxOpenConns)
 	sqlDB.SetMaxIdleConns(config.MaxIdleConns)
-	
+
 	if config.ConnMaxLifetime > 0 {
 		sqlDB.SetConnMaxLifetime(time.Duration(config.ConnMaxLifetime) * time.Second)
 	}
@@ -3,7 +3,9 @@ package controllers
 import (
 	"TapTransit-backend/models"
 	"TapTransit-backend/utils"
-	"fmt"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -32,7 +34,10 @@ type loginResponse struct {
 	User  loginUser `json:"user"`
 }
 
-// Login 简单登录（开发用，明文密码）
+// sessionTTL 登录令牌有效期
+const sessionTTL = 24 * time.Hour
+
+// Login 简单登录（开发用，明文密码），签发的令牌通过 Authorization: Bearer <token> 访问需要登录的接口
 func (a *AuthController) Login(ctx *gin.Context) {
 	var req loginRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -51,8 +56,30 @@ func (a *AuthController) Login(ctx *gin.Context) {
 		return
 	}
 
+	if user.Status != "active" {
+		utils.Unauthorized(ctx, "用户已停用")
+		return
+	}
+
+	// 签发访问令牌
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		utils.InternalServerError(ctx, "生成令牌失败")
+		return
+	}
+	token := hex.EncodeToString(tokenBytes)
+	session := models.UserSession{
+		TokenHash: utils.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(sessionTTL),
+	}
+	if err := utils.DB.Create(&session).Error; err != nil {
+		utils.InternalServerError(ctx, "保存登录会话失败")
+		return
+	}
+
 	resp := loginResponse{
-		Token: fmt.Sprintf("dev_token_%d", time.Now().Unix()),
+		Token: token,
 		User: loginUser{
 			ID:       user.ID,
 			Username: user.Username,
@@ -63,7 +90,11 @@ func (a *AuthController) Login(ctx *gin.Context) {
 	utils.Success(ctx, resp)
 }
 
-// Logout 登出（占位）
+// Logout 登出（使当前令牌失效）
 func (a *AuthController) Logout(ctx *gin.Context) {
+	token := strings.TrimSpace(strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer "))
+	if token != "" {
+		utils.DB.Where("token_hash = ?", utils.HashToken(token)).Delete(&models.UserSession{})
+	}
 	utils.Success(ctx, gin.H{"ok": true})
 }
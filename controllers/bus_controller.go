@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"TapTransit-backend/middleware"
 	"TapTransit-backend/services"
 	"TapTransit-backend/utils"
 
@@ -19,7 +20,7 @@ func NewBusController(uploadService *services.UploadService) *BusController {
 
 // UploadBatchRecords 批量上传乘车记录
 // @Summary 批量上传乘车记录
-// @Description 网关上传批量乘车记录
+// @Description 网关上传批量乘车记录（需设备凭证，记录的gateway_id以凭证对应的设备为准）
 // @Tags 公交数据
 // @Accept json
 // @Produce json
@@ -33,6 +34,17 @@ func (c *BusController) UploadBatchRecords(ctx *gin.Context) {
 		return
 	}
 
+	// 记录的网关ID必须与设备凭证一致，未填写时使用凭证对应的设备
+	device := middleware.CurrentDevice(ctx)
+	for i := range records {
+		if records[i].GatewayID == "" {
+			records[i].GatewayID = device.DeviceID
+		} else if records[i].GatewayID != device.DeviceID {
+			utils.Forbidden(ctx, "记录的gateway_id与设备凭证不符: "+records[i].GatewayID)
+			return
+		}
+	}
+
 	successCount, err := c.uploadService.UploadBatchRecords(records)
 	if err != nil {
 		utils.InternalServerError(ctx, "处理记录失败: "+err.Error())
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"TapTransit-backend/middleware"
+	"TapTransit-backend/services"
+	"TapTransit-backend/utils"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CampaignController struct {
+	campaignService   *services.CampaignService
+	fareChangeService *services.FareChangeService
+}
+
+func NewCampaignController(campaignService *services.CampaignService, fareChangeService *services.FareChangeService) *CampaignController {
+	return &CampaignController{
+		campaignService:   campaignService,
+		fareChangeService: fareChangeService,
+	}
+}
+
+// ListCampaigns 查询优惠活动
+// @Summary 查询优惠活动
+// @Description 返回优惠活动（免费/优惠出行时段、首乘免费推广等）的适用范围、时间窗口、优惠方式与预算
+// @Tags 优惠活动
+// @Produce json
+// @Param status query string false "状态（active/inactive）"
+// @Success 200 {array} models.Campaign
+// @Router /api/v1/campaigns [get]
+func (c *CampaignController) ListCampaigns(ctx *gin.Context) {
+	campaigns, err := c.campaignService.ListCampaigns(ctx.Query("status"))
+	if err != nil {
+		utils.InternalServerError(ctx, "查询活动失败: "+err.Error())
+		return
+	}
+	utils.Success(ctx, campaigns)
+}
+
+// CreateCampaign 创建优惠活动
+// @Summary 创建优惠活动
+// @Description 提交创建优惠活动的变更申请（需fare_editor或admin角色），审批生效后创建；可按线路、上车站点、卡类型限定范围，并设置优惠总预算与次数上限
+// @Tags 优惠活动
+// @Accept json
+// @Produce json
+// @Param request body services.EntityChangeRequest true "活动字段"
+// @Success 200 {object} models.FareChangeRequest
+// @Router /api/v1/campaigns [post]
+func (c *CampaignController) CreateCampaign(ctx *gin.Context) {
+	submitEntityChange(ctx, c.fareChangeService, "campaign", services.FareChangeCreate)
+}
+
+// UpdateCampaign 修改优惠活动
+// @Summary 修改优惠活动
+// @Description 提交修改优惠活动的变更申请（需fare_editor或admin角色），values只需包含修改的字段
+// @Tags 优惠活动
+// @Accept json
+// @Produce json
+// @Param id path int true "活动ID"
+// @Param request body services.EntityChangeRequest true "修改的字段"
+// @Success 200 {object} models.FareChangeRequest
+// @Router /api/v1/campaigns/{id} [put]
+func (c *CampaignController) UpdateCampaign(ctx *gin.Context) {
+	submitEntityChange(ctx, c.fareChangeService, "campaign", services.FareChangeUpdate)
+}
+
+// DeactivateCampaign 停用优惠活动
+// @Summary 停用优惠活动
+// @Description 提交停用优惠活动的变更申请（需fare_editor或admin角色），审批生效后活动不再享受优惠，核销记录保留
+// @Tags 优惠活动
+// @Accept json
+// @Produce json
+// @Param id path int true "活动ID"
+// @Param request body services.EntityChangeRequest false "变更说明与生效时间（values可省略）"
+// @Success 200 {object} models.FareChangeRequest
+// @Router /api/v1/campaigns/{id}/deactivate [post]
+func (c *CampaignController) DeactivateCampaign(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(ctx, "ID格式错误")
+		return
+	}
+
+	var req services.EntityChangeRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+			return
+		}
+	}
+	req.Values = map[string]interface{}{"status": "inactive"}
+	if req.Title == "" {
+		req.Title = fmt.Sprintf("停用优惠活动#%d", id)
+	}
+
+	request, err := c.fareChangeService.SubmitEntityChange(middleware.CurrentUser(ctx), "campaign", services.FareChangeUpdate, uint(id), req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, request)
+}
+
+// GetRedemptionReport 查询活动核销报表
+// @Summary 查询活动核销报表
+// @Description 返回活动累计核销次数、优惠金额、剩余预算，以及查询时间段内按日期和线路汇总的核销情况
+// @Tags 优惠活动
+// @Produce json
+// @Param id path int true "活动ID"
+// @Param start_date query string false "开始日期（格式：2006-01-02）"
+// @Param end_date query string false "结束日期（格式：2006-01-02，包含当天）"
+// @Success 200 {object} services.CampaignRedemptionReport
+// @Router /api/v1/campaigns/{id}/redemptions [get]
+func (c *CampaignController) GetRedemptionReport(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(ctx, "活动ID格式错误")
+		return
+	}
+
+	var start, end *time.Time
+	if startStr := ctx.Query("start_date"); startStr != "" {
+		startDate, err := time.Parse("2006-01-02", startStr)
+		if err != nil {
+			utils.BadRequest(ctx, "开始日期格式错误，应为YYYY-MM-DD")
+			return
+		}
+		start = &startDate
+	}
+	if endStr := ctx.Query("end_date"); endStr != "" {
+		endDate, err := time.Parse("2006-01-02", endStr)
+		if err != nil {
+			utils.BadRequest(ctx, "结束日期格式错误，应为YYYY-MM-DD")
+			return
+		}
+		endDate = endDate.Add(24 * time.Hour)
+		end = &endDate
+	}
+
+	report, err := c.campaignService.GetRedemptionReport(uint(id), start, end)
+	if err != nil {
+		utils.NotFound(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, report)
+}
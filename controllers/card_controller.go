@@ -14,8 +14,8 @@ type CardController struct {
 
 type CardProfileResponse struct {
 	models.Card
-	DiscountRate   *float64 `json:"discount_rate,omitempty"`
-	DiscountAmount *float64 `json:"discount_amount,omitempty"`
+	DiscountRate   *float64      `json:"discount_rate,omitempty"`
+	DiscountAmount *models.Money `json:"discount_amount,omitempty"`
 }
 
 func NewCardController(cardService *services.CardService) *CardController {
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"TapTransit-backend/models"
+	"TapTransit-backend/services"
 	"TapTransit-backend/utils"
 	"strconv"
 
@@ -46,9 +47,24 @@ func (c *ConfigController) GetRouteConfig(ctx *gin.Context) {
 	var fares []models.Fare
 	utils.DB.Where("route_id = ? AND status = 'active'", routeID).Find(&fares)
 
-	// 获取换乘优惠规则
+	// 获取线路所属的线路组
+	var routeGroupIDs []uint
+	utils.DB.Model(&models.RouteGroupMember{}).Where("route_id = ?", routeID).Pluck("route_group_id", &routeGroupIDs)
+	var routeGroups []models.RouteGroup
+	if len(routeGroupIDs) > 0 {
+		utils.DB.Where("id IN ? AND status = 'active'", routeGroupIDs).Find(&routeGroups)
+	}
+
+	// 获取换乘优惠规则（含本线路、任意线路与按线路组匹配的规则）
 	var transfers []models.Transfer
-	utils.DB.Where("(from_route_id = ? OR to_route_id = ?) AND status = 'active'", routeID, routeID).Find(&transfers)
+	transferQuery := utils.DB.Where("from_route_id = ? OR to_route_id = ? OR (from_route_id = 0 AND from_route_group_id = 0) OR (to_route_id = 0 AND to_route_group_id = 0)", routeID, routeID)
+	if len(routeGroupIDs) > 0 {
+		transferQuery = transferQuery.Or("from_route_group_id IN ? OR to_route_group_id IN ?", routeGroupIDs, routeGroupIDs)
+	}
+	utils.DB.Where("status = 'active'").Where(transferQuery).Find(&transfers)
+
+	// 获取分区票价矩阵（供网关离线计价）
+	zoneFares, _ := services.FindZoneFares(utils.DB, uint(routeID))
 
 	// 获取站点列表（通过RouteStation关联）
 	var routeStations []models.RouteStation
@@ -81,18 +97,23 @@ func (c *ConfigController) GetRouteConfig(ctx *gin.Context) {
 			"station_id":  station.StationID,
 			"name":        station.Name,
 			"sequence":    rs.Sequence,
+			"direction":   rs.Direction,
+			"distance_km": rs.DistanceKm,
+			"zone_id":     rs.ZoneID,
 			"is_transfer": station.IsTransfer,
 		})
 	}
 
 	utils.Success(ctx, gin.H{
-		"route_id":   route.ID,
-		"route_name": route.Name,
-		"fare_type":  route.FareType,
-		"tap_mode":   route.TapMode,
-		"max_fare":   route.MaxFare,
-		"stations":   stations,
-		"fares":      fares,
-		"transfers":  transfers,
+		"route_id":     route.ID,
+		"route_name":   route.Name,
+		"fare_type":    route.FareType,
+		"tap_mode":     route.TapMode,
+		"max_fare":     route.MaxFare,
+		"stations":     stations,
+		"fares":        fares,
+		"transfers":    transfers,
+		"route_groups": routeGroups,
+		"zone_fares":   zoneFares,
 	})
 }
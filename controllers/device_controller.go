@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"TapTransit-backend/middleware"
+	"TapTransit-backend/services"
+	"TapTransit-backend/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeviceController struct {
+	deviceService *services.DeviceService
+}
+
+func NewDeviceController(deviceService *services.DeviceService) *DeviceController {
+	return &DeviceController{
+		deviceService: deviceService,
+	}
+}
+
+// Heartbeat 网关心跳
+// @Summary 网关心跳
+// @Description 网关定时上报固件版本、配置版本、待上传记录数、设备时钟和GPS位置，返回服务器时间用于校准时钟（需设备凭证）
+// @Tags 公交数据
+// @Accept json
+// @Produce json
+// @Param request body services.HeartbeatRequest true "心跳信息"
+// @Success 200 {object} services.HeartbeatResponse
+// @Router /api/v1/bus/heartbeat [post]
+func (c *DeviceController) Heartbeat(ctx *gin.Context) {
+	var req services.HeartbeatRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+	device := middleware.CurrentDevice(ctx)
+	if req.GatewayID != "" && req.GatewayID != device.DeviceID {
+		utils.Forbidden(ctx, "gateway_id与设备凭证不符: "+req.GatewayID)
+		return
+	}
+	req.GatewayID = device.DeviceID
+
+	result, err := c.deviceService.Heartbeat(req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, result)
+}
+
+// ListDevices 查询设备列表
+// @Summary 查询设备列表
+// @Description 查询网关设备及最近一次心跳上报的状态
+// @Tags 设备管理
+// @Produce json
+// @Param status query string false "状态（online/offline/unknown按连接状态，active/inactive/maintenance按设备状态）"
+// @Param vehicle_id query int false "车辆ID"
+// @Success 200 {array} models.Device
+// @Router /api/v1/devices [get]
+func (c *DeviceController) ListDevices(ctx *gin.Context) {
+	var query services.DeviceQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	devices, err := c.deviceService.ListDevices(query)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, devices)
+}
+
+// CreateEnrollmentToken 签发设备注册令牌
+// @Summary 签发设备注册令牌
+// @Description 为网关签发一次性注册令牌（设备未登记时自动登记），令牌明文只在响应中返回一次
+// @Tags 设备管理
+// @Accept json
+// @Produce json
+// @Param request body services.EnrollmentTokenRequest true "设备与有效期"
+// @Success 200 {object} services.EnrollmentTokenResponse
+// @Router /api/v1/devices/enrollment-tokens [post]
+func (c *DeviceController) CreateEnrollmentToken(ctx *gin.Context) {
+	var req services.EnrollmentTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	result, err := c.deviceService.CreateEnrollmentToken(middleware.CurrentUser(ctx), req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, result)
+}
+
+// RevokeCredentials 吊销设备凭证
+// @Summary 吊销设备凭证
+// @Description 吊销设备的全部有效凭证，设备需使用新的注册令牌重新注册
+// @Tags 设备管理
+// @Produce json
+// @Param id path int true "设备ID"
+// @Success 200 {object} models.Device
+// @Router /api/v1/devices/{id}/revoke-credentials [post]
+func (c *DeviceController) RevokeCredentials(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(ctx, "设备ID格式错误")
+		return
+	}
+
+	device, err := c.deviceService.RevokeCredentials(uint(id))
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, device)
+}
+
+// Enroll 网关注册
+// @Summary 网关注册
+// @Description 网关以一次性注册令牌兑换设备凭证，之后上传记录和心跳通过 Authorization: Bearer <设备凭证> 认证
+// @Tags 公交数据
+// @Accept json
+// @Produce json
+// @Param request body services.EnrollRequest true "注册令牌"
+// @Success 200 {object} services.EnrollResponse
+// @Router /api/v1/bus/enroll [post]
+func (c *DeviceController) Enroll(ctx *gin.Context) {
+	var req services.EnrollRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	result, err := c.deviceService.Enroll(req)
+	if err != nil {
+		utils.Unauthorized(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, result)
+}
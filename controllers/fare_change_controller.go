@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"TapTransit-backend/middleware"
+	"TapTransit-backend/services"
+	"TapTransit-backend/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FareChangeController struct {
+	fareChangeService *services.FareChangeService
+}
+
+func NewFareChangeController(fareChangeService *services.FareChangeService) *FareChangeController {
+	return &FareChangeController{
+		fareChangeService: fareChangeService,
+	}
+}
+
+// ListChanges 查询票价配置变更申请
+// @Summary 查询变更申请
+// @Description 查询票价配置变更申请，可按状态筛选（pending/scheduled/applied/rejected/cancelled/failed）
+// @Tags 票价配置变更
+// @Produce json
+// @Param status query string false "状态"
+// @Success 200 {array} models.FareChangeRequest
+// @Router /api/v1/fare-changes [get]
+func (c *FareChangeController) ListChanges(ctx *gin.Context) {
+	requests, err := c.fareChangeService.List(ctx.Query("status"))
+	if err != nil {
+		utils.InternalServerError(ctx, "查询变更申请失败: "+err.Error())
+		return
+	}
+	utils.Success(ctx, requests)
+}
+
+// GetChange 查询变更申请详情
+// @Summary 查询变更申请详情
+// @Tags 票价配置变更
+// @Produce json
+// @Param id path int true "变更申请ID"
+// @Success 200 {object} models.FareChangeRequest
+// @Router /api/v1/fare-changes/{id} [get]
+func (c *FareChangeController) GetChange(ctx *gin.Context) {
+	id, ok := parseChangeID(ctx)
+	if !ok {
+		return
+	}
+	request, err := c.fareChangeService.Get(id)
+	if err != nil {
+		utils.NotFound(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, request)
+}
+
+// SubmitChange 提交票价配置变更申请
+// @Summary 提交变更申请
+// @Description 提交票价、换乘规则、折扣策略或线路最高票价的变更（需fare_editor或admin角色），审批前不修改任何配置
+// @Tags 票价配置变更
+// @Accept json
+// @Produce json
+// @Param request body services.SubmitFareChangeRequest true "变更申请"
+// @Success 200 {object} models.FareChangeRequest
+// @Router /api/v1/fare-changes [post]
+func (c *FareChangeController) SubmitChange(ctx *gin.Context) {
+	var req services.SubmitFareChangeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+	request, err := c.fareChangeService.Submit(middleware.CurrentUser(ctx), req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, request)
+}
+
+// ApproveChange 审批通过变更申请
+// @Summary 审批通过变更申请
+// @Description 由提交人以外具有fare_approver或admin角色的用户审批；未指定生效时间或已到生效时间时立即整体生效，否则到时由定时任务生效
+// @Tags 票价配置变更
+// @Accept json
+// @Produce json
+// @Param id path int true "变更申请ID"
+// @Param request body services.ReviewFareChangeRequest false "审批意见"
+// @Success 200 {object} models.FareChangeRequest
+// @Router /api/v1/fare-changes/{id}/approve [post]
+func (c *FareChangeController) ApproveChange(ctx *gin.Context) {
+	id, ok := parseChangeID(ctx)
+	if !ok {
+		return
+	}
+	var req services.ReviewFareChangeRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+			return
+		}
+	}
+	request, err := c.fareChangeService.Approve(id, middleware.CurrentUser(ctx), req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, request)
+}
+
+// RejectChange 驳回变更申请
+// @Summary 驳回变更申请
+// @Tags 票价配置变更
+// @Accept json
+// @Produce json
+// @Param id path int true "变更申请ID"
+// @Param request body services.ReviewFareChangeRequest false "审批意见"
+// @Success 200 {object} models.FareChangeRequest
+// @Router /api/v1/fare-changes/{id}/reject [post]
+func (c *FareChangeController) RejectChange(ctx *gin.Context) {
+	id, ok := parseChangeID(ctx)
+	if !ok {
+		return
+	}
+	var req services.ReviewFareChangeRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+			return
+		}
+	}
+	request, err := c.fareChangeService.Reject(id, middleware.CurrentUser(ctx), req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, request)
+}
+
+// CancelChange 撤回变更申请
+// @Summary 撤回变更申请
+// @Description 提交人撤回尚未生效的变更申请
+// @Tags 票价配置变更
+// @Produce json
+// @Param id path int true "变更申请ID"
+// @Success 200 {object} models.FareChangeRequest
+// @Router /api/v1/fare-changes/{id}/cancel [post]
+func (c *FareChangeController) CancelChange(ctx *gin.Context) {
+	id, ok := parseChangeID(ctx)
+	if !ok {
+		return
+	}
+	request, err := c.fareChangeService.Cancel(id, middleware.CurrentUser(ctx))
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, request)
+}
+
+// ListAuditLogs 查询票价配置审计记录
+// @Summary 查询审计记录
+// @Description 查询已生效的票价配置变更及变更前后的值，可按变更对象、对象ID和变更申请筛选
+// @Tags 票价配置变更
+// @Produce json
+// @Param entity query string false "变更对象（fare/transfer/discount_policy/route_max_fare）"
+// @Param entity_id query int false "变更对象ID"
+// @Param change_request_id query int false "变更申请ID"
+// @Success 200 {array} models.FareAuditLog
+// @Router /api/v1/fare-audit-logs [get]
+func (c *FareChangeController) ListAuditLogs(ctx *gin.Context) {
+	entityID, _ := strconv.ParseUint(ctx.Query("entity_id"), 10, 64)
+	changeRequestID, _ := strconv.ParseUint(ctx.Query("change_request_id"), 10, 64)
+	logs, err := c.fareChangeService.ListAuditLogs(services.FareAuditQuery{
+		Entity:          ctx.Query("entity"),
+		EntityID:        uint(entityID),
+		ChangeRequestID: uint(changeRequestID),
+	})
+	if err != nil {
+		utils.InternalServerError(ctx, "查询审计记录失败: "+err.Error())
+		return
+	}
+	utils.Success(ctx, logs)
+}
+
+// parseChangeID 解析路径中的变更申请ID（格式错误时返回400）
+func parseChangeID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(ctx, "变更申请ID格式错误")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"TapTransit-backend/middleware"
+	"TapTransit-backend/services"
+	"TapTransit-backend/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FareConfigController 票价规则、换乘规则与折扣策略管理
+// 查询直接返回当前配置；新增、修改、删除提交为只包含一项变更的变更申请，审批生效后才修改配置
+type FareConfigController struct {
+	fareConfigService *services.FareConfigService
+	fareChangeService *services.FareChangeService
+}
+
+func NewFareConfigController(fareConfigService *services.FareConfigService, fareChangeService *services.FareChangeService) *FareConfigController {
+	return &FareConfigController{
+		fareConfigService: fareConfigService,
+		fareChangeService: fareChangeService,
+	}
+}
+
+// ListFares 查询票价规则
+// @Summary 查询票价规则
+// @Tags 票价配置
+// @Produce json
+// @Param route_id query int false "线路ID"
+// @Param station_id query int false "起始或结束站点ID"
+// @Param fare_type query string false "计价类型"
+// @Param status query string false "状态（active/inactive）"
+// @Success 200 {array} models.Fare
+// @Router /api/v1/fares [get]
+func (c *FareConfigController) ListFares(ctx *gin.Context) {
+	var query services.FareQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+	fares, err := c.fareConfigService.ListFares(query)
+	if err != nil {
+		utils.InternalServerError(ctx, "查询票价规则失败: "+err.Error())
+		return
+	}
+	utils.Success(ctx, fares)
+}
+
+// CreateFare 新增票价规则
+// @Summary 新增票价规则
+// @Description 提交新增票价规则的变更申请（需fare_editor或admin角色），审批生效后创建
+// @Tags 票价配置
+// @Accept json
+// @Produce json
+// @Param request body services.EntityChangeRequest true "票价规则字段"
+// @Success 200 {object} models.FareChangeRequest
+// @Router /api/v1/fares [post]
+func (c *FareConfigController) CreateFare(ctx *gin.Context) {
+	c.submitChange(ctx, "fare", services.FareChangeCreate)
+}
+
+// UpdateFare 修改票价规则
+// @Summary 修改票价规则
+// @Description 提交修改票价规则的变更申请（需fare_editor或admin角色），values只需包含修改的字段
+// @Tags 票价配置
+// @Accept json
+// @Produce json
+// @Param id path int true "票价规则ID"
+// @Param request body services.EntityChangeRequest true "修改的字段"
+// @Success 200 {object} models.FareChangeRequest
+// @Router /api/v1/fares/{id} [put]
+func (c *FareConfigController) UpdateFare(ctx *gin.Context) {
+	c.submitChange(ctx, "fare", services.FareChangeUpdate)
+}
+
+// DeleteFare 删除票价规则
+// @Summary 删除票价规则
+// @Description 提交删除票价规则的变更申请（需fare_editor或admin角色）；停用规则可通过修改status为inactive
+// @Tags 票价配置
+// @Produce json
+// @Param id path int true "票价规则ID"
+// @Success 200 {object} models.FareChangeRequest
+// @Router /api/v1/fares/{id} [delete]
+func (c *FareConfigController) DeleteFare(ctx *gin.Context) {
+	c.submitChange(ctx, "fare", services.FareChangeDelete)
+}
+
+// ListTransfers 查询换乘规则
+// @Summary 查询换乘规则
+// @Tags 票价配置
+// @Produce json
+// @Param route_id query int false "起始或换乘后线路ID"
+// @Param station_id query int false "起始或换乘后站点ID"
+// @Param status query string false "状态（active/inactive）"
+// @Success 200 {array} models.Transfer
+// @Router /api/v1/transfers [get]
+func (c *FareConfigController) ListTransfers(ctx *gin.Context) {
+	var query services.TransferQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+	transfers, err := c.fareConfigService.ListTransfers(query)
+	if err != nil {
+		utils.InternalServerError(ctx, "查询换乘规则失败: "+err.Error())
+		return
+	}
+	utils.Success(ctx, transfers)
+}
+
+// CreateTransfer 新增换乘规则
+// @Summary 新增换乘规则
+// @Description 提交新增换乘规则的变更申请（需fare_editor或admin角色），审批生效后创建
+// @Tags 票价配置
+// @Accept json
+// @Produce json
+// @Param request body services.EntityChangeRequest true "换乘规则字段"
+// @Success 200 {object} models.FareChangeRequest
+// @Router /api/v1/transfers [post]
+func (c *FareConfigController) CreateTransfer(ctx *gin.Context) {
+	c.submitChange(ctx, "transfer", services.FareChangeCreate)
+}
+
+// UpdateTransfer 修改换乘规则
+// @Summary 修改换乘规则
+// @Description 提交修改换乘规则的变更申请（需fare_editor或admin角色），values只需包含修改的字段
+// @Tags 票价配置
+// @Accept json
+// @Produce json
+// @Param id path int true "换乘规则ID"
+// @Param request body services.EntityChangeRequest true "修改的字段"
+// @Success 200 {object} models.FareChangeRequest
+// @Router /api/v1/transfers/{id} [put]
+func (c *FareConfigController) UpdateTransfer(ctx *gin.Context) {
+	c.submitChange(ctx, "transfer", services.FareChangeUpdate)
+}
+
+// DeleteTransfer 删除换乘规则
+// @Summary 删除换乘规则
+// @Description 提交删除换乘规则的变更申请（需fare_editor或admin角色）
+// @Tags 票价配置
+// @Produce json
+// @Param id path int true "换乘规则ID"
+// @Success 200 {object} models.FareChangeRequest
+// @Router /api/v1/transfers/{id} [delete]
+func (c *FareConfigController) DeleteTransfer(ctx *gin.Context) {
+	c.submitChange(ctx, "transfer", services.FareChangeDelete)
+}
+
+// ListDiscountPolicies 查询折扣策略
+// @Summary 查询折扣策略
+// @Tags 票价配置
+// @Produce json
+// @Param policy_type query string false "策略类型"
+// @Param card_type query string false "适用的卡类型（包括适用所有卡类型的策略）"
+// @Param status query string false "状态（active/inactive）"
+// @Success 200 {array} models.DiscountPolicy
+// @Router /api/v1/discount-policies [get]
+func (c *FareConfigController) ListDiscountPolicies(ctx *gin.Context) {
+	var query services.DiscountPolicyQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+	policies, err := c.fareConfigService.ListDiscountPolicies(query)
+	if err != nil {
+		utils.InternalServerError(ctx, "查询折扣策略失败: "+err.Error())
+		return
+	}
+	utils.Success(ctx, policies)
+}
+
+// CreateDiscountPolicy 新增折扣策略
+// @Summary 新增折扣策略
+// @Description 提交新增折扣策略的变更申请（需fare_editor或admin角色），审批生效后创建
+// @Tags 票价配置
+// @Accept json
+// @Produce json
+// @Param request body services.EntityChangeRequest true "折扣策略字段"
+// @Success 200 {object} models.FareChangeRequest
+// @Router /api/v1/discount-policies [post]
+func (c *FareConfigController) CreateDiscountPolicy(ctx *gin.Context) {
+	c.submitChange(ctx, "discount_policy", services.FareChangeCreate)
+}
+
+// UpdateDiscountPolicy 修改折扣策略
+// @Summary 修改折扣策略
+// @Description 提交修改折扣策略的变更申请（需fare_editor或admin角色），values只需包含修改的字段
+// @Tags 票价配置
+// @Accept json
+// @Produce json
+// @Param id path int true "折扣策略ID"
+// @Param request body services.EntityChangeRequest true "修改的字段"
+// @Success 200 {object} models.FareChangeRequest
+// @Router /api/v1/discount-policies/{id} [put]
+func (c *FareConfigController) UpdateDiscountPolicy(ctx *gin.Context) {
+	c.submitChange(ctx, "discount_policy", services.FareChangeUpdate)
+}
+
+// DeleteDiscountPolicy 删除折扣策略
+// @Summary 删除折扣策略
+// @Description 提交删除折扣策略的变更申请（需fare_editor或admin角色）
+// @Tags 票价配置
+// @Produce json
+// @Param id path int true "折扣策略ID"
+// @Success 200 {object} models.FareChangeRequest
+// @Router /api/v1/discount-policies/{id} [delete]
+func (c *FareConfigController) DeleteDiscountPolicy(ctx *gin.Context) {
+	c.submitChange(ctx, "discount_policy", services.FareChangeDelete)
+}
+
+// submitChange 提交单项变更申请（修改、删除时从路径读取ID；删除时请求体可省略）
+func (c *FareConfigController) submitChange(ctx *gin.Context, entity, action string) {
+	submitEntityChange(ctx, c.fareChangeService, entity, action)
+}
+
+// submitEntityChange 提交单项变更申请（票价配置与优惠活动的管理接口共用）
+func submitEntityChange(ctx *gin.Context, fareChangeService *services.FareChangeService, entity, action string) {
+	var entityID uint
+	if action != services.FareChangeCreate {
+		id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+		if err != nil {
+			utils.BadRequest(ctx, "ID格式错误")
+			return
+		}
+		entityID = uint(id)
+	}
+
+	var req services.EntityChangeRequest
+	if action != services.FareChangeDelete || ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+			return
+		}
+	}
+
+	request, err := fareChangeService.SubmitEntityChange(middleware.CurrentUser(ctx), entity, action, entityID, req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, request)
+}
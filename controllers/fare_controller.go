@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"TapTransit-backend/services"
+	"TapTransit-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FareController struct {
+	fareService *services.FareService
+}
+
+func NewFareController(fareService *services.FareService) *FareController {
+	return &FareController{
+		fareService: fareService,
+	}
+}
+
+// QuoteFare 票价报价
+// @Summary 票价报价
+// @Description 乘车前查询票价（与实际扣费使用同一计费流水线，不产生交易记录），返回票价及计费明细
+// @Tags 票价
+// @Accept json
+// @Produce json
+// @Param request body services.FareQuoteRequest true "报价请求"
+// @Success 200 {object} services.FareQuoteResult
+// @Router /api/v1/fares/quote [post]
+func (c *FareController) QuoteFare(ctx *gin.Context) {
+	var req services.FareQuoteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	quote, err := c.fareService.Quote(req)
+	if err != nil {
+		utils.BadRequest(ctx, "报价失败: "+err.Error())
+		return
+	}
+
+	utils.Success(ctx, quote)
+}
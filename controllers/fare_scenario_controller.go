@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"TapTransit-backend/services"
+	"TapTransit-backend/utils"
+	"io"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FareScenarioController struct {
+	runner *services.FareScenarioRunner
+}
+
+func NewFareScenarioController(runner *services.FareScenarioRunner) *FareScenarioController {
+	return &FareScenarioController{
+		runner: runner,
+	}
+}
+
+// RunScenarios 执行计费场景
+// @Summary 执行计费场景
+// @Description 在独立事务中以候选票价配置执行计费场景（刷卡序列与期望票价），返回各场景是否通过及与期望不一致的字段和计费明细；执行结束后回滚，不写入任何数据
+// @Tags 票价
+// @Accept json
+// @Produce json
+// @Param request body services.FareScenarioSuite true "场景集（JSON或YAML）"
+// @Success 200 {object} services.FareScenarioReport
+// @Router /api/v1/admin/fare-scenarios/run [post]
+func (c *FareScenarioController) RunScenarios(ctx *gin.Context) {
+	data, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		utils.BadRequest(ctx, "读取请求失败: "+err.Error())
+		return
+	}
+	suite, err := services.ParseFareScenarioSuite(data)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+
+	report, err := c.runner.Run(suite)
+	if err != nil {
+		utils.BadRequest(ctx, "执行场景失败: "+err.Error())
+		return
+	}
+	utils.Success(ctx, report)
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"TapTransit-backend/services"
+	"TapTransit-backend/utils"
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FareSimulationController struct {
+	simulator *services.FareSimulator
+}
+
+func NewFareSimulationController(simulator *services.FareSimulator) *FareSimulationController {
+	return &FareSimulationController{
+		simulator: simulator,
+	}
+}
+
+// SimulateFares 票价调整模拟
+// @Summary 票价调整模拟
+// @Description 将时间段内的历史乘次分别按现有配置和拟调整配置重新计费（不写入任何数据），返回总收入、按线路和卡类型的收入变化及单程票价变化分布；format=csv时按view导出CSV
+// @Tags 票价
+// @Accept json
+// @Produce json
+// @Param request body services.FareSimulationRequest true "模拟请求"
+// @Param format query string false "导出格式（json/csv）" default(json)
+// @Param view query string false "CSV视图（trips/routes/card_types/distribution）" default(trips)
+// @Success 200 {object} services.FareSimulationReport
+// @Router /api/v1/admin/fare-simulations [post]
+func (c *FareSimulationController) SimulateFares(ctx *gin.Context) {
+	var req services.FareSimulationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	view := ctx.DefaultQuery("view", services.SimulationCSVTrips)
+	if ctx.Query("format") == "csv" && view == services.SimulationCSVTrips {
+		c.streamTripCSV(ctx, req)
+		return
+	}
+
+	report, err := c.simulator.Simulate(req, nil)
+	if err != nil {
+		utils.BadRequest(ctx, "模拟失败: "+err.Error())
+		return
+	}
+
+	if ctx.Query("format") != "csv" {
+		utils.Success(ctx, report)
+		return
+	}
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf, view); err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	ctx.Header("Content-Disposition", "attachment; filename="+simulationCSVFilename(req, view))
+	ctx.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// streamTripCSV 逐程明细随模拟逐批写出响应（不在内存中保留全部乘次）
+// 开始输出前失败时返回错误响应；输出过程中失败时在末尾写出一行错误说明
+func (c *FareSimulationController) streamTripCSV(ctx *gin.Context, req services.FareSimulationRequest) {
+	ctx.Header("Content-Disposition", "attachment; filename="+simulationCSVFilename(req, services.SimulationCSVTrips))
+	ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	trips := services.NewSimulationTripWriter(ctx.Writer)
+	_, err := c.simulator.Simulate(req, trips.Write)
+	if err == nil {
+		trips.Flush()
+		return
+	}
+	if !ctx.Writer.Written() {
+		ctx.Writer.Header().Del("Content-Disposition")
+		ctx.Writer.Header().Del("Content-Type")
+		utils.BadRequest(ctx, "模拟失败: "+err.Error())
+		return
+	}
+	trips.Write(services.FareSimulationTrip{Error: "模拟中止: " + err.Error()})
+	trips.Flush()
+}
+
+// simulationCSVFilename 模拟报告CSV文件名
+func simulationCSVFilename(req services.FareSimulationRequest, view string) string {
+	return fmt.Sprintf("fare_simulation_%s_%s_%s.csv", req.StartDate, req.EndDate, view)
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"TapTransit-backend/services"
+	"TapTransit-backend/utils"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GTFSController struct {
+	importer *services.GTFSImporter
+	exporter *services.GTFSExporter
+}
+
+func NewGTFSController(importer *services.GTFSImporter, exporter *services.GTFSExporter) *GTFSController {
+	return &GTFSController{
+		importer: importer,
+		exporter: exporter,
+	}
+}
+
+// ImportGTFS 导入GTFS静态数据
+// @Summary 导入GTFS静态数据
+// @Description 从GTFS压缩包（routes.txt、stops.txt、trips.txt、stop_times.txt、shapes.txt）新增或更新线路、站点和线路站序（含经纬度、方向与累计距离）；dry_run=true时只返回与现有数据的差异，不写入任何数据
+// @Tags 线路管理
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "GTFS压缩包"
+// @Param dry_run query bool false "只返回差异不导入"
+// @Success 200 {object} services.GTFSImportReport
+// @Router /api/v1/admin/gtfs/import [post]
+func (c *GTFSController) ImportGTFS(ctx *gin.Context) {
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		utils.BadRequest(ctx, "请上传GTFS压缩包（字段名file）")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.BadRequest(ctx, "读取文件失败: "+err.Error())
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		utils.BadRequest(ctx, "读取文件失败: "+err.Error())
+		return
+	}
+
+	report, err := c.importer.Import(data, ctx.Query("dry_run") == "true")
+	if err != nil {
+		utils.BadRequest(ctx, "导入失败: "+err.Error())
+		return
+	}
+	utils.Success(ctx, report)
+}
+
+// DownloadGTFS 下载GTFS数据
+// @Summary 下载GTFS数据
+// @Description 下载由线路、站点、站序和票价配置生成的GTFS压缩包（含GTFS-Fares v2的fare_products、fare_leg_rules、fare_transfer_rules、areas）；主数据有变化时重新生成
+// @Tags 开放数据
+// @Produce application/zip
+// @Success 200 {file} file
+// @Router /api/v1/gtfs/feed.zip [get]
+func (c *GTFSController) DownloadGTFS(ctx *gin.Context) {
+	export, err := c.exporter.Export()
+	if err != nil {
+		utils.InternalServerError(ctx, "生成GTFS数据失败: "+err.Error())
+		return
+	}
+	ctx.Header("Content-Disposition", "attachment; filename=gtfs.zip")
+	ctx.Header("ETag", `"`+export.Fingerprint+`"`)
+	ctx.Header("Last-Modified", export.GeneratedAt.UTC().Format(http.TimeFormat))
+	if ctx.GetHeader("If-None-Match") == `"`+export.Fingerprint+`"` {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	ctx.Data(http.StatusOK, "application/zip", export.Data)
+}
+
+// GetGTFSInfo 查询GTFS数据生成信息
+// @Summary 查询GTFS数据生成信息
+// @Description 返回当前GTFS数据的生成时间、各文件记录数，以及无法用GTFS-Fares v2表达而未导出的规则
+// @Tags 开放数据
+// @Produce json
+// @Success 200 {object} services.GTFSExport
+// @Router /api/v1/gtfs/feed [get]
+func (c *GTFSController) GetGTFSInfo(ctx *gin.Context) {
+	export, err := c.exporter.Export()
+	if err != nil {
+		utils.InternalServerError(ctx, "生成GTFS数据失败: "+err.Error())
+		return
+	}
+	utils.Success(ctx, export)
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"TapTransit-backend/middleware"
+	"TapTransit-backend/models"
+	"TapTransit-backend/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type JourneyController struct {
+}
+
+func NewJourneyController() *JourneyController {
+	return &JourneyController{}
+}
+
+// GetJourneys 查询行程列表
+// @Summary 查询行程列表
+// @Description 按卡片和日期查询行程（通过换乘连接的多程乘车），便于客服查看乘客完整出行；需管理员或运营人员，运营人员须按卡片查询
+// @Tags 行程
+// @Produce json
+// @Param card_id query string false "卡片ID（运营人员必填）"
+// @Param date query string false "日期（格式：2006-01-02）"
+// @Param start_date query string false "开始日期（格式：2006-01-02）"
+// @Param end_date query string false "结束日期（格式：2006-01-02，包含当天）"
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/journeys [get]
+func (c *JourneyController) GetJourneys(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", "20"))
+	cardID := ctx.Query("card_id")
+
+	// 只有管理员可以浏览全部行程，运营人员只能查询指定卡片的行程
+	if user := middleware.CurrentUser(ctx); cardID == "" && (user == nil || user.Role != models.RoleAdmin) {
+		utils.Forbidden(ctx, "请指定卡片ID查询行程")
+		return
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := utils.DB.Model(&models.Journey{})
+
+	// 卡片筛选
+	if cardID != "" {
+		query = query.Where("card_id = ?", cardID)
+	}
+
+	// 日期筛选（按首程上车时间）
+	if dateStr := ctx.Query("date"); dateStr != "" {
+		date, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			utils.BadRequest(ctx, "日期格式错误，应为YYYY-MM-DD")
+			return
+		}
+		query = query.Where("start_time >= ? AND start_time < ?", date, date.Add(24*time.Hour))
+	}
+	if startStr := ctx.Query("start_date"); startStr != "" {
+		startDate, err := time.Parse("2006-01-02", startStr)
+		if err != nil {
+			utils.BadRequest(ctx, "开始日期格式错误，应为YYYY-MM-DD")
+			return
+		}
+		query = query.Where("start_time >= ?", startDate)
+	}
+	if endStr := ctx.Query("end_date"); endStr != "" {
+		endDate, err := time.Parse("2006-01-02", endStr)
+		if err != nil {
+			utils.BadRequest(ctx, "结束日期格式错误，应为YYYY-MM-DD")
+			return
+		}
+		query = query.Where("start_time < ?", endDate.Add(24*time.Hour))
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var journeys []models.Journey
+	offset := (page - 1) * pageSize
+	query.Order("start_time DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&journeys)
+
+	utils.Success(ctx, gin.H{
+		"data": journeys,
+		"pagination": gin.H{
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
+			"pages":     (total + int64(pageSize) - 1) / int64(pageSize),
+		},
+	})
+}
+
+// GetJourney 查询行程详情
+// @Summary 查询行程详情
+// @Description 返回行程汇总及按时间排序的各程交易记录（需管理员或运营人员）
+// @Tags 行程
+// @Produce json
+// @Param id path string true "行程ID"
+// @Success 200 {object} models.Journey
+// @Router /api/v1/journeys/{id} [get]
+func (c *JourneyController) GetJourney(ctx *gin.Context) {
+	var journey models.Journey
+	err := utils.DB.Where("journey_id = ?", ctx.Param("id")).
+		Preload("Legs", func(db *gorm.DB) *gorm.DB {
+			return db.Where("status = 'completed'").Order("board_time ASC")
+		}).
+		Preload("Legs.Route").
+		First(&journey).Error
+	if err != nil {
+		utils.NotFound(ctx, "行程不存在")
+		return
+	}
+
+	utils.Success(ctx, journey)
+}
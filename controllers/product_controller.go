@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"TapTransit-backend/services"
+	"TapTransit-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProductController struct {
+	productService *services.ProductService
+}
+
+func NewProductController(productService *services.ProductService) *ProductController {
+	return &ProductController{
+		productService: productService,
+	}
+}
+
+// ListProducts 查询票卡产品目录
+// @Summary 查询票卡产品目录
+// @Description 返回在售的月票、周票等票卡产品（票种、有效期、适用线路/分区、可购买卡类型、售价）
+// @Tags 票卡产品
+// @Produce json
+// @Success 200 {array} models.Product
+// @Router /api/v1/products [get]
+func (c *ProductController) ListProducts(ctx *gin.Context) {
+	products, err := c.productService.ListProducts()
+	if err != nil {
+		utils.InternalServerError(ctx, "查询产品失败: "+err.Error())
+		return
+	}
+	utils.Success(ctx, products)
+}
+
+// PurchaseProduct 为卡片购买票卡产品
+// @Summary 购买票卡产品
+// @Description 柜台收款后为卡片办理票卡产品（需管理员或运营人员）；首次乘车激活的产品未指定生效时间时，在首次乘车时激活
+// @Tags 票卡产品
+// @Accept json
+// @Produce json
+// @Param id path string true "卡片ID"
+// @Param request body services.PurchaseProductRequest true "购买请求"
+// @Success 200 {object} models.CardProduct
+// @Router /api/v1/cards/{id}/products [post]
+func (c *ProductController) PurchaseProduct(ctx *gin.Context) {
+	var req services.PurchaseProductRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	cardProduct, err := c.productService.PurchaseProduct(ctx.Param("id"), req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, cardProduct)
+}
+
+// ListCardProducts 查询卡片购买的票卡产品
+// @Summary 查询卡片票卡产品
+// @Description 返回卡片购买的票卡产品及其状态、有效期和使用次数
+// @Tags 票卡产品
+// @Produce json
+// @Param id path string true "卡片ID"
+// @Success 200 {array} models.CardProduct
+// @Router /api/v1/cards/{id}/products [get]
+func (c *ProductController) ListCardProducts(ctx *gin.Context) {
+	cardProducts, err := c.productService.ListCardProducts(ctx.Param("id"))
+	if err != nil {
+		utils.InternalServerError(ctx, "查询票卡失败: "+err.Error())
+		return
+	}
+	utils.Success(ctx, cardProducts)
+}
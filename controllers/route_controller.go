@@ -1,32 +1,187 @@
 package controllers
 
 import (
-	"TapTransit-backend/models"
+	"TapTransit-backend/services"
 	"TapTransit-backend/utils"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
 type RouteController struct {
+	networkService *services.NetworkService
 }
 
-func NewRouteController() *RouteController {
-	return &RouteController{}
+func NewRouteController(networkService *services.NetworkService) *RouteController {
+	return &RouteController{
+		networkService: networkService,
+	}
 }
 
 // GetRoutes 获取所有线路列表
 // @Summary 获取线路列表
-// @Description 获取所有线路信息
+// @Description 获取所有线路信息（默认仅返回启用线路）
 // @Tags 线路管理
 // @Produce json
+// @Param status query string false "状态（active/inactive/all，默认active）"
 // @Success 200 {object} map[string]interface{}
 // @Router /api/v1/routes [get]
 func (c *RouteController) GetRoutes(ctx *gin.Context) {
-	var routes []models.Route
-	if err := utils.DB.Where("status = 'active'").Find(&routes).Error; err != nil {
+	routes, err := c.networkService.ListRoutes(ctx.Query("status"))
+	if err != nil {
 		utils.InternalServerError(ctx, "查询失败")
 		return
 	}
 
 	utils.Success(ctx, routes)
 }
+
+// CreateRoute 新增线路
+// @Summary 新增线路
+// @Description 新增线路，校验线路编号唯一及计价模式、刷卡模式、方向模式取值（最高票价通过票价配置变更申请设置）
+// @Tags 线路管理
+// @Accept json
+// @Produce json
+// @Param request body services.RouteRequest true "线路信息"
+// @Success 200 {object} models.Route
+// @Router /api/v1/routes [post]
+func (c *RouteController) CreateRoute(ctx *gin.Context) {
+	var req services.RouteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	route, err := c.networkService.CreateRoute(req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, route)
+}
+
+// UpdateRoute 修改线路
+// @Summary 修改线路
+// @Description 修改线路基本信息
+// @Tags 线路管理
+// @Accept json
+// @Produce json
+// @Param id path int true "线路ID"
+// @Param request body services.RouteRequest true "线路信息"
+// @Success 200 {object} models.Route
+// @Router /api/v1/routes/{id} [put]
+func (c *RouteController) UpdateRoute(ctx *gin.Context) {
+	id, ok := parseRouteID(ctx)
+	if !ok {
+		return
+	}
+	var req services.RouteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	route, err := c.networkService.UpdateRoute(id, req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, route)
+}
+
+// DeactivateRoute 停用线路
+// @Summary 停用线路
+// @Description 停用线路（保留站序与历史交易，停用后不再下发给车载设备）
+// @Tags 线路管理
+// @Produce json
+// @Param id path int true "线路ID"
+// @Success 200 {object} models.Route
+// @Router /api/v1/routes/{id}/deactivate [post]
+func (c *RouteController) DeactivateRoute(ctx *gin.Context) {
+	c.setRouteStatus(ctx, "inactive")
+}
+
+// ActivateRoute 启用线路
+// @Summary 启用线路
+// @Description 重新启用已停用的线路
+// @Tags 线路管理
+// @Produce json
+// @Param id path int true "线路ID"
+// @Success 200 {object} models.Route
+// @Router /api/v1/routes/{id}/activate [post]
+func (c *RouteController) ActivateRoute(ctx *gin.Context) {
+	c.setRouteStatus(ctx, "active")
+}
+
+func (c *RouteController) setRouteStatus(ctx *gin.Context, status string) {
+	id, ok := parseRouteID(ctx)
+	if !ok {
+		return
+	}
+	route, err := c.networkService.SetRouteStatus(id, status)
+	if err != nil {
+		utils.NotFound(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, route)
+}
+
+// GetRouteStations 查询线路站序
+// @Summary 查询线路站序
+// @Description 返回线路经过的站点及站序、分区与累计距离
+// @Tags 线路管理
+// @Produce json
+// @Param id path int true "线路ID"
+// @Param direction query string false "方向（up/down，不传返回全部方向）"
+// @Success 200 {array} models.RouteStation
+// @Router /api/v1/routes/{id}/stations [get]
+func (c *RouteController) GetRouteStations(ctx *gin.Context) {
+	id, ok := parseRouteID(ctx)
+	if !ok {
+		return
+	}
+	routeStations, err := c.networkService.ListRouteStations(id, ctx.Query("direction"))
+	if err != nil {
+		utils.InternalServerError(ctx, "查询失败")
+		return
+	}
+	utils.Success(ctx, routeStations)
+}
+
+// SetRouteStations 设置线路站序
+// @Summary 设置线路站序
+// @Description 整体替换线路某一方向的站点列表，可在一次调用中完成增删站点和调整顺序；校验站序连续、站点不重复且累计距离单调不减
+// @Tags 线路管理
+// @Accept json
+// @Produce json
+// @Param id path int true "线路ID"
+// @Param request body services.RouteStationsRequest true "站序"
+// @Success 200 {array} models.RouteStation
+// @Router /api/v1/routes/{id}/stations [put]
+func (c *RouteController) SetRouteStations(ctx *gin.Context) {
+	id, ok := parseRouteID(ctx)
+	if !ok {
+		return
+	}
+	var req services.RouteStationsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	routeStations, err := c.networkService.SetRouteStations(id, req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, routeStations)
+}
+
+func parseRouteID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(ctx, "线路ID格式错误")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"TapTransit-backend/services"
+	"TapTransit-backend/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StationController struct {
+	networkService *services.NetworkService
+}
+
+func NewStationController(networkService *services.NetworkService) *StationController {
+	return &StationController{
+		networkService: networkService,
+	}
+}
+
+// ListStations 查询站点列表
+// @Summary 查询站点列表
+// @Description 查询站点（默认仅返回启用站点）
+// @Tags 站点管理
+// @Produce json
+// @Param status query string false "状态（active/inactive/all，默认active）"
+// @Param keyword query string false "站点编号或名称关键字"
+// @Success 200 {array} models.Station
+// @Router /api/v1/stations [get]
+func (c *StationController) ListStations(ctx *gin.Context) {
+	stations, err := c.networkService.ListStations(ctx.Query("status"), ctx.Query("keyword"))
+	if err != nil {
+		utils.InternalServerError(ctx, "查询失败")
+		return
+	}
+	utils.Success(ctx, stations)
+}
+
+// CreateStation 新增站点
+// @Summary 新增站点
+// @Description 新增站点，校验站点编号唯一及经纬度范围
+// @Tags 站点管理
+// @Accept json
+// @Produce json
+// @Param request body services.StationRequest true "站点信息"
+// @Success 200 {object} models.Station
+// @Router /api/v1/stations [post]
+func (c *StationController) CreateStation(ctx *gin.Context) {
+	var req services.StationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	station, err := c.networkService.CreateStation(req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, station)
+}
+
+// UpdateStation 修改站点
+// @Summary 修改站点
+// @Description 修改站点基本信息
+// @Tags 站点管理
+// @Accept json
+// @Produce json
+// @Param id path int true "站点ID"
+// @Param request body services.StationRequest true "站点信息"
+// @Success 200 {object} models.Station
+// @Router /api/v1/stations/{id} [put]
+func (c *StationController) UpdateStation(ctx *gin.Context) {
+	id, ok := parseStationID(ctx)
+	if !ok {
+		return
+	}
+	var req services.StationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	station, err := c.networkService.UpdateStation(id, req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, station)
+}
+
+// DeactivateStation 停用站点
+// @Summary 停用站点
+// @Description 停用站点（站点仍在启用线路的站序中时拒绝停用）
+// @Tags 站点管理
+// @Produce json
+// @Param id path int true "站点ID"
+// @Success 200 {object} models.Station
+// @Router /api/v1/stations/{id}/deactivate [post]
+func (c *StationController) DeactivateStation(ctx *gin.Context) {
+	c.setStationStatus(ctx, "inactive")
+}
+
+// ActivateStation 启用站点
+// @Summary 启用站点
+// @Description 重新启用已停用的站点
+// @Tags 站点管理
+// @Produce json
+// @Param id path int true "站点ID"
+// @Success 200 {object} models.Station
+// @Router /api/v1/stations/{id}/activate [post]
+func (c *StationController) ActivateStation(ctx *gin.Context) {
+	c.setStationStatus(ctx, "active")
+}
+
+func (c *StationController) setStationStatus(ctx *gin.Context, status string) {
+	id, ok := parseStationID(ctx)
+	if !ok {
+		return
+	}
+	station, err := c.networkService.SetStationStatus(id, status)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, station)
+}
+
+func parseStationID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(ctx, "站点ID格式错误")
+		return 0, false
+	}
+	return uint(id), true
+}
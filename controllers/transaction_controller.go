@@ -88,3 +88,45 @@ func (c *TransactionController) GetTransactions(ctx *gin.Context) {
 		},
 	})
 }
+
+// GetFareBreakdown 查询交易的计费明细
+// @Summary 查询交易计费明细
+// @Description 返回交易实收金额的计算过程（命中的票价、换乘、优惠规则及各阶段金额变化）
+// @Tags 交易记录
+// @Produce json
+// @Param id path int true "交易ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/transactions/{id}/fare-breakdown [get]
+func (c *TransactionController) GetFareBreakdown(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(ctx, "交易ID格式错误")
+		return
+	}
+
+	var transaction models.Transaction
+	if err := utils.DB.First(&transaction, id).Error; err != nil {
+		utils.NotFound(ctx, "交易记录不存在")
+		return
+	}
+
+	trace := transaction.FareTrace
+	if trace == nil {
+		// 待完成的交易或历史交易没有计费明细
+		trace = models.FareTrace{}
+	}
+
+	utils.Success(ctx, gin.H{
+		"transaction_id":  transaction.ID,
+		"record_id":       transaction.RecordID,
+		"card_id":         transaction.CardID,
+		"route_id":        transaction.RouteID,
+		"status":          transaction.Status,
+		"fare":            transaction.Fare,
+		"actual_fare":     transaction.ActualFare,
+		"discount_amount": transaction.DiscountAmount,
+		"discount_type":   transaction.DiscountType,
+		"penalty_fare":    transaction.PenaltyFare,
+		"breakdown":       trace,
+	})
+}
@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"TapTransit-backend/services"
+	"TapTransit-backend/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VehicleController struct {
+	vehicleService *services.VehicleService
+}
+
+func NewVehicleController(vehicleService *services.VehicleService) *VehicleController {
+	return &VehicleController{
+		vehicleService: vehicleService,
+	}
+}
+
+// ListVehicles 查询车辆列表
+// @Summary 查询车辆列表
+// @Description 查询车辆（status为空时返回全部）
+// @Tags 车辆管理
+// @Produce json
+// @Param status query string false "状态（active/inactive/maintenance）"
+// @Success 200 {array} models.Vehicle
+// @Router /api/v1/vehicles [get]
+func (c *VehicleController) ListVehicles(ctx *gin.Context) {
+	vehicles, err := c.vehicleService.ListVehicles(ctx.Query("status"))
+	if err != nil {
+		utils.InternalServerError(ctx, "查询失败")
+		return
+	}
+	utils.Success(ctx, vehicles)
+}
+
+// CreateVehicle 新增车辆
+// @Summary 新增车辆
+// @Description 新增车辆，校验车辆编号唯一
+// @Tags 车辆管理
+// @Accept json
+// @Produce json
+// @Param request body services.VehicleRequest true "车辆信息"
+// @Success 200 {object} models.Vehicle
+// @Router /api/v1/vehicles [post]
+func (c *VehicleController) CreateVehicle(ctx *gin.Context) {
+	var req services.VehicleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	vehicle, err := c.vehicleService.CreateVehicle(req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, vehicle)
+}
+
+// UpdateVehicle 修改车辆
+// @Summary 修改车辆
+// @Description 修改车辆信息（车辆编号同步到已绑定的网关设备）
+// @Tags 车辆管理
+// @Accept json
+// @Produce json
+// @Param id path int true "车辆ID"
+// @Param request body services.VehicleRequest true "车辆信息"
+// @Success 200 {object} models.Vehicle
+// @Router /api/v1/vehicles/{id} [put]
+func (c *VehicleController) UpdateVehicle(ctx *gin.Context) {
+	id, ok := parseVehicleID(ctx)
+	if !ok {
+		return
+	}
+	var req services.VehicleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	vehicle, err := c.vehicleService.UpdateVehicle(id, req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, vehicle)
+}
+
+// BindDevice 绑定网关设备
+// @Summary 绑定网关设备
+// @Description 将网关设备绑定到车辆，上传记录未携带线路时按车辆排班确定线路
+// @Tags 车辆管理
+// @Accept json
+// @Produce json
+// @Param id path int true "车辆ID"
+// @Param request body services.BindDeviceRequest true "设备信息"
+// @Success 200 {object} models.Device
+// @Router /api/v1/vehicles/{id}/devices [post]
+func (c *VehicleController) BindDevice(ctx *gin.Context) {
+	id, ok := parseVehicleID(ctx)
+	if !ok {
+		return
+	}
+	var req services.BindDeviceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	device, err := c.vehicleService.BindDevice(id, req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, device)
+}
+
+// ListAssignments 查询车辆排班
+// @Summary 查询车辆排班
+// @Description 查询车辆的线路排班（按开始时间倒序）
+// @Tags 车辆管理
+// @Produce json
+// @Param id path int true "车辆ID"
+// @Success 200 {array} models.VehicleAssignment
+// @Router /api/v1/vehicles/{id}/assignments [get]
+func (c *VehicleController) ListAssignments(ctx *gin.Context) {
+	id, ok := parseVehicleID(ctx)
+	if !ok {
+		return
+	}
+	assignments, err := c.vehicleService.ListAssignments(id)
+	if err != nil {
+		utils.InternalServerError(ctx, "查询失败")
+		return
+	}
+	utils.Success(ctx, assignments)
+}
+
+// CreateAssignment 新增车辆排班
+// @Summary 新增车辆排班
+// @Description 指定车辆在时间段内运营的线路与方向，同一车辆的排班时间段不能重叠
+// @Tags 车辆管理
+// @Accept json
+// @Produce json
+// @Param id path int true "车辆ID"
+// @Param request body services.AssignmentRequest true "排班信息"
+// @Success 200 {object} models.VehicleAssignment
+// @Router /api/v1/vehicles/{id}/assignments [post]
+func (c *VehicleController) CreateAssignment(ctx *gin.Context) {
+	id, ok := parseVehicleID(ctx)
+	if !ok {
+		return
+	}
+	var req services.AssignmentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	assignment, err := c.vehicleService.CreateAssignment(id, req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, assignment)
+}
+
+// EndAssignment 结束车辆排班
+// @Summary 结束车辆排班
+// @Description 结束车辆排班（未指定结束时间时以当前时间结束）
+// @Tags 车辆管理
+// @Accept json
+// @Produce json
+// @Param id path int true "车辆ID"
+// @Param assignmentId path int true "排班ID"
+// @Param request body services.EndAssignmentRequest false "结束时间"
+// @Success 200 {object} models.VehicleAssignment
+// @Router /api/v1/vehicles/{id}/assignments/{assignmentId}/end [post]
+func (c *VehicleController) EndAssignment(ctx *gin.Context) {
+	id, ok := parseVehicleID(ctx)
+	if !ok {
+		return
+	}
+	assignmentID, err := strconv.ParseUint(ctx.Param("assignmentId"), 10, 64)
+	if err != nil {
+		utils.BadRequest(ctx, "排班ID格式错误")
+		return
+	}
+	var req services.EndAssignmentRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			utils.BadRequest(ctx, "请求参数错误: "+err.Error())
+			return
+		}
+	}
+
+	assignment, err := c.vehicleService.EndAssignment(id, uint(assignmentID), req)
+	if err != nil {
+		utils.BadRequest(ctx, err.Error())
+		return
+	}
+	utils.Success(ctx, assignment)
+}
+
+func parseVehicleID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(ctx, "车辆ID格式错误")
+		return 0, false
+	}
+	return uint(id), true
+}
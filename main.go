@@ -47,6 +47,8 @@ func main() {
 	penaltyService := services.NewPenaltyService(db, fareService)
 	cacheService := services.NewCacheService(db)
 	cleanupService := services.NewCleanupService(db)
+	fareChangeService := services.NewFareChangeService(db)
+	deviceService := services.NewDeviceService(db)
 
 	// 启动配置缓存刷新定时任务（每5分钟刷新一次）
 	cacheService.StartCacheRefreshTask(5)
@@ -60,6 +62,14 @@ func main() {
 	cleanupService.StartCleanupTask(24, 7)
 	log.Println("数据清理定时任务已启动（每24小时执行，保留7天）")
 
+	// 启动票价配置变更定时生效任务（每分钟检查一次）
+	fareChangeService.StartScheduler(1)
+	log.Println("票价配置变更定时生效任务已启动（每分钟检查）")
+
+	// 启动设备离线检查定时任务（每分钟检查一次，10分钟未上线视为离线）
+	deviceService.StartOfflineChecker(1, 10)
+	log.Println("设备离线检查定时任务已启动（每分钟检查，10分钟未上线视为离线）")
+
 	// 创建Gin引擎
 	r := gin.Default()
 
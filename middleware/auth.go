@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"TapTransit-backend/models"
+	"TapTransit-backend/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentUserKey 上下文中保存当前用户的键
+const currentUserKey = "current_user"
+
+// AuthRequired 校验登录令牌（Authorization: Bearer <token>），并将当前用户保存到上下文
+func AuthRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+		if token == "" {
+			utils.Unauthorized(c, "未登录")
+			c.Abort()
+			return
+		}
+
+		var session models.UserSession
+		if err := utils.DB.Where("token_hash = ? AND expires_at > ?", utils.HashToken(token), time.Now()).First(&session).Error; err != nil {
+			utils.Unauthorized(c, "登录已失效，请重新登录")
+			c.Abort()
+			return
+		}
+		var user models.User
+		if err := utils.DB.First(&user, session.UserID).Error; err != nil || user.Status != "active" {
+			utils.Unauthorized(c, "用户不存在或已停用")
+			c.Abort()
+			return
+		}
+
+		c.Set(currentUserKey, &user)
+		c.Next()
+	}
+}
+
+// RequireRole 要求当前用户具有指定角色之一（需在AuthRequired之后使用）
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c)
+		if user == nil {
+			utils.Unauthorized(c, "未登录")
+			c.Abort()
+			return
+		}
+		for _, role := range roles {
+			if user.Role == role {
+				c.Next()
+				return
+			}
+		}
+		utils.Forbidden(c, "当前用户没有此操作的权限")
+		c.Abort()
+	}
+}
+
+// CurrentUser 获取当前登录用户（未登录时返回nil）
+func CurrentUser(c *gin.Context) *models.User {
+	value, ok := c.Get(currentUserKey)
+	if !ok {
+		return nil
+	}
+	user, _ := value.(*models.User)
+	return user
+}
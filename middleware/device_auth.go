@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"TapTransit-backend/models"
+	"TapTransit-backend/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上下文中保存当前设备及其凭证的键
+const (
+	currentDeviceKey           = "current_device"
+	currentDeviceCredentialKey = "current_device_credential"
+)
+
+// 设备凭证校验失败的错误代码
+const (
+	CodeDeviceCredentialMissing = "DEVICE_CREDENTIAL_MISSING" // 缺少设备凭证
+	CodeDeviceCredentialInvalid = "DEVICE_CREDENTIAL_INVALID" // 设备凭证无效或已吊销
+	CodeDeviceInactive          = "DEVICE_INACTIVE"           // 设备不存在或已停用
+)
+
+// DeviceAuthRequired 校验网关设备凭证（Authorization: Bearer <设备凭证>），并将当前设备保存到上下文
+func DeviceAuthRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+		if token == "" {
+			abortWithCode(c, http.StatusUnauthorized, CodeDeviceCredentialMissing, "缺少设备凭证")
+			return
+		}
+
+		var credential models.DeviceCredential
+		if err := utils.DB.Where("token_hash = ? AND status = ?", utils.HashToken(token), "active").First(&credential).Error; err != nil {
+			abortWithCode(c, http.StatusUnauthorized, CodeDeviceCredentialInvalid, "设备凭证无效或已吊销")
+			return
+		}
+		var device models.Device
+		if err := utils.DB.First(&device, credential.DeviceID).Error; err != nil || device.Status == "inactive" {
+			abortWithCode(c, http.StatusUnauthorized, CodeDeviceInactive, "设备不存在或已停用")
+			return
+		}
+		utils.DB.Model(&credential).UpdateColumn("last_used_at", time.Now())
+
+		c.Set(currentDeviceKey, &device)
+		c.Set(currentDeviceCredentialKey, &credential)
+		c.Next()
+	}
+}
+
+// CurrentDevice 获取当前认证的网关设备（未认证时返回nil）
+func CurrentDevice(c *gin.Context) *models.Device {
+	value, ok := c.Get(currentDeviceKey)
+	if !ok {
+		return nil
+	}
+	device, _ := value.(*models.Device)
+	return device
+}
+
+// abortWithCode 返回带错误代码的错误响应并中止请求
+func abortWithCode(c *gin.Context, status int, code string, message string) {
+	utils.ErrorWithCode(c, status, code, message)
+	c.Abort()
+}
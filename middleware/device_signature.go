@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"TapTransit-backend/models"
+	"TapTransit-backend/utils"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// 网关请求签名的请求头
+const (
+	HeaderTimestamp = "X-Timestamp" // 签名时间（Unix秒）
+	HeaderNonce     = "X-Nonce"     // 随机数（每个请求不同）
+	HeaderSignature = "X-Signature" // HMAC-SHA256签名（十六进制）
+)
+
+// signatureTolerance 签名时间与服务器时间允许的最大偏差，随机数在两倍容差内不能重复使用
+const signatureTolerance = 5 * time.Minute
+
+// 请求签名校验失败的错误代码
+const (
+	CodeSignatureMissing     = "SIGNATURE_MISSING"      // 缺少签名请求头
+	CodeSigningKeyMissing    = "SIGNING_KEY_MISSING"    // 设备凭证没有签名密钥（需重新注册）
+	CodeTimestampInvalid     = "TIMESTAMP_INVALID"      // 签名时间格式错误
+	CodeTimestampExpired     = "TIMESTAMP_EXPIRED"      // 签名时间超出允许偏差
+	CodeNonceInvalid         = "NONCE_INVALID"          // 随机数格式错误
+	CodeSignatureInvalid     = "SIGNATURE_INVALID"      // 签名不匹配
+	CodeNonceReused          = "NONCE_REUSED"           // 随机数已使用（重放请求）
+	CodeSignatureCheckFailed = "SIGNATURE_CHECK_FAILED" // 服务器校验签名出错
+)
+
+// DeviceSignatureRequired 校验网关请求签名并防止重放（需在DeviceAuthRequired之后使用）
+//
+// 签名内容为 METHOD\nPATH\nTIMESTAMP\nNONCE\nSHA256(BODY)（请求体摘要为十六进制小写），
+// 使用设备注册时下发的签名密钥计算HMAC-SHA256。
+func DeviceSignatureRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timestamp := c.GetHeader(HeaderTimestamp)
+		nonce := c.GetHeader(HeaderNonce)
+		signature := c.GetHeader(HeaderSignature)
+		if timestamp == "" || nonce == "" || signature == "" {
+			abortWithCode(c, http.StatusUnauthorized, CodeSignatureMissing, "缺少签名请求头（X-Timestamp、X-Nonce、X-Signature）")
+			return
+		}
+
+		device := CurrentDevice(c)
+		credential := currentDeviceCredential(c)
+		if device == nil || credential == nil {
+			abortWithCode(c, http.StatusUnauthorized, CodeDeviceCredentialMissing, "缺少设备凭证")
+			return
+		}
+		if credential.SigningSecret == "" {
+			abortWithCode(c, http.StatusUnauthorized, CodeSigningKeyMissing, "设备凭证没有签名密钥，请重新注册设备")
+			return
+		}
+
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			abortWithCode(c, http.StatusUnauthorized, CodeTimestampInvalid, "签名时间格式错误（应为Unix秒）")
+			return
+		}
+		now := time.Now()
+		skew := now.Sub(time.Unix(seconds, 0))
+		if skew > signatureTolerance || skew < -signatureTolerance {
+			abortWithCode(c, http.StatusUnauthorized, CodeTimestampExpired, "签名时间与服务器时间相差超过5分钟，请校准设备时钟")
+			return
+		}
+		if len(nonce) < 8 || len(nonce) > 64 {
+			abortWithCode(c, http.StatusUnauthorized, CodeNonceInvalid, "随机数长度应为8-64个字符")
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortWithCode(c, http.StatusBadRequest, CodeSignatureCheckFailed, "读取请求体失败")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		expected := deviceSignature(credential.SigningSecret, c.Request.Method, c.Request.URL.Path, timestamp, nonce, body)
+		provided, err := hex.DecodeString(strings.ToLower(signature))
+		if err != nil || !hmac.Equal(provided, expected) {
+			abortWithCode(c, http.StatusUnauthorized, CodeSignatureInvalid, "请求签名不匹配")
+			return
+		}
+
+		// 签名通过后再登记随机数，避免伪造请求占用随机数
+		fresh, err := nonceClaimer(device.ID, nonce, 2*signatureTolerance)
+		if err != nil {
+			abortWithCode(c, http.StatusInternalServerError, CodeSignatureCheckFailed, "登记随机数失败")
+			return
+		}
+		if !fresh {
+			abortWithCode(c, http.StatusUnauthorized, CodeNonceReused, "随机数已使用，请求被拒绝")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// deviceSignature 计算网关请求签名
+func deviceSignature(secret, method, path, timestamp, nonce string, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+	payload := strings.Join([]string{method, path, timestamp, nonce, hex.EncodeToString(bodyHash[:])}, "\n")
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// nonceClaimer 登记随机数的方法（测试时替换为内存实现）
+var nonceClaimer = claimNonce
+
+// claimNonce 登记随机数，已使用过时返回false
+// 随机数只记录在数据库中（device_id与nonce唯一，过期记录由清理任务删除），不因缓存可用与否出现不同的判断
+func claimNonce(deviceID uint, nonce string, ttl time.Duration) (bool, error) {
+	record := models.DeviceNonce{DeviceID: deviceID, Nonce: nonce, ExpiresAt: time.Now().Add(ttl)}
+	result := utils.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// currentDeviceCredential 获取当前请求使用的设备凭证
+func currentDeviceCredential(c *gin.Context) *models.DeviceCredential {
+	value, ok := c.Get(currentDeviceCredentialKey)
+	if !ok {
+		return nil
+	}
+	credential, _ := value.(*models.DeviceCredential)
+	return credential
+}
@@ -0,0 +1,252 @@
+package middleware
+
+import (
+	"TapTransit-backend/models"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testSigningSecret = "test-signing-secret"
+
+// memoryNonceClaimer 内存实现的随机数登记（按设备区分）
+func memoryNonceClaimer() func(uint, string, time.Duration) (bool, error) {
+	claimed := make(map[string]bool)
+	return func(deviceID uint, nonce string, ttl time.Duration) (bool, error) {
+		key := fmt.Sprintf("%d:%s", deviceID, nonce)
+		if claimed[key] {
+			return false, nil
+		}
+		claimed[key] = true
+		return true, nil
+	}
+}
+
+// newSignatureRouter 模拟DeviceAuthRequired已写入设备与凭证后再校验签名
+func newSignatureRouter(secret string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(currentDeviceKey, &models.Device{ID: 7})
+		c.Set(currentDeviceCredentialKey, &models.DeviceCredential{SigningSecret: secret})
+		c.Next()
+	})
+	router.Use(DeviceSignatureRequired())
+	router.POST("/api/v1/gateway/taps", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+// signedRequest 按网关签名规则构造请求
+func signedRequest(secret, path, body string, at time.Time, nonce string) *http.Request {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, hex.EncodeToString(deviceSignature(secret, http.MethodPost, path, timestamp, nonce, []byte(body))))
+	return req
+}
+
+func serve(router *gin.Engine, req *http.Request) (int, string) {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp.Code
+}
+
+func TestDeviceSignaturePayload(t *testing.T) {
+	body := []byte(`{"card_id":"C001"}`)
+	bodyHash := sha256.Sum256(body)
+	payload := "POST\n/api/v1/gateway/taps\n1700000000\nnonce-0001\n" + hex.EncodeToString(bodyHash[:])
+	mac := hmac.New(sha256.New, []byte(testSigningSecret))
+	mac.Write([]byte(payload))
+
+	got := deviceSignature(testSigningSecret, "POST", "/api/v1/gateway/taps", "1700000000", "nonce-0001", body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		t.Errorf("签名 = %x，应为 %x", got, mac.Sum(nil))
+	}
+
+	// 签名内容任一部分变化，签名都应不同
+	variants := map[string][]byte{
+		"方法":  deviceSignature(testSigningSecret, "PUT", "/api/v1/gateway/taps", "1700000000", "nonce-0001", body),
+		"路径":  deviceSignature(testSigningSecret, "POST", "/api/v1/gateway/tap", "1700000000", "nonce-0001", body),
+		"时间":  deviceSignature(testSigningSecret, "POST", "/api/v1/gateway/taps", "1700000001", "nonce-0001", body),
+		"随机数": deviceSignature(testSigningSecret, "POST", "/api/v1/gateway/taps", "1700000000", "nonce-0002", body),
+		"请求体": deviceSignature(testSigningSecret, "POST", "/api/v1/gateway/taps", "1700000000", "nonce-0001", []byte(`{}`)),
+		"密钥":  deviceSignature("other-secret", "POST", "/api/v1/gateway/taps", "1700000000", "nonce-0001", body),
+	}
+	for name, variant := range variants {
+		if hmac.Equal(got, variant) {
+			t.Errorf("修改%s后签名未变化", name)
+		}
+	}
+}
+
+func TestDeviceSignatureRequired(t *testing.T) {
+	const path = "/api/v1/gateway/taps"
+	const body = `{"card_id":"C001"}`
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		secret     string
+		request    func() *http.Request
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "签名正确",
+			secret:     testSigningSecret,
+			request:    func() *http.Request { return signedRequest(testSigningSecret, path, body, now, "nonce-0001") },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "缺少签名请求头",
+			secret: testSigningSecret,
+			request: func() *http.Request {
+				req := signedRequest(testSigningSecret, path, body, now, "nonce-0001")
+				req.Header.Del(HeaderSignature)
+				return req
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   CodeSignatureMissing,
+		},
+		{
+			name:       "设备凭证没有签名密钥",
+			secret:     "",
+			request:    func() *http.Request { return signedRequest(testSigningSecret, path, body, now, "nonce-0001") },
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   CodeSigningKeyMissing,
+		},
+		{
+			name:   "容差内的时钟偏差",
+			secret: testSigningSecret,
+			request: func() *http.Request {
+				return signedRequest(testSigningSecret, path, body, now.Add(-4*time.Minute), "nonce-0001")
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "签名时间过早",
+			secret: testSigningSecret,
+			request: func() *http.Request {
+				return signedRequest(testSigningSecret, path, body, now.Add(-6*time.Minute), "nonce-0001")
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   CodeTimestampExpired,
+		},
+		{
+			name:   "签名时间超前",
+			secret: testSigningSecret,
+			request: func() *http.Request {
+				return signedRequest(testSigningSecret, path, body, now.Add(6*time.Minute), "nonce-0001")
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   CodeTimestampExpired,
+		},
+		{
+			name:   "签名时间格式错误",
+			secret: testSigningSecret,
+			request: func() *http.Request {
+				req := signedRequest(testSigningSecret, path, body, now, "nonce-0001")
+				req.Header.Set(HeaderTimestamp, "2024-01-01T00:00:00Z")
+				return req
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   CodeTimestampInvalid,
+		},
+		{
+			name:       "随机数过短",
+			secret:     testSigningSecret,
+			request:    func() *http.Request { return signedRequest(testSigningSecret, path, body, now, "n1") },
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   CodeNonceInvalid,
+		},
+		{
+			name:       "密钥不一致",
+			secret:     testSigningSecret,
+			request:    func() *http.Request { return signedRequest("other-secret", path, body, now, "nonce-0001") },
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   CodeSignatureInvalid,
+		},
+		{
+			name:   "请求体被篡改",
+			secret: testSigningSecret,
+			request: func() *http.Request {
+				req := signedRequest(testSigningSecret, path, body, now, "nonce-0001")
+				tampered := signedRequest(testSigningSecret, path, `{"card_id":"C002"}`, now, "nonce-0001")
+				tampered.Header = req.Header
+				return tampered
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   CodeSignatureInvalid,
+		},
+		{
+			name:   "签名不是十六进制",
+			secret: testSigningSecret,
+			request: func() *http.Request {
+				req := signedRequest(testSigningSecret, path, body, now, "nonce-0001")
+				req.Header.Set(HeaderSignature, "not-hex")
+				return req
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   CodeSignatureInvalid,
+		},
+	}
+
+	defer func(original func(uint, string, time.Duration) (bool, error)) { nonceClaimer = original }(nonceClaimer)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonceClaimer = memoryNonceClaimer()
+			status, code := serve(newSignatureRouter(tt.secret), tt.request())
+			if status != tt.wantStatus || code != tt.wantCode {
+				t.Errorf("响应 = %d %q，应为 %d %q", status, code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestDeviceSignatureRejectsNonceReplay(t *testing.T) {
+	const path = "/api/v1/gateway/taps"
+	const body = `{"card_id":"C001"}`
+	defer func(original func(uint, string, time.Duration) (bool, error)) { nonceClaimer = original }(nonceClaimer)
+	nonceClaimer = memoryNonceClaimer()
+	router := newSignatureRouter(testSigningSecret)
+	now := time.Now()
+
+	// 签名错误的请求不占用随机数
+	if status, code := serve(router, signedRequest("other-secret", path, body, now, "nonce-0001")); code != CodeSignatureInvalid {
+		t.Fatalf("伪造请求响应 = %d %q，应为签名不匹配", status, code)
+	}
+
+	steps := []struct {
+		name       string
+		request    *http.Request
+		wantStatus int
+		wantCode   string
+	}{
+		{"首次使用随机数", signedRequest(testSigningSecret, path, body, now, "nonce-0001"), http.StatusOK, ""},
+		{"重放同一请求", signedRequest(testSigningSecret, path, body, now, "nonce-0001"), http.StatusUnauthorized, CodeNonceReused},
+		{"重新签名但复用随机数", signedRequest(testSigningSecret, path, body, now.Add(time.Second), "nonce-0001"), http.StatusUnauthorized, CodeNonceReused},
+		{"使用新随机数", signedRequest(testSigningSecret, path, body, now, "nonce-0002"), http.StatusOK, ""},
+	}
+	for _, step := range steps {
+		if status, code := serve(router, step.request); status != step.wantStatus || code != step.wantCode {
+			t.Errorf("%s：响应 = %d %q，应为 %d %q", step.name, status, code, step.wantStatus, step.wantCode)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Campaign 优惠活动（主管部门宣布的免费/优惠出行、市场推广的首乘免费等）
+type Campaign struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	CampaignCode string `gorm:"uniqueIndex;not null;size:50" json:"campaign_code"` // 活动编号
+	Name         string `gorm:"size:100;not null" json:"name"`                     // 活动名称
+	CampaignType string `gorm:"size:20;default:'promotion'" json:"campaign_type"`  // 活动类型：service(主管部门宣布的出行优惠), promotion(市场推广)
+	RouteIDs     string `gorm:"size:500" json:"route_ids"`                         // 适用线路ID（逗号分隔，为空表示全部线路）
+	StationIDs   string `gorm:"size:500" json:"station_ids"`                       // 适用上车站点ID（逗号分隔，为空表示全部站点）
+	CardTypes    string `gorm:"size:200" json:"card_types"`                        // 适用卡类型（逗号分隔，为空表示全部）
+
+	StartTime time.Time `gorm:"index;not null" json:"start_time"` // 开始时间
+	EndTime   time.Time `gorm:"index;not null" json:"end_time"`   // 结束时间（不含）
+
+	DiscountType   string  `gorm:"size:20;not null" json:"discount_type"`               // 优惠方式：free(免费), amount(固定优惠金额), rate(按比例优惠), fixed_fare(固定票价)
+	DiscountAmount Money   `gorm:"type:decimal(10,2);default:0" json:"discount_amount"` // 优惠金额（amount）或固定票价（fixed_fare）
+	DiscountRate   float64 `gorm:"type:decimal(5,4);default:0" json:"discount_rate"`    // 优惠比例（rate，0-1之间）
+
+	FirstRideOnly   bool   `gorm:"default:false" json:"first_ride_only"`       // 仅限卡片首次乘车（新卡首乘优惠）
+	Budget          Money  `gorm:"type:decimal(12,2);default:0" json:"budget"` // 优惠总预算（0表示不限）
+	MaxRides        int    `gorm:"default:0" json:"max_rides"`                 // 优惠总次数上限（0表示不限）
+	MaxRidesPerCard int    `gorm:"default:0" json:"max_rides_per_card"`        // 每张卡优惠次数上限（0表示不限）
+	Priority        int    `gorm:"default:0" json:"priority"`                  // 优先级（多个活动优惠金额相同时优先级高者优先）
+	Status          string `gorm:"size:20;default:'active'" json:"status"`     // 状态：active, inactive
+
+	UsedAmount Money `gorm:"type:decimal(12,2);default:0" json:"used_amount"` // 已预留的优惠金额（计费时按预算条件原子累加）
+	UsedRides  int   `gorm:"default:0" json:"used_rides"`                     // 已预留的优惠次数（计费时按次数上限条件原子累加）
+}
+
+// TableName 指定表名
+func (Campaign) TableName() string {
+	return "campaigns"
+}
+
+// CampaignRedemption 优惠活动核销记录（每次享受活动优惠的乘车）
+type CampaignRedemption struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	CampaignID     uint      `gorm:"not null;uniqueIndex:idx_campaign_redemption" json:"campaign_id"` // 活动ID
+	RecordID       string    `gorm:"size:100;uniqueIndex:idx_campaign_redemption" json:"record_id"`   // 交易记录ID（同一交易同一活动只核销一次）
+	CardID         string    `gorm:"index;not null;size:32" json:"card_id"`                           // 卡ID
+	RouteID        uint      `gorm:"index" json:"route_id"`                                           // 线路ID
+	DiscountAmount Money     `gorm:"type:decimal(10,2);not null" json:"discount_amount"`              // 优惠金额
+	RedeemedAt     time.Time `gorm:"index;not null" json:"redeemed_at"`                               // 乘车时间
+}
+
+// TableName 指定表名
+func (CampaignRedemption) TableName() string {
+	return "campaign_redemptions"
+}
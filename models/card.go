@@ -13,11 +13,11 @@ type Card struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
-	CardID    string `gorm:"uniqueIndex;not null;size:32" json:"card_id"` // 卡片UID
-	HolderName string `gorm:"size:100" json:"holder_name"`                // 持有人姓名（可选）
-	CardType  string `gorm:"size:50;default:'normal'" json:"card_type"`   // 卡类型：normal, student, elder, disabled等
-	Status    string `gorm:"size:20;default:'active'" json:"status"`      // 状态：active, blocked, lost
-	Balance   float64 `gorm:"default:0" json:"balance"`                   // 卡内余额（如果支持电子钱包）
+	CardID     string `gorm:"uniqueIndex;not null;size:32" json:"card_id"` // 卡片UID
+	HolderName string `gorm:"size:100" json:"holder_name"`                 // 持有人姓名（可选）
+	CardType   string `gorm:"size:50;default:'normal'" json:"card_type"`   // 卡类型：normal, student, elder, disabled等
+	Status     string `gorm:"size:20;default:'active'" json:"status"`      // 状态：active, blocked, lost
+	Balance    Money  `gorm:"type:decimal(10,2);default:0" json:"balance"` // 卡内余额（如果支持电子钱包）
 }
 
 // TableName 指定表名
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CompanionRule 陪同人员优惠规则（如残疾人卡可免费携带一名陪同人员）
+type CompanionRule struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	CardType      string  `gorm:"size:50;not null;index" json:"card_type"`          // 持卡人卡类型
+	MaxCompanions int     `gorm:"default:1" json:"max_companions"`                  // 享受优惠的陪同人数上限（超出部分按普通乘客计费）
+	DiscountRate  float64 `gorm:"type:decimal(5,4);default:1" json:"discount_rate"` // 陪同人员优惠比例（1表示免费）
+	Status        string  `gorm:"size:20;default:'active'" json:"status"`           // 状态：active, inactive
+}
+
+// TableName 指定表名
+func (CompanionRule) TableName() string {
+	return "companion_rules"
+}
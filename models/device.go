@@ -13,11 +13,22 @@ type Device struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
-	DeviceID  string `gorm:"uniqueIndex;not null;size:50" json:"device_id"` // 设备ID
-	DeviceType string `gorm:"size:50;default:'gateway'" json:"device_type"` // 设备类型：gateway, reader
-	VehicleNumber string `gorm:"size:50" json:"vehicle_number"`            // 车辆编号
-	Status    string `gorm:"size:20;default:'active'" json:"status"`       // 状态：active, inactive, maintenance
-	LastSeen  *time.Time `json:"last_seen"`                                // 最后在线时间
+	DeviceID      string     `gorm:"uniqueIndex;not null;size:50" json:"device_id"` // 设备ID
+	DeviceType    string     `gorm:"size:50;default:'gateway'" json:"device_type"`  // 设备类型：gateway, reader
+	VehicleNumber string     `gorm:"size:50" json:"vehicle_number"`                 // 车辆编号（绑定车辆时同步为车辆的编号）
+	VehicleID     *uint      `gorm:"index" json:"vehicle_id"`                       // 安装的车辆ID
+	Status        string     `gorm:"size:20;default:'active'" json:"status"`        // 状态：active, inactive, maintenance
+	LastSeen      *time.Time `json:"last_seen"`                                     // 最后在线时间（心跳或上传记录时刷新）
+
+	ConnectionStatus string     `gorm:"size:20;default:'unknown';index" json:"connection_status"` // 连接状态：unknown（从未上线）, online, offline
+	OfflineSince     *time.Time `json:"offline_since"`                                            // 判定离线时的最后在线时间
+	FirmwareVersion  string     `gorm:"size:50" json:"firmware_version"`                          // 固件版本（心跳上报）
+	ConfigVersion    string     `gorm:"size:50" json:"config_version"`                            // 网关当前使用的配置版本（心跳上报）
+	QueueDepth       int        `gorm:"default:0" json:"queue_depth"`                             // 待上传记录数（心跳上报）
+	ClockOffset      int64      `gorm:"default:0" json:"clock_offset"`                            // 设备时钟与服务器时钟之差（秒，正数表示设备时钟偏快）
+	Latitude         *float64   `gorm:"type:decimal(10,8)" json:"latitude"`                       // 最后上报的GPS纬度
+	Longitude        *float64   `gorm:"type:decimal(11,8)" json:"longitude"`                      // 最后上报的GPS经度
+	LastHeartbeat    *time.Time `json:"last_heartbeat"`                                           // 最后心跳时间
 }
 
 // TableName 指定表名
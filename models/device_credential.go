@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+// DeviceEnrollmentToken 设备注册令牌（管理员签发，网关一次性兑换设备凭证）
+type DeviceEnrollmentToken struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	TokenHash string     `gorm:"uniqueIndex;not null;size:64" json:"-"` // 令牌SHA-256摘要（明文只在签发时返回一次）
+	DeviceID  uint       `gorm:"index;not null" json:"device_id"`       // 设备ID（devices.id）
+	CreatedBy uint       `gorm:"not null" json:"created_by"`            // 签发人用户ID
+	ExpiresAt time.Time  `gorm:"index;not null" json:"expires_at"`      // 过期时间
+	UsedAt    *time.Time `json:"used_at"`                               // 兑换时间（为空表示未使用）
+}
+
+// TableName 指定表名
+func (DeviceEnrollmentToken) TableName() string {
+	return "device_enrollment_tokens"
+}
+
+// DeviceCredential 设备凭证（网关上传记录、心跳时通过 Authorization: Bearer <凭证> 认证）
+type DeviceCredential struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TokenHash         string     `gorm:"uniqueIndex;not null;size:64" json:"-"`  // 凭证SHA-256摘要
+	SigningSecret     string     `gorm:"size:64" json:"-"`                       // 请求签名密钥（HMAC-SHA256，兑换时随凭证下发）
+	DeviceID          uint       `gorm:"index;not null" json:"device_id"`        // 设备ID（devices.id）
+	EnrollmentTokenID uint       `gorm:"not null" json:"enrollment_token_id"`    // 兑换的注册令牌ID
+	Status            string     `gorm:"size:20;default:'active'" json:"status"` // 状态：active, revoked
+	LastUsedAt        *time.Time `json:"last_used_at"`                           // 最后使用时间
+	RevokedAt         *time.Time `json:"revoked_at"`                             // 吊销时间
+}
+
+// TableName 指定表名
+func (DeviceCredential) TableName() string {
+	return "device_credentials"
+}
+
+// DeviceNonce 网关签名请求已使用的随机数（device_id与nonce唯一索引防重放，过期记录由清理任务删除）
+type DeviceNonce struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	DeviceID  uint      `gorm:"uniqueIndex:idx_device_nonce;not null" json:"device_id"`     // 设备ID（devices.id）
+	Nonce     string    `gorm:"uniqueIndex:idx_device_nonce;not null;size:64" json:"nonce"` // 随机数
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`                           // 过期时间（超过签名时间容差后可清理）
+}
+
+// TableName 指定表名
+func (DeviceNonce) TableName() string {
+	return "device_nonces"
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DiscountStackingPolicy 优惠叠加策略（定义各类优惠的应用顺序、互斥组与择优规则）
+type DiscountStackingPolicy struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	PolicyName      string `gorm:"size:100" json:"policy_name"`                   // 策略名称
+	RouteID         uint   `gorm:"index" json:"route_id"`                         // 线路ID（0表示运营方或全局默认策略）
+	Operator        string `gorm:"size:50;index" json:"operator"`                 // 运营方编码（route_id为0时按线路的operator生效，为空表示全局默认策略）
+	ApplyOrder      string `gorm:"size:200;not null" json:"apply_order"`          // 优惠应用顺序（逗号分隔），如"card_type,transfer,monthly"
+	ExclusiveGroups string `gorm:"size:200" json:"exclusive_groups"`              // 互斥组（组间分号分隔、组内逗号分隔），同组只取优惠金额最大的一项
+	SelectionMode   string `gorm:"size:20;default:'stack'" json:"selection_mode"` // 选择模式：stack(按顺序叠加), best_of(只取单项最优)
+	Status          string `gorm:"size:20;default:'active'" json:"status"`        // 状态：active, inactive
+}
+
+// TableName 指定表名
+func (DiscountStackingPolicy) TableName() string {
+	return "discount_stacking_policies"
+}
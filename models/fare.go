@@ -13,14 +13,18 @@ type Fare struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
-	RouteID      uint    `gorm:"index" json:"route_id"`                      // 线路ID（0表示通用规则）
-	StartStation uint    `gorm:"index" json:"start_station"`                 // 起始站点ID（0表示通用）
-	EndStation   uint    `gorm:"index" json:"end_station"`                   // 结束站点ID（0表示通用）
-	BasePrice    float64 `gorm:"type:decimal(10,2);not null" json:"base_price"` // 基础票价
-	FareType     string  `gorm:"size:50;default:'uniform'" json:"fare_type"`    // 计价类型：uniform(统一票价), segment(分段计价), distance(按距离)
-	SegmentCount int     `gorm:"default:0" json:"segment_count"`                // 区段数（用于分段计价）
-	ExtraPrice   float64 `gorm:"type:decimal(10,2);default:0" json:"extra_price"` // 续程价（分段计价用）
-	Status       string  `gorm:"size:20;default:'active'" json:"status"`         // 状态：active, inactive
+	RouteID      uint   `gorm:"index" json:"route_id"`                           // 线路ID（0表示通用规则）
+	StartStation uint   `gorm:"index" json:"start_station"`                      // 起始站点ID（0表示通用）
+	EndStation   uint   `gorm:"index" json:"end_station"`                        // 结束站点ID（0表示通用）
+	BasePrice    Money  `gorm:"type:decimal(10,2);not null" json:"base_price"`   // 基础票价
+	FareType     string `gorm:"size:50;default:'uniform'" json:"fare_type"`      // 计价类型：uniform(统一票价), segment(分段计价), distance(按距离), zone_count(按经过分区数)
+	SegmentCount int    `gorm:"default:0" json:"segment_count"`                  // 区段数（分段计价为起步站数，按分区数计价为起步分区数）
+	ExtraPrice   Money  `gorm:"type:decimal(10,2);default:0" json:"extra_price"` // 续程价（分段计价为每站，按距离计价为每个续程区间，按分区数计价为每增加一个分区）
+
+	BaseDistanceKm float64 `gorm:"type:decimal(10,3);default:0" json:"base_distance_km"` // 起步里程（公里，按距离计价时基础票价覆盖的里程）
+	BandKm         float64 `gorm:"type:decimal(10,3);default:0" json:"band_km"`          // 续程区间（公里，超出起步里程后每区间加收续程价，0表示按每公里）
+	CapPrice       Money   `gorm:"type:decimal(10,2);default:0" json:"cap_price"`        // 封顶票价（按距离计价用，0表示不封顶）
+	Status         string  `gorm:"size:20;default:'active'" json:"status"`               // 状态：active, inactive
 }
 
 // TableName 指定表名
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// FareAuditLog 票价配置变更审计记录（每项生效的变更一条，记录变更前后的值）
+type FareAuditLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	ChangeRequestID uint      `gorm:"index;not null" json:"change_request_id"`           // 变更申请ID
+	Entity          string    `gorm:"size:50;index:idx_fare_audit_entity" json:"entity"` // 变更对象：fare, transfer, discount_policy, route_max_fare
+	EntityID        uint      `gorm:"index:idx_fare_audit_entity" json:"entity_id"`      // 变更对象ID
+	Action          string    `gorm:"size:20" json:"action"`                             // 操作：create, update, delete
+	Before          JSONB     `gorm:"type:jsonb" json:"before"`                          // 变更前的值（create为空）
+	After           JSONB     `gorm:"type:jsonb" json:"after"`                           // 变更后的值（delete为空）
+	SubmittedBy     uint      `json:"submitted_by"`                                      // 提交人
+	ApprovedBy      uint      `json:"approved_by"`                                       // 审批人
+	AppliedAt       time.Time `gorm:"index" json:"applied_at"`                           // 生效时间
+}
+
+// TableName 指定表名
+func (FareAuditLog) TableName() string {
+	return "fare_audit_logs"
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FareChangeItem 变更申请中的单项变更
+type FareChangeItem struct {
+	Entity   string                 `json:"entity"`              // 变更对象：fare, transfer, discount_policy, route_max_fare
+	Action   string                 `json:"action"`              // 操作：create, update, delete（route_max_fare仅支持update）
+	EntityID uint                   `json:"entity_id,omitempty"` // 变更对象ID（update/delete）
+	Values   map[string]interface{} `json:"values,omitempty"`    // 字段值（create/update，字段名同接口JSON字段）
+}
+
+// FareChangeItems 变更申请的变更列表（以jsonb存储）
+type FareChangeItems []FareChangeItem
+
+// Value 实现driver.Valuer接口
+func (c FareChangeItems) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+// Scan 实现sql.Scanner接口
+func (c *FareChangeItems) Scan(value interface{}) error {
+	if value == nil {
+		*c = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, c)
+}
+
+// FareChangeRequest 票价配置变更申请（提交后需由另一名具有审批角色的用户审批，审批后按生效时间整体生效）
+type FareChangeRequest struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Title       string          `gorm:"size:200;not null" json:"title"`                // 标题
+	Description string          `gorm:"type:text" json:"description"`                  // 变更说明
+	Changes     FareChangeItems `gorm:"type:jsonb;not null" json:"changes"`            // 变更列表
+	Status      string          `gorm:"size:20;index;default:'pending'" json:"status"` // 状态：pending(待审批), scheduled(已审批待生效), applied(已生效), rejected(已驳回), cancelled(已撤回), failed(生效失败)
+	EffectiveAt *time.Time      `gorm:"index" json:"effective_at"`                     // 计划生效时间（为空表示审批后立即生效）
+	SubmittedBy uint            `gorm:"index;not null" json:"submitted_by"`            // 提交人（users.id）
+	ReviewedBy  *uint           `json:"reviewed_by"`                                   // 审批人（users.id）
+	ReviewedAt  *time.Time      `json:"reviewed_at"`                                   // 审批时间
+	ReviewNote  string          `gorm:"size:500" json:"review_note"`                   // 审批意见
+	AppliedAt   *time.Time      `json:"applied_at"`                                    // 实际生效时间
+	Error       string          `gorm:"size:500" json:"error,omitempty"`               // 生效失败原因
+}
+
+// TableName 指定表名
+func (FareChangeRequest) TableName() string {
+	return "fare_change_requests"
+}
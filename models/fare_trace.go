@@ -0,0 +1,40 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
+// FareTraceStep 计费明细中的单个步骤
+type FareTraceStep struct {
+	Stage       string                 `json:"stage"`             // 计费阶段：base_fare, penalty, discounts, cap, rounding等
+	Rule        string                 `json:"rule,omitempty"`    // 命中的规则（表名#ID，如fares#12、transfers#3）
+	Description string                 `json:"description"`       // 说明
+	Before      Money                  `json:"before"`            // 本步骤前的应付金额
+	After       Money                  `json:"after"`             // 本步骤后的应付金额
+	Details     map[string]interface{} `json:"details,omitempty"` // 计算参数
+}
+
+// FareTrace 计费明细（按计算顺序记录每个阶段，随交易一起保存）
+type FareTrace []FareTraceStep
+
+// Value 实现driver.Valuer接口
+func (t FareTrace) Value() (driver.Value, error) {
+	if t == nil {
+		return nil, nil
+	}
+	return json.Marshal(t)
+}
+
+// Scan 实现sql.Scanner接口
+func (t *FareTrace) Scan(value interface{}) error {
+	if value == nil {
+		*t = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, t)
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
+// PassengerFare 同行乘客中一类乘客的计费结果
+type PassengerFare struct {
+	Category   string `json:"category"`       // 乘客类别：cardholder(持卡人), adult, child, student, elder, companion等
+	CardType   string `json:"card_type"`      // 计费使用的卡类型
+	Count      int    `json:"count"`          // 人数
+	Fare       Money  `json:"fare"`           // 每人应收金额
+	ActualFare Money  `json:"actual_fare"`    // 每人实收金额
+	Rule       string `json:"rule,omitempty"` // 命中的同行优惠规则（如companion_rules#2）
+}
+
+// GroupComposition 同行乘客构成（一张卡为多名乘客付费时记录各类乘客的人数与票价）
+type GroupComposition []PassengerFare
+
+// Value 实现driver.Valuer接口
+func (g GroupComposition) Value() (driver.Value, error) {
+	if g == nil {
+		return nil, nil
+	}
+	return json.Marshal(g)
+}
+
+// Scan 实现sql.Scanner接口
+func (g *GroupComposition) Scan(value interface{}) error {
+	if value == nil {
+		*g = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, g)
+}
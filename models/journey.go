@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Journey 行程（通过换乘连接的多程乘车，由上传处理时根据交易记录汇总生成）
+type Journey struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	JourneyID        string    `gorm:"uniqueIndex;not null;size:100" json:"journey_id"`       // 行程ID（与交易记录的journey_id对应）
+	CardID           string    `gorm:"index;not null;size:32" json:"card_id"`                 // 卡ID
+	StartTime        time.Time `gorm:"index;not null" json:"start_time"`                      // 首程上车时间
+	EndTime          time.Time `json:"end_time"`                                              // 末程下车时间（single_tap末程为上车时间）
+	LegCount         int       `gorm:"default:0" json:"leg_count"`                            // 乘次
+	TransferCount    int       `gorm:"default:0" json:"transfer_count"`                       // 换乘次数
+	StartRouteID     uint      `gorm:"index" json:"start_route_id"`                           // 首程线路ID
+	EndRouteID       uint      `gorm:"index" json:"end_route_id"`                             // 末程线路ID
+	StartStation     uint      `json:"start_station"`                                         // 首程上车站点ID
+	EndStation       *uint     `json:"end_station,omitempty"`                                 // 末程下车站点ID（nullable）
+	StartStationName string    `gorm:"size:100" json:"start_station_name"`                    // 首程上车站点名称
+	EndStationName   string    `gorm:"size:100" json:"end_station_name"`                      // 末程下车站点名称
+	TotalFare        Money     `gorm:"type:decimal(10,2);default:0" json:"total_fare"`        // 应收金额合计
+	TotalDiscount    Money     `gorm:"type:decimal(10,2);default:0" json:"total_discount"`    // 优惠金额合计
+	TotalActualFare  Money     `gorm:"type:decimal(10,2);default:0" json:"total_actual_fare"` // 实收金额合计
+
+	Legs []Transaction `gorm:"foreignKey:JourneyID;references:JourneyID" json:"legs,omitempty"`
+}
+
+// TableName 指定表名
+func (Journey) TableName() string {
+	return "journeys"
+}
@@ -0,0 +1,231 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money 金额（以分为单位的整数，避免浮点运算误差）
+// 数据库中仍以decimal(10,2)存储，JSON中序列化为保留两位小数的数字（如 2.50）
+type Money int64
+
+// RoundingMode 舍入模式
+type RoundingMode string
+
+const (
+	RoundDown     RoundingMode = "down"      // 向下舍入（向零方向截断）
+	RoundHalfUp   RoundingMode = "half_up"   // 四舍五入
+	RoundHalfEven RoundingMode = "half_even" // 银行家舍入（四舍六入五成双）
+)
+
+// ParseRoundingMode 解析舍入模式（为空时默认向下舍入）
+func ParseRoundingMode(value string) (RoundingMode, error) {
+	switch RoundingMode(value) {
+	case "":
+		return RoundDown, nil
+	case RoundDown, RoundHalfUp, RoundHalfEven:
+		return RoundingMode(value), nil
+	default:
+		return "", fmt.Errorf("不支持的舍入模式: %s", value)
+	}
+}
+
+// ParseMoney 解析十进制金额字符串（如"2"、"2.5"、"-0.29"），最多两位小数
+func ParseMoney(value string) (Money, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("金额为空")
+	}
+
+	negative := false
+	switch value[0] {
+	case '-':
+		negative = true
+		value = value[1:]
+	case '+':
+		value = value[1:]
+	}
+
+	intPart, fracPart := value, ""
+	if dot := strings.IndexByte(value, '.'); dot >= 0 {
+		intPart, fracPart = value[:dot], value[dot+1:]
+	}
+	// 只接受十进制数字（拒绝"1e2"等科学计数法和重复的正负号）
+	if (intPart == "" && fracPart == "") || !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, fmt.Errorf("金额格式错误: %s", value)
+	}
+	// 超过两位小数时，多余的位数必须全为0（如数据库返回的"2.000"）
+	if len(fracPart) > 2 {
+		if strings.Trim(fracPart[2:], "0") != "" {
+			return 0, fmt.Errorf("金额最多两位小数: %s", value)
+		}
+		fracPart = fracPart[:2]
+	}
+	for len(fracPart) < 2 {
+		fracPart += "0"
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+
+	yuan, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil || yuan < 0 || yuan > math.MaxInt64/100-1 {
+		return 0, fmt.Errorf("金额格式错误: %s", value)
+	}
+	cents, err := strconv.ParseInt(fracPart, 10, 64)
+	if err != nil || cents < 0 {
+		return 0, fmt.Errorf("金额格式错误: %s", value)
+	}
+
+	amount := Money(yuan*100 + cents)
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// isDigits 字符串是否只包含十进制数字（空字符串返回true）
+func isDigits(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] < '0' || value[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MustParseMoney 解析金额字符串，格式错误时panic（仅用于常量与初始化数据）
+func MustParseMoney(value string) Money {
+	amount, err := ParseMoney(value)
+	if err != nil {
+		panic(err)
+	}
+	return amount
+}
+
+// Cents 返回以分为单位的金额
+func (m Money) Cents() int64 {
+	return int64(m)
+}
+
+// Float64 返回以元为单位的浮点金额（仅用于展示与统计，不应参与计费）
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// String 返回保留两位小数的金额字符串（如"2.50"）
+func (m Money) String() string {
+	sign := ""
+	value := int64(m)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, value/100, value%100)
+}
+
+// MulRate 金额乘以比例（比例按4位小数处理，与decimal(5,4)一致），结果按舍入模式保留到分
+func (m Money) MulRate(rate float64, mode RoundingMode) Money {
+	basisPoints := int64(math.Round(rate * 10000))
+	return Money(divRound(int64(m)*basisPoints, 10000, mode))
+}
+
+// RoundTo 按舍入模式将金额舍入为unit的整数倍（如unit为10分时舍入到角）
+func (m Money) RoundTo(unit Money, mode RoundingMode) Money {
+	if unit <= 1 {
+		return m
+	}
+	return Money(divRound(int64(m), int64(unit), mode)) * unit
+}
+
+// Min 返回两个金额中较小的一个
+func (m Money) Min(other Money) Money {
+	if other < m {
+		return other
+	}
+	return m
+}
+
+// divRound 整数除法，按舍入模式处理余数（den必须为正数）
+func divRound(num, den int64, mode RoundingMode) int64 {
+	quotient := num / den
+	remainder := num % den
+	if remainder == 0 {
+		return quotient
+	}
+
+	sign := int64(1)
+	if num < 0 {
+		sign = -1
+		remainder = -remainder
+	}
+
+	switch mode {
+	case RoundHalfUp:
+		if remainder*2 >= den {
+			quotient += sign
+		}
+	case RoundHalfEven:
+		if remainder*2 > den || (remainder*2 == den && quotient%2 != 0) {
+			quotient += sign
+		}
+	}
+	// RoundDown：Go的整数除法本身向零截断
+	return quotient
+}
+
+// MarshalJSON 序列化为保留两位小数的数字
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON 支持数字或字符串形式的金额
+func (m *Money) UnmarshalJSON(b []byte) error {
+	value := string(b)
+	if value == "null" {
+		return nil
+	}
+	value = strings.Trim(value, `"`)
+	amount, err := ParseMoney(value)
+	if err != nil {
+		return err
+	}
+	*m = amount
+	return nil
+}
+
+// Value 实现driver.Valuer接口（以十进制字符串写入decimal列）
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan 实现sql.Scanner接口
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		amount, err := ParseMoney(string(v))
+		if err != nil {
+			return err
+		}
+		*m = amount
+	case string:
+		amount, err := ParseMoney(v)
+		if err != nil {
+			return err
+		}
+		*m = amount
+	case int64:
+		*m = Money(v * 100)
+	case float64:
+		*m = Money(math.Round(v * 100))
+	default:
+		return fmt.Errorf("无法将%T转换为金额", value)
+	}
+	return nil
+}
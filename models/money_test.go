@@ -0,0 +1,182 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{"2", 200, false},
+		{"2.5", 250, false},
+		{"2.50", 250, false},
+		{"0.29", 29, false},
+		{".5", 50, false},
+		{"3.", 300, false},
+		{"+1.20", 120, false},
+		{"-0.29", -29, false},
+		{"-2", -200, false},
+		{"2.000", 200, false},
+		{" 12.34 ", 1234, false},
+		{"", 0, true},
+		{".", 0, true},
+		{"-", 0, true},
+		{"2.005", 0, true},
+		{"1e2", 0, true},
+		{"1E2", 0, true},
+		{"1.5e1", 0, true},
+		{"--1", 0, true},
+		{"+-1", 0, true},
+		{"1.+5", 0, true},
+		{"1.-5", 0, true},
+		{"1.2.3", 0, true},
+		{"0x10", 0, true},
+		{"1,000", 0, true},
+		{"99999999999999999999", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseMoney(%q) = %s，应返回错误", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseMoney(%q) 返回错误: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d，应为 %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		amount Money
+		want   string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{250, "2.50"},
+		{-29, "-0.29"},
+		{-200, "-2.00"},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q，应为 %q", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	var values struct {
+		Number Money `json:"number"`
+		Text   Money `json:"text"`
+	}
+	if err := json.Unmarshal([]byte(`{"number": 2.5, "text": "-0.30"}`), &values); err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if values.Number != 250 || values.Text != -30 {
+		t.Errorf("解析结果为 %d、%d，应为 250、-30", values.Number, values.Text)
+	}
+	if err := json.Unmarshal([]byte(`{"number": 1e2}`), &values); err == nil {
+		t.Errorf("科学计数法应返回错误")
+	}
+}
+
+func TestParseRoundingMode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    RoundingMode
+		wantErr bool
+	}{
+		{"", RoundDown, false},
+		{"down", RoundDown, false},
+		{"half_up", RoundHalfUp, false},
+		{"half_even", RoundHalfEven, false},
+		{"ceil", "", true},
+		{"HALF_UP", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRoundingMode(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRoundingMode(%q) = %q, %v，应为 %q（返回错误: %v）", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMoneyRoundTo(t *testing.T) {
+	tests := []struct {
+		amount Money
+		unit   Money
+		mode   RoundingMode
+		want   Money
+	}{
+		// 单位不超过1分时不舍入
+		{237, 1, RoundHalfUp, 237},
+		{237, 0, RoundHalfUp, 237},
+		// 向下舍入（向零方向截断）
+		{237, 10, RoundDown, 230},
+		{239, 10, RoundDown, 230},
+		{-237, 10, RoundDown, -230},
+		// 四舍五入
+		{234, 10, RoundHalfUp, 230},
+		{235, 10, RoundHalfUp, 240},
+		{245, 10, RoundHalfUp, 250},
+		{-235, 10, RoundHalfUp, -240},
+		{-234, 10, RoundHalfUp, -230},
+		// 银行家舍入（五成双）
+		{235, 10, RoundHalfEven, 240},
+		{245, 10, RoundHalfEven, 240},
+		{246, 10, RoundHalfEven, 250},
+		{-245, 10, RoundHalfEven, -240},
+		{-235, 10, RoundHalfEven, -240},
+		// 舍入到5角
+		{275, 50, RoundHalfUp, 300},
+		{274, 50, RoundHalfUp, 250},
+		{275, 50, RoundHalfEven, 300},
+		{225, 50, RoundHalfEven, 200},
+		// 整数倍不变
+		{300, 50, RoundDown, 300},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.RoundTo(tt.unit, tt.mode); got != tt.want {
+			t.Errorf("Money(%d).RoundTo(%d, %s) = %d，应为 %d", tt.amount, tt.unit, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyMulRate(t *testing.T) {
+	tests := []struct {
+		amount Money
+		rate   float64
+		mode   RoundingMode
+		want   Money
+	}{
+		{200, 0.8, RoundDown, 160},
+		{199, 0.5, RoundDown, 99},
+		{199, 0.5, RoundHalfUp, 100},
+		{199, 0.5, RoundHalfEven, 100},
+		{197, 0.5, RoundHalfEven, 98},
+		{195, 0.3, RoundDown, 58},
+		{195, 0.3, RoundHalfUp, 59},
+		{-199, 0.5, RoundDown, -99},
+		{-199, 0.5, RoundHalfUp, -100},
+		{-197, 0.5, RoundHalfEven, -98},
+		// 比例按4位小数处理
+		{10000, 0.12345, RoundDown, 1235},
+		{250, 0, RoundHalfUp, 0},
+		{250, 1, RoundHalfUp, 250},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.MulRate(tt.rate, tt.mode); got != tt.want {
+			t.Errorf("Money(%d).MulRate(%v, %s) = %d，应为 %d", tt.amount, tt.rate, tt.mode, got, tt.want)
+		}
+	}
+}
@@ -9,7 +9,7 @@ type MonthlyAggregate struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
 	CardID      string    `gorm:"uniqueIndex:idx_card_month;not null;size:32;index" json:"card_id"` // 卡片ID
 	Month       string    `gorm:"uniqueIndex:idx_card_month;not null;size:7" json:"month"`          // 月份（YYYY-MM格式）
-	TotalAmount float64   `gorm:"type:decimal(10,2);default:0;not null" json:"total_amount"`        // 当月累计金额
+	TotalAmount Money     `gorm:"type:decimal(10,2);default:0;not null" json:"total_amount"`        // 当月累计金额
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
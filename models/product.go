@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Product 票卡产品（月票、周票等不限次乘车卡）
+type Product struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	ProductCode        string `gorm:"uniqueIndex;not null;size:50" json:"product_code"`    // 产品编号，如"MONTHLY_ALL"
+	Name               string `gorm:"size:100;not null" json:"name"`                       // 产品名称
+	PassType           string `gorm:"size:20;not null;default:'monthly'" json:"pass_type"` // 票种：monthly(月票), weekly(周票), period(按天数)
+	ValidityDays       int    `gorm:"default:0" json:"validity_days"`                      // 有效天数（0表示按票种：月票一个月、周票7天）
+	CoveredRoutes      string `gorm:"size:500" json:"covered_routes"`                      // 适用线路ID（逗号分隔，为空表示全部线路）
+	CoveredZones       string `gorm:"size:500" json:"covered_zones"`                       // 适用分区（逗号分隔，为空表示全部分区）
+	AllowedCardTypes   string `gorm:"size:200" json:"allowed_card_types"`                  // 允许购买的卡类型（逗号分隔，为空表示全部）
+	Price              Money  `gorm:"type:decimal(10,2);not null" json:"price"`            // 售价
+	ActivateOnFirstUse bool   `gorm:"default:false" json:"activate_on_first_use"`          // 是否首次乘车时激活（否则购买时或指定日期生效）
+	Status             string `gorm:"size:20;default:'active'" json:"status"`              // 状态：active, inactive
+}
+
+// TableName 指定表名
+func (Product) TableName() string {
+	return "products"
+}
+
+// CardProduct 卡片购买的票卡产品
+type CardProduct struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	CardID       string     `gorm:"index;not null;size:32" json:"card_id"`         // 卡ID
+	ProductID    uint       `gorm:"index;not null" json:"product_id"`              // 产品ID
+	PurchaseTime time.Time  `gorm:"not null" json:"purchase_time"`                 // 购买时间
+	PricePaid    Money      `gorm:"type:decimal(10,2);not null" json:"price_paid"` // 实付金额
+	Status       string     `gorm:"size:20;default:'active'" json:"status"`        // 状态：pending_activation(待首次乘车激活), active, cancelled
+	ValidFrom    *time.Time `gorm:"index" json:"valid_from,omitempty"`             // 生效时间（待激活时为空）
+	ValidUntil   *time.Time `gorm:"index" json:"valid_until,omitempty"`            // 失效时间（不含）
+	FirstUsedAt  *time.Time `json:"first_used_at,omitempty"`                       // 首次乘车时间
+	RideCount    int        `gorm:"default:0" json:"ride_count"`                   // 使用次数
+
+	Product Product `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+}
+
+// TableName 指定表名
+func (CardProduct) TableName() string {
+	return "card_products"
+}
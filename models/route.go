@@ -13,13 +13,17 @@ type Route struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
-	RouteID       string  `gorm:"uniqueIndex;not null;size:50" json:"route_id"` // 线路编号，如"A"、"1"、"K1"
-	Name          string  `gorm:"size:100;not null" json:"name"`                // 线路名称
-	Status        string  `gorm:"size:20;default:'active'" json:"status"`       // 状态：active, inactive
-	FareType      string  `gorm:"size:50;default:'uniform'" json:"fare_type"`   // 计价模式：uniform(统一), segment(分段), distance(距离)
-	TapMode       string  `gorm:"size:20;default:'single_tap'" json:"tap_mode"` // 刷卡模式：single_tap(单次刷卡), tap_in_out(进出站刷卡)
-	MaxFare       float64 `gorm:"type:decimal(10,2);default:0" json:"max_fare"` // 最高无优惠票价（用于罚款计费）
-	DirectionMode string  `gorm:"size:20;default:'both'" json:"direction_mode"` // 方向模式：single(单向), both(双向)
+	RouteID       string `gorm:"uniqueIndex;not null;size:50" json:"route_id"` // 线路编号，如"A"、"1"、"K1"
+	Name          string `gorm:"size:100;not null" json:"name"`                // 线路名称
+	Status        string `gorm:"size:20;default:'active'" json:"status"`       // 状态：active, inactive
+	FareType      string `gorm:"size:50;default:'uniform'" json:"fare_type"`   // 计价模式：uniform(统一), segment(分段), distance(距离), zone(分区)
+	TapMode       string `gorm:"size:20;default:'single_tap'" json:"tap_mode"` // 刷卡模式：single_tap(单次刷卡), tap_in_out(进出站刷卡)
+	MaxFare       Money  `gorm:"type:decimal(10,2);default:0" json:"max_fare"` // 最高无优惠票价（用于罚款计费）
+	DirectionMode string `gorm:"size:20;default:'both'" json:"direction_mode"` // 方向模式：single(单向), both(双向), loop(环线)
+
+	LoopFarePolicy string `gorm:"size:20;default:'shortest'" json:"loop_fare_policy"` // 环线计价策略：shortest(按较短一侧), travel(按实际行驶方向)
+	MaxRideMinutes int    `gorm:"default:0" json:"max_ride_minutes"`                  // 最长乘车时间（分钟，single_tap线路用于估算下车时间，0表示使用系统默认）
+	Operator       string `gorm:"size:50;index" json:"operator"`                      // 运营方编码（按运营方配置舍入规则，为空时使用默认配置）
 }
 
 // TableName 指定表名
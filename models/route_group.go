@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RouteGroup 线路组（如全部干线），用于换乘规则按组匹配线路
+type RouteGroup struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	GroupCode string `gorm:"uniqueIndex;not null;size:50" json:"group_code"` // 线路组编号，如"trunk"
+	Name      string `gorm:"size:100;not null" json:"name"`                  // 线路组名称
+	Status    string `gorm:"size:20;default:'active'" json:"status"`         // 状态：active, inactive
+
+	Members []RouteGroupMember `gorm:"foreignKey:RouteGroupID" json:"members,omitempty"`
+}
+
+// TableName 指定表名
+func (RouteGroup) TableName() string {
+	return "route_groups"
+}
+
+// RouteGroupMember 线路组成员
+type RouteGroupMember struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	RouteGroupID uint `gorm:"not null;uniqueIndex:idx_route_group_member" json:"route_group_id"` // 线路组ID
+	RouteID      uint `gorm:"not null;uniqueIndex:idx_route_group_member" json:"route_id"`       // 线路ID
+}
+
+// TableName 指定表名
+func (RouteGroupMember) TableName() string {
+	return "route_group_members"
+}
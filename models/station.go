@@ -13,12 +13,13 @@ type Station struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
-	StationID string  `gorm:"uniqueIndex;not null;size:50" json:"station_id"` // 站点编号
-	Name      string  `gorm:"size:100;not null" json:"name"`                  // 站点名称
-	Latitude  float64 `gorm:"type:decimal(10,8)" json:"latitude"`             // 纬度
-	Longitude float64 `gorm:"type:decimal(11,8)" json:"longitude"`            // 经度
-	Address   string  `gorm:"size:200" json:"address"`                        // 地址
-	IsTransfer bool   `gorm:"default:false" json:"is_transfer"`               // 是否为换乘站
+	StationID  string  `gorm:"uniqueIndex;not null;size:50" json:"station_id"` // 站点编号
+	Name       string  `gorm:"size:100;not null" json:"name"`                  // 站点名称
+	Latitude   float64 `gorm:"type:decimal(10,8)" json:"latitude"`             // 纬度
+	Longitude  float64 `gorm:"type:decimal(11,8)" json:"longitude"`            // 经度
+	Address    string  `gorm:"size:200" json:"address"`                        // 地址
+	IsTransfer bool    `gorm:"default:false" json:"is_transfer"`               // 是否为换乘站
+	Status     string  `gorm:"size:20;default:'active'" json:"status"`         // 状态：active, inactive
 }
 
 // TableName 指定表名
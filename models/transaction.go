@@ -13,22 +13,28 @@ type Transaction struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
-	RecordID         string     `gorm:"uniqueIndex;size:100" json:"record_id"`               // 记录ID（网关幂等键）
-	CardID           string     `gorm:"index;not null;size:32" json:"card_id"`               // 卡ID
-	RouteID          uint       `gorm:"index" json:"route_id"`                               // 线路ID
-	StartStation     uint       `gorm:"index" json:"start_station"`                          // 上车站点ID
-	EndStation       *uint      `gorm:"index" json:"end_station,omitempty"`                  // 下车站点ID（nullable）
-	StartStationName string     `gorm:"size:100" json:"start_station_name"`                  // 上车站点名称（冗余字段，便于查询）
-	EndStationName   string     `gorm:"size:100" json:"end_station_name"`                    // 下车站点名称
-	BoardTime        time.Time  `gorm:"index;not null" json:"board_time"`                    // 上车时间
-	AlightTime       *time.Time `gorm:"index" json:"alight_time,omitempty"`                  // 下车时间（NULL表示未下车）
-	Fare             float64    `gorm:"type:decimal(10,2);not null" json:"fare"`             // 应收金额（基础票价）
-	ActualFare       float64    `gorm:"type:decimal(10,2);not null" json:"actual_fare"`      // 实收金额（优惠后）
-	DiscountType     string     `gorm:"size:50" json:"discount_type"`                        // 优惠类型：transfer, monthly_discount, student, elder等
-	DiscountAmount   float64    `gorm:"type:decimal(10,2);default:0" json:"discount_amount"` // 优惠金额
-	PenaltyFare      bool       `gorm:"default:false" json:"penalty_fare"`                   // 是否为罚款计费
-	Status           string     `gorm:"size:20;default:'completed'" json:"status"`           // 状态：pending, completed, cancelled
-	GatewayID        string     `gorm:"size:50" json:"gateway_id"`                           // 网关设备ID（记录来源）
+	RecordID         string           `gorm:"uniqueIndex;size:100" json:"record_id"`               // 记录ID（网关幂等键）
+	CardID           string           `gorm:"index;not null;size:32" json:"card_id"`               // 卡ID
+	RouteID          uint             `gorm:"index" json:"route_id"`                               // 线路ID
+	StartStation     uint             `gorm:"index" json:"start_station"`                          // 上车站点ID
+	EndStation       *uint            `gorm:"index" json:"end_station,omitempty"`                  // 下车站点ID（nullable）
+	StartStationName string           `gorm:"size:100" json:"start_station_name"`                  // 上车站点名称（冗余字段，便于查询）
+	EndStationName   string           `gorm:"size:100" json:"end_station_name"`                    // 下车站点名称
+	BoardTime        time.Time        `gorm:"index;not null" json:"board_time"`                    // 上车时间
+	AlightTime       *time.Time       `gorm:"index" json:"alight_time,omitempty"`                  // 下车时间（NULL表示未下车）
+	Fare             Money            `gorm:"type:decimal(10,2);not null" json:"fare"`             // 应收金额（基础票价）
+	ActualFare       Money            `gorm:"type:decimal(10,2);not null" json:"actual_fare"`      // 实收金额（优惠后）
+	DiscountType     string           `gorm:"size:50" json:"discount_type"`                        // 优惠类型：transfer, monthly_discount, student, elder等
+	DiscountAmount   Money            `gorm:"type:decimal(10,2);default:0" json:"discount_amount"` // 优惠金额
+	PenaltyFare      bool             `gorm:"default:false" json:"penalty_fare"`                   // 是否为罚款计费
+	Status           string           `gorm:"size:20;default:'completed'" json:"status"`           // 状态：pending, completed, cancelled
+	GatewayID        string           `gorm:"size:50" json:"gateway_id"`                           // 网关设备ID（记录来源）
+	Direction        string           `gorm:"size:20" json:"direction"`                            // 行驶方向：up, down（网关上报或计费时推断）
+	JourneyID        string           `gorm:"size:100;index" json:"journey_id"`                    // 行程ID（通过换乘连接的多程乘车共用同一行程ID）
+	PassID           *uint            `gorm:"index" json:"pass_id,omitempty"`                      // 使用的票卡（card_products.id，使用月票等乘车时记录）
+	PassengerCount   int              `gorm:"default:1" json:"passenger_count"`                    // 乘客人数（含持卡人）
+	GroupComposition GroupComposition `gorm:"type:jsonb" json:"group_composition,omitempty"`       // 同行乘客构成（多人同行时记录各类乘客人数与票价）
+	FareTrace        FareTrace        `gorm:"type:jsonb" json:"-"`                                 // 计费明细（通过fare-breakdown接口查询）
 
 	Card  Card  `gorm:"foreignKey:CardID;references:CardID" json:"card,omitempty"`
 	Route Route `gorm:"foreignKey:RouteID" json:"route,omitempty"`
@@ -13,14 +13,21 @@ type Transfer struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
-	FromRouteID   uint    `gorm:"index" json:"from_route_id"`   // 起始线路ID
-	FromStationID uint    `gorm:"index" json:"from_station_id"` // 起始站点ID（换乘站）
-	ToRouteID     uint    `gorm:"index" json:"to_route_id"`     // 换乘后线路ID
-	ToStationID   uint    `gorm:"index" json:"to_station_id"`   // 换乘后站点ID
-	DiscountAmount float64 `gorm:"type:decimal(10,2);default:0" json:"discount_amount"` // 优惠金额
-	DiscountRate   float64 `gorm:"type:decimal(5,4);default:0" json:"discount_rate"`    // 优惠比例（0-1之间）
-	TimeWindow     int     `gorm:"default:60" json:"time_window"`                        // 时间窗口（分钟），在此时间内换乘才享受优惠
-	Status         string  `gorm:"size:20;default:'active'" json:"status"`              // 状态：active, inactive
+	FromRouteID      uint    `gorm:"index" json:"from_route_id"`                          // 起始线路ID（0表示任意线路或按线路组匹配）
+	FromRouteGroupID uint    `gorm:"index" json:"from_route_group_id"`                    // 起始线路组ID（0表示不按线路组匹配）
+	FromStationID    uint    `gorm:"index" json:"from_station_id"`                        // 起始站点ID（换乘站，0表示线路上任意站点）
+	ToRouteID        uint    `gorm:"index" json:"to_route_id"`                            // 换乘后线路ID（0表示任意线路或按线路组匹配）
+	ToRouteGroupID   uint    `gorm:"index" json:"to_route_group_id"`                      // 换乘后线路组ID（0表示不按线路组匹配）
+	ToStationID      uint    `gorm:"index" json:"to_station_id"`                          // 换乘后站点ID（0表示线路上任意站点）
+	DiscountAmount   Money   `gorm:"type:decimal(10,2);default:0" json:"discount_amount"` // 优惠金额
+	DiscountRate     float64 `gorm:"type:decimal(5,4);default:0" json:"discount_rate"`    // 优惠比例（0-1之间）
+	TimeWindow       int     `gorm:"default:60" json:"time_window"`                       // 时间窗口（分钟），在此时间内换乘才享受优惠
+
+	MaxLegs                int `gorm:"default:0" json:"max_legs"`                 // 行程最多乘次（含首程，0表示不限）
+	JourneyWindow          int `gorm:"default:0" json:"journey_window"`           // 行程总时间窗口（分钟，从首程上车起算，0表示不限）
+	MaxDiscountedTransfers int `gorm:"default:0" json:"max_discounted_transfers"` // 每个行程最多享受换乘优惠的次数（0表示不限）
+
+	Status string `gorm:"size:20;default:'active'" json:"status"` // 状态：active, inactive
 }
 
 // TableName 指定表名
@@ -16,7 +16,7 @@ type User struct {
 	Username string `gorm:"uniqueIndex;not null;size:50" json:"username"` // 用户名
 	Password string `gorm:"not null;size:255" json:"-"`                   // 密码（哈希后）
 	RealName string `gorm:"size:100" json:"real_name"`                    // 真实姓名
-	Role     string `gorm:"size:50;default:'driver'" json:"role"`         // 角色：admin, driver, operator
+	Role     string `gorm:"size:50;default:'driver'" json:"role"`         // 角色：admin, driver, operator, fare_editor, fare_approver
 	Status   string `gorm:"size:20;default:'active'" json:"status"`       // 状态：active, inactive
 }
 
@@ -24,3 +24,12 @@ type User struct {
 func (User) TableName() string {
 	return "users"
 }
+
+// 用户角色
+const (
+	RoleAdmin        = "admin"         // 管理员（可提交和审批票价配置变更）
+	RoleDriver       = "driver"        // 司机
+	RoleOperator     = "operator"      // 运营人员
+	RoleFareEditor   = "fare_editor"   // 票价配置编辑（提交变更申请）
+	RoleFareApprover = "fare_approver" // 票价配置审批（审批他人提交的变更申请）
+)
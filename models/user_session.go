@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// UserSession 用户登录会话（登录时签发的访问令牌）
+type UserSession struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	TokenHash string    `gorm:"uniqueIndex;not null;size:64" json:"-"` // 访问令牌SHA-256摘要（明文只在登录时返回一次）
+	UserID    uint      `gorm:"index;not null" json:"user_id"`         // 用户ID
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`      // 过期时间
+}
+
+// TableName 指定表名
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Vehicle 车辆信息
+type Vehicle struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	VehicleNumber string `gorm:"uniqueIndex;not null;size:50" json:"vehicle_number"` // 车辆编号（自编号）
+	PlateNumber   string `gorm:"size:20" json:"plate_number"`                        // 车牌号
+	VehicleType   string `gorm:"size:50" json:"vehicle_type"`                        // 车型
+	Capacity      int    `gorm:"default:0" json:"capacity"`                          // 额定载客人数
+	Status        string `gorm:"size:20;default:'active'" json:"status"`             // 状态：active, inactive, maintenance
+}
+
+// TableName 指定表名
+func (Vehicle) TableName() string {
+	return "vehicles"
+}
+
+// VehicleAssignment 车辆排班（车辆在时间段内运营的线路与方向）
+type VehicleAssignment struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	VehicleID uint       `gorm:"not null;index" json:"vehicle_id"` // 车辆ID
+	RouteID   uint       `gorm:"not null;index" json:"route_id"`   // 线路ID
+	Direction string     `gorm:"size:20" json:"direction"`         // 行驶方向：up, down（为空表示不限定，按刷卡推断）
+	StartTime time.Time  `gorm:"not null;index" json:"start_time"` // 开始时间
+	EndTime   *time.Time `gorm:"index" json:"end_time"`            // 结束时间（不含，为空表示长期有效）
+
+	Vehicle Vehicle `gorm:"foreignKey:VehicleID;references:ID" json:"vehicle,omitempty"`
+	Route   Route   `gorm:"foreignKey:RouteID;references:ID" json:"route,omitempty"`
+}
+
+// TableName 指定表名
+func (VehicleAssignment) TableName() string {
+	return "vehicle_assignments"
+}
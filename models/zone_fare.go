@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ZoneFare 分区票价矩阵（起始分区到目的分区的票价）
+type ZoneFare struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	RouteID  uint   `gorm:"index" json:"route_id"`                    // 线路ID（0表示全网通用矩阵）
+	FromZone string `gorm:"size:50;not null;index" json:"from_zone"`  // 起始分区ID（对应route_stations.zone_id）
+	ToZone   string `gorm:"size:50;not null;index" json:"to_zone"`    // 目的分区ID
+	Price    Money  `gorm:"type:decimal(10,2);not null" json:"price"` // 票价（未配置反方向时按对称处理）
+	Status   string `gorm:"size:20;default:'active'" json:"status"`   // 状态：active, inactive
+}
+
+// TableName 指定表名
+func (ZoneFare) TableName() string {
+	return "zone_fares"
+}
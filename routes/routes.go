@@ -2,6 +2,8 @@ package routes
 
 import (
 	"TapTransit-backend/controllers"
+	"TapTransit-backend/middleware"
+	"TapTransit-backend/models"
 	"TapTransit-backend/services"
 	"TapTransit-backend/utils"
 
@@ -14,14 +16,37 @@ func SetupRoutes(r *gin.Engine) {
 	fareService := services.NewFareService(utils.DB)
 	uploadService := services.NewUploadService(utils.DB, fareService)
 	cardService := services.NewCardService(utils.DB)
+	productService := services.NewProductService(utils.DB)
+	campaignService := services.NewCampaignService(utils.DB)
+	scenarioRunner := services.NewFareScenarioRunner(utils.DB)
+	fareSimulator := services.NewFareSimulator(utils.DB)
+	fareChangeService := services.NewFareChangeService(utils.DB)
+	networkService := services.NewNetworkService(utils.DB)
+	fareConfigService := services.NewFareConfigService(utils.DB)
+	gtfsImporter := services.NewGTFSImporter(utils.DB)
+	gtfsExporter := services.NewGTFSExporter(utils.DB, fareService)
+	vehicleService := services.NewVehicleService(utils.DB)
+	deviceService := services.NewDeviceService(utils.DB)
 
 	// 初始化控制器
 	busController := controllers.NewBusController(uploadService)
 	cardController := controllers.NewCardController(cardService)
 	configController := controllers.NewConfigController()
 	transactionController := controllers.NewTransactionController()
-	routeController := controllers.NewRouteController()
+	routeController := controllers.NewRouteController(networkService)
+	stationController := controllers.NewStationController(networkService)
 	authController := controllers.NewAuthController()
+	fareController := controllers.NewFareController(fareService)
+	journeyController := controllers.NewJourneyController()
+	productController := controllers.NewProductController(productService)
+	campaignController := controllers.NewCampaignController(campaignService, fareChangeService)
+	fareScenarioController := controllers.NewFareScenarioController(scenarioRunner)
+	fareSimulationController := controllers.NewFareSimulationController(fareSimulator)
+	fareChangeController := controllers.NewFareChangeController(fareChangeService)
+	fareConfigController := controllers.NewFareConfigController(fareConfigService, fareChangeService)
+	gtfsController := controllers.NewGTFSController(gtfsImporter, gtfsExporter)
+	vehicleController := controllers.NewVehicleController(vehicleService)
+	deviceController := controllers.NewDeviceController(deviceService)
 
 	// API v1路由组
 	v1 := r.Group("/api/v1")
@@ -36,10 +61,18 @@ func SetupRoutes(r *gin.Engine) {
 		// 公交数据相关
 		bus := v1.Group("/bus")
 		{
-			bus.POST("/batchRecords", busController.UploadBatchRecords) // 批量上传记录
-			bus.GET("/config", configController.GetRouteConfig)         // 获取线路配置
+			bus.POST("/enroll", deviceController.Enroll) // 网关以注册令牌兑换设备凭证
+
+			// 网关上传需设备凭证（心跳不要求签名，网关时钟偏差较大时仍可通过心跳校准时钟）
+			gateway := bus.Group("", middleware.DeviceAuthRequired())
+			gateway.POST("/batchRecords", middleware.DeviceSignatureRequired(), busController.UploadBatchRecords) // 批量上传记录（需请求签名）
+			gateway.POST("/heartbeat", deviceController.Heartbeat)                                                // 网关心跳
+			bus.GET("/config", configController.GetRouteConfig)                                                   // 获取线路配置
 		}
 
+		// 线路、站点、车辆与设备维护人员（同时负责票卡产品售卖和行程查询）
+		networkEditors := middleware.RequireRole(models.RoleAdmin, models.RoleOperator)
+
 		// 卡片相关
 		card := v1.Group("/card")
 		{
@@ -48,19 +81,153 @@ func SetupRoutes(r *gin.Engine) {
 
 		cards := v1.Group("/cards")
 		{
-			cards.GET("", cardController.ListCards) // 查询卡片列表
+			cards.GET("", cardController.ListCards)                                                                   // 查询卡片列表
+			cards.GET("/:id/products", productController.ListCardProducts)                                            // 查询卡片票卡产品
+			cards.POST("/:id/products", middleware.AuthRequired(), networkEditors, productController.PurchaseProduct) // 售卖票卡产品（柜台收款后由管理员或运营人员办理）
 		}
 
 		// 交易记录相关
 		transactions := v1.Group("/transactions")
 		{
-			transactions.GET("", transactionController.GetTransactions) // 查询交易记录
+			transactions.GET("", transactionController.GetTransactions)                     // 查询交易记录
+			transactions.GET("/:id/fare-breakdown", transactionController.GetFareBreakdown) // 查询交易计费明细
+		}
+
+		// 行程相关（需管理员或运营人员）
+		journeys := v1.Group("/journeys", middleware.AuthRequired(), networkEditors)
+		{
+			journeys.GET("", journeyController.GetJourneys)    // 查询行程列表
+			journeys.GET("/:id", journeyController.GetJourney) // 查询行程详情
+		}
+
+		// 票卡产品相关
+		products := v1.Group("/products")
+		{
+			products.GET("", productController.ListProducts) // 查询票卡产品目录
+		}
+
+		// 票价配置变更（提交人与审批人需为不同用户）
+		fareEditors := middleware.RequireRole(models.RoleAdmin, models.RoleFareEditor)
+		fareApprovers := middleware.RequireRole(models.RoleAdmin, models.RoleFareApprover)
+
+		// 优惠活动相关（创建、修改、停用提交为变更申请）
+		campaigns := v1.Group("/campaigns")
+		{
+			campaigns.GET("", campaignController.ListCampaigns)                       // 查询优惠活动
+			campaigns.GET("/:id/redemptions", campaignController.GetRedemptionReport) // 查询活动核销报表
+
+			campaignAdmin := campaigns.Group("", middleware.AuthRequired(), fareEditors)
+			campaignAdmin.POST("", campaignController.CreateCampaign)                    // 创建优惠活动
+			campaignAdmin.PUT("/:id", campaignController.UpdateCampaign)                 // 修改优惠活动
+			campaignAdmin.POST("/:id/deactivate", campaignController.DeactivateCampaign) // 停用优惠活动
+		}
+
+		// 票价相关（新增、修改、删除提交为变更申请）
+		fares := v1.Group("/fares")
+		{
+			fares.POST("/quote", fareController.QuoteFare) // 票价报价
+			fares.GET("", fareConfigController.ListFares)  // 查询票价规则
+
+			fareAdmin := fares.Group("", middleware.AuthRequired(), fareEditors)
+			fareAdmin.POST("", fareConfigController.CreateFare)       // 新增票价规则
+			fareAdmin.PUT("/:id", fareConfigController.UpdateFare)    // 修改票价规则
+			fareAdmin.DELETE("/:id", fareConfigController.DeleteFare) // 删除票价规则
+		}
+
+		// 换乘规则相关（新增、修改、删除提交为变更申请）
+		transfers := v1.Group("/transfers")
+		{
+			transfers.GET("", fareConfigController.ListTransfers) // 查询换乘规则
+
+			transferAdmin := transfers.Group("", middleware.AuthRequired(), fareEditors)
+			transferAdmin.POST("", fareConfigController.CreateTransfer)       // 新增换乘规则
+			transferAdmin.PUT("/:id", fareConfigController.UpdateTransfer)    // 修改换乘规则
+			transferAdmin.DELETE("/:id", fareConfigController.DeleteTransfer) // 删除换乘规则
+		}
+
+		// 折扣策略相关（新增、修改、删除提交为变更申请）
+		discountPolicies := v1.Group("/discount-policies")
+		{
+			discountPolicies.GET("", fareConfigController.ListDiscountPolicies) // 查询折扣策略
+
+			policyAdmin := discountPolicies.Group("", middleware.AuthRequired(), fareEditors)
+			policyAdmin.POST("", fareConfigController.CreateDiscountPolicy)       // 新增折扣策略
+			policyAdmin.PUT("/:id", fareConfigController.UpdateDiscountPolicy)    // 修改折扣策略
+			policyAdmin.DELETE("/:id", fareConfigController.DeleteDiscountPolicy) // 删除折扣策略
+		}
+
+		fareChanges := v1.Group("/fare-changes", middleware.AuthRequired())
+		{
+			fareChanges.GET("", fareChangeController.ListChanges)                               // 查询变更申请
+			fareChanges.GET("/:id", fareChangeController.GetChange)                             // 查询变更申请详情
+			fareChanges.POST("", fareEditors, fareChangeController.SubmitChange)                // 提交变更申请
+			fareChanges.POST("/:id/approve", fareApprovers, fareChangeController.ApproveChange) // 审批通过
+			fareChanges.POST("/:id/reject", fareApprovers, fareChangeController.RejectChange)   // 驳回
+			fareChanges.POST("/:id/cancel", fareChangeController.CancelChange)                  // 撤回
+		}
+		v1.GET("/fare-audit-logs", middleware.AuthRequired(), fareChangeController.ListAuditLogs) // 查询票价配置审计记录
+
+		// 开放数据（GTFS与GTFS-Fares v2）
+		gtfs := v1.Group("/gtfs")
+		{
+			gtfs.GET("/feed.zip", gtfsController.DownloadGTFS) // 下载GTFS数据
+			gtfs.GET("/feed", gtfsController.GetGTFSInfo)      // 查询GTFS数据生成信息
+		}
+
+		// 管理相关
+		admin := v1.Group("/admin")
+		{
+			admin.POST("/fare-scenarios/run", middleware.AuthRequired(), fareEditors, fareScenarioController.RunScenarios)  // 执行计费场景
+			admin.POST("/fare-simulations", middleware.AuthRequired(), fareEditors, fareSimulationController.SimulateFares) // 票价调整模拟
+			admin.POST("/gtfs/import", middleware.AuthRequired(), networkEditors, gtfsController.ImportGTFS)                // 导入GTFS静态数据
 		}
 
-		// 线路相关
+		// 线路相关（写操作需管理员或运营人员）
 		routes := v1.Group("/routes")
 		{
-			routes.GET("", routeController.GetRoutes) // 获取线路列表
+			routes.GET("", routeController.GetRoutes)                     // 获取线路列表
+			routes.GET("/:id/stations", routeController.GetRouteStations) // 查询线路站序
+
+			routeAdmin := routes.Group("", middleware.AuthRequired(), networkEditors)
+			routeAdmin.POST("", routeController.CreateRoute)                    // 新增线路
+			routeAdmin.PUT("/:id", routeController.UpdateRoute)                 // 修改线路
+			routeAdmin.POST("/:id/deactivate", routeController.DeactivateRoute) // 停用线路
+			routeAdmin.POST("/:id/activate", routeController.ActivateRoute)     // 启用线路
+			routeAdmin.PUT("/:id/stations", routeController.SetRouteStations)   // 设置线路站序
+		}
+
+		// 站点相关（写操作需管理员或运营人员）
+		stations := v1.Group("/stations")
+		{
+			stations.GET("", stationController.ListStations) // 查询站点列表
+
+			stationAdmin := stations.Group("", middleware.AuthRequired(), networkEditors)
+			stationAdmin.POST("", stationController.CreateStation)                    // 新增站点
+			stationAdmin.PUT("/:id", stationController.UpdateStation)                 // 修改站点
+			stationAdmin.POST("/:id/deactivate", stationController.DeactivateStation) // 停用站点
+			stationAdmin.POST("/:id/activate", stationController.ActivateStation)     // 启用站点
+		}
+
+		// 车辆与排班相关（需管理员或运营人员）
+		vehicles := v1.Group("/vehicles", middleware.AuthRequired(), networkEditors)
+		{
+			vehicles.GET("", vehicleController.ListVehicles)                                     // 查询车辆列表
+			vehicles.POST("", vehicleController.CreateVehicle)                                   // 新增车辆
+			vehicles.PUT("/:id", vehicleController.UpdateVehicle)                                // 修改车辆
+			vehicles.POST("/:id/devices", vehicleController.BindDevice)                          // 绑定网关设备
+			vehicles.GET("/:id/assignments", vehicleController.ListAssignments)                  // 查询车辆排班
+			vehicles.POST("/:id/assignments", vehicleController.CreateAssignment)                // 新增车辆排班
+			vehicles.POST("/:id/assignments/:assignmentId/end", vehicleController.EndAssignment) // 结束车辆排班
+		}
+
+		// 设备相关（需管理员或运营人员）
+		devices := v1.Group("/devices", middleware.AuthRequired(), networkEditors)
+		{
+			devices.GET("", deviceController.ListDevices) // 查询设备列表（status=offline查询离线设备）
+
+			deviceAdmin := devices.Group("", middleware.RequireRole(models.RoleAdmin))
+			deviceAdmin.POST("/enrollment-tokens", deviceController.CreateEnrollmentToken)  // 签发设备注册令牌
+			deviceAdmin.POST("/:id/revoke-credentials", deviceController.RevokeCredentials) // 吊销设备凭证
 		}
 	}
 }
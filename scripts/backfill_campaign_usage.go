@@ -0,0 +1,37 @@
+package main
+
+// 按已有核销记录补齐活动已预留用量（campaigns.used_amount、used_rides）
+// 升级到记录活动用量的版本后执行一次；只处理尚未记录用量的活动
+// 使用方法：go run scripts/backfill_campaign_usage.go [-config config/config.yaml]
+
+import (
+	"TapTransit-backend/config"
+	"TapTransit-backend/utils"
+	"flag"
+	"log"
+)
+
+func main() {
+	configPath := flag.String("config", "config/config.yaml", "配置文件路径")
+	flag.Parse()
+
+	// 加载配置
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	// 初始化数据库（迁移后campaigns表才有用量字段）
+	db, err := utils.InitDatabase(cfg)
+	if err != nil {
+		log.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	result := db.Exec(`UPDATE campaigns SET used_rides = r.rides, used_amount = r.amount
+		FROM (SELECT campaign_id, COUNT(*) AS rides, SUM(discount_amount) AS amount FROM campaign_redemptions GROUP BY campaign_id) r
+		WHERE campaigns.id = r.campaign_id AND campaigns.used_rides = 0 AND campaigns.used_amount = 0`)
+	if result.Error != nil {
+		log.Fatalf("补齐活动已预留用量失败: %v", result.Error)
+	}
+	log.Printf("✅ 已补齐%d个活动的已预留用量", result.RowsAffected)
+}
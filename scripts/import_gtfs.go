@@ -0,0 +1,73 @@
+package main
+
+// 从GTFS静态数据导入线路、站点与线路站序
+// 使用方法：go run scripts/import_gtfs.go [-dry-run] [-json] gtfs.zip
+// -dry-run 只输出与现有数据的差异，不写入任何数据
+
+import (
+	"TapTransit-backend/config"
+	"TapTransit-backend/services"
+	"TapTransit-backend/utils"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "只输出差异，不导入")
+	jsonOutput := flag.Bool("json", false, "以JSON格式输出导入报告")
+	configPath := flag.String("config", "config/config.yaml", "配置文件路径")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		log.Fatalf("用法: go run scripts/import_gtfs.go [-dry-run] [-json] <GTFS压缩包>")
+	}
+
+	// 加载配置
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	// 初始化数据库
+	if _, err := utils.InitDatabase(cfg); err != nil {
+		log.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	data, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("读取GTFS压缩包失败: %v", err)
+	}
+	report, err := services.NewGTFSImporter(utils.DB).Import(data, *dryRun)
+	if err != nil {
+		log.Fatalf("导入失败: %v", err)
+	}
+
+	if *jsonOutput {
+		output, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(output))
+		return
+	}
+	printReport(report)
+}
+
+// printReport 输出导入报告
+func printReport(report *services.GTFSImportReport) {
+	if report.DryRun {
+		fmt.Println("试运行（未写入数据）")
+	}
+	for _, change := range report.Changes {
+		fmt.Printf("%-7s %-15s %s\n", change.Action, change.Entity, change.Key)
+		for _, field := range change.Fields {
+			fmt.Printf("        %s: %v -> %v\n", field.Field, field.Old, field.New)
+		}
+	}
+	for _, warning := range report.Warnings {
+		fmt.Printf("警告: %s\n", warning)
+	}
+	s := report.Summary
+	fmt.Printf("线路：新增%d 修改%d 未变化%d\n", s.RoutesCreated, s.RoutesUpdated, s.RoutesUnchanged)
+	fmt.Printf("站点：新增%d 修改%d 未变化%d\n", s.StationsCreated, s.StationsUpdated, s.StationsUnchanged)
+	fmt.Printf("站序：新增%d 修改%d 移除%d 未变化%d\n", s.SequencesCreated, s.SequencesUpdated, s.SequencesRemoved, s.SequencesUnchanged)
+}
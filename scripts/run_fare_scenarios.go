@@ -0,0 +1,90 @@
+package main
+
+// 执行计费场景文件，检查已知行程的票价是否符合期望
+// 使用方法：go run scripts/run_fare_scenarios.go [-json] scenarios/example.yaml
+// 场景在独立事务中执行并在结束后回滚，不写入任何数据；有未通过的场景时退出码为1
+
+import (
+	"TapTransit-backend/config"
+	"TapTransit-backend/services"
+	"TapTransit-backend/utils"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+func main() {
+	jsonOutput := flag.Bool("json", false, "以JSON格式输出执行报告")
+	configPath := flag.String("config", "config/config.yaml", "配置文件路径")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		log.Fatalf("用法: go run scripts/run_fare_scenarios.go [-json] <场景文件>")
+	}
+
+	// 加载配置
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	// 初始化数据库
+	if _, err := utils.InitDatabase(cfg); err != nil {
+		log.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	data, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("读取场景文件失败: %v", err)
+	}
+	suite, err := services.ParseFareScenarioSuite(data)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	report, err := services.NewFareScenarioRunner(utils.DB).Run(suite)
+	if err != nil {
+		log.Fatalf("执行场景失败: %v", err)
+	}
+
+	if *jsonOutput {
+		output, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(output))
+	} else {
+		printReport(report)
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// printReport 输出可读的执行报告
+func printReport(report *services.FareScenarioReport) {
+	fmt.Printf("=== 计费场景：%s ===\n", report.Suite)
+	for _, scenario := range report.Scenarios {
+		status := "通过"
+		if !scenario.Passed {
+			status = "未通过"
+		}
+		fmt.Printf("[%s] %s（卡片%s）\n", status, scenario.Name, scenario.CardID)
+		if scenario.Error != "" {
+			fmt.Printf("    错误: %s\n", scenario.Error)
+		}
+		for _, leg := range scenario.Legs {
+			if leg.Actual != nil {
+				fmt.Printf("    第%d次刷卡 %s: 票价%s 优惠%s(%s) 实收%s\n", leg.Tap, leg.RecordID,
+					leg.Actual.BaseFare, leg.Actual.DiscountAmount, leg.Actual.DiscountType, leg.Actual.ActualFare)
+			}
+			for _, diff := range leg.Diffs {
+				fmt.Printf("    第%d次刷卡 %s 期望 %s 实际 %s\n", leg.Tap, diff.Field, diff.Expected, diff.Actual)
+			}
+			if !leg.Passed {
+				for _, step := range leg.Trace {
+					fmt.Printf("        [%s] %s %s: %s → %s\n", step.Stage, step.Rule, step.Description, step.Before, step.After)
+				}
+			}
+		}
+	}
+	fmt.Printf("通过 %d，未通过 %d\n", report.Passed, report.Failed)
+}
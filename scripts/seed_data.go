@@ -102,7 +102,7 @@ func main() {
 	if err := db.Where("route_id = ?", "A").First(&route1ForFare).Error; err == nil {
 		fare := models.Fare{
 			RouteID:   route1ForFare.ID,
-			BasePrice: 2.00,
+			BasePrice: models.MustParseMoney("2.00"),
 			FareType:  "uniform",
 			Status:    "active",
 		}
@@ -113,10 +113,10 @@ func main() {
 	if err := db.Where("route_id = ?", "B").First(&route2).Error; err == nil {
 		fare := models.Fare{
 			RouteID:      route2.ID,
-			BasePrice:    2.00,
+			BasePrice:    models.MustParseMoney("2.00"),
 			FareType:     "segment",
 			SegmentCount: 1,
-			ExtraPrice:   1.00,
+			ExtraPrice:   models.MustParseMoney("1.00"),
 			Status:       "active",
 		}
 		db.FirstOrCreate(&fare, models.Fare{RouteID: route2.ID, StartStation: 0, EndStation: 0})
@@ -133,7 +133,7 @@ func main() {
 					FromStationID:  stationCenter.ID,
 					ToRouteID:      routeB.ID,
 					ToStationID:    stationCenter.ID,
-					DiscountAmount: 2.00,
+					DiscountAmount: models.MustParseMoney("2.00"),
 					TimeWindow:     60,
 					Status:         "active",
 				}
@@ -149,10 +149,10 @@ func main() {
 
 	// 6. 创建折扣策略
 	discountPolicies := []models.DiscountPolicy{
-		{PolicyName: "月度累计折扣-8折", PolicyType: "monthly_accumulate", Threshold: 200.00, DiscountRate: 0.80, Status: "active"},
-		{PolicyName: "月度累计折扣-5折", PolicyType: "monthly_accumulate", Threshold: 500.00, DiscountRate: 0.50, Status: "active"},
-		{PolicyName: "学生卡折扣", PolicyType: "student", Threshold: 0.00, DiscountRate: 0.50, CardTypeFilter: "student", Status: "active"},
-		{PolicyName: "老人卡免费", PolicyType: "elder", Threshold: 0.00, DiscountRate: 1.00, CardTypeFilter: "elder", Status: "active"},
+		{PolicyName: "月度累计折扣-8折", PolicyType: "monthly_accumulate", Threshold: models.MustParseMoney("200.00"), DiscountRate: 0.80, Status: "active"},
+		{PolicyName: "月度累计折扣-5折", PolicyType: "monthly_accumulate", Threshold: models.MustParseMoney("500.00"), DiscountRate: 0.50, Status: "active"},
+		{PolicyName: "学生卡折扣", PolicyType: "student", Threshold: 0, DiscountRate: 0.50, CardTypeFilter: "student", Status: "active"},
+		{PolicyName: "老人卡免费", PolicyType: "elder", Threshold: 0, DiscountRate: 1.00, CardTypeFilter: "elder", Status: "active"},
 	}
 
 	for _, policy := range discountPolicies {
@@ -172,9 +172,18 @@ func main() {
 	}
 	db.FirstOrCreate(&admin, models.User{Username: admin.Username})
 
+	// 票价配置变更的提交人与审批人
+	fareUsers := []models.User{
+		{Username: "fare_editor", Password: "editor123", RealName: "票价专员", Role: models.RoleFareEditor, Status: "active"},
+		{Username: "fare_approver", Password: "approver123", RealName: "票价审批人", Role: models.RoleFareApprover, Status: "active"},
+	}
+	for _, user := range fareUsers {
+		db.FirstOrCreate(&user, models.User{Username: user.Username})
+	}
+
 	// 8. 创建示例卡片（当前模型仅保存UID）
 	cards := []models.Card{
-		{CardID: "A4ABFC7C", HolderName: "", CardType: "normal", Status: "active", Balance: 0.0},
+		{CardID: "A4ABFC7C", HolderName: "", CardType: "normal", Status: "active", Balance: 0},
 	}
 	for _, card := range cards {
 		db.FirstOrCreate(&card, models.Card{CardID: card.CardID})
@@ -182,5 +191,6 @@ func main() {
 
 	fmt.Println("数据库初始化数据已成功创建！")
 	fmt.Println("管理员账号：admin / admin123")
+	fmt.Println("票价配置账号：fare_editor / editor123（提交），fare_approver / approver123（审批）")
 	fmt.Println("示例卡片：A4ABFC7C")
 }
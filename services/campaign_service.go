@@ -0,0 +1,130 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CampaignService struct {
+	db *gorm.DB
+}
+
+func NewCampaignService(db *gorm.DB) *CampaignService {
+	return &CampaignService{db: db}
+}
+
+// ListCampaigns 查询优惠活动（status为空时返回全部）
+func (s *CampaignService) ListCampaigns(status string) ([]models.Campaign, error) {
+	var campaigns []models.Campaign
+	query := s.db.Order("start_time DESC, id DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&campaigns).Error
+	return campaigns, err
+}
+
+// CampaignUsage 活动核销汇总
+type CampaignUsage struct {
+	Rides  int64        `json:"rides"`  // 核销次数
+	Amount models.Money `json:"amount"` // 优惠金额合计
+	Cards  int64        `json:"cards"`  // 享受优惠的卡片数
+}
+
+// campaignUsageColumns 核销汇总字段（次数、优惠金额合计、卡片数）
+const campaignUsageColumns = "COUNT(*) AS rides, COALESCE(SUM(discount_amount), 0) AS amount, COUNT(DISTINCT card_id) AS cards"
+
+// GetCampaignUsage 查询活动已核销的次数、金额与卡片数
+func GetCampaignUsage(db *gorm.DB, campaignID uint) CampaignUsage {
+	var usage CampaignUsage
+	db.Model(&models.CampaignRedemption{}).
+		Select(campaignUsageColumns).
+		Where("campaign_id = ?", campaignID).
+		Scan(&usage)
+	return usage
+}
+
+// RecordCampaignRedemption 记录活动核销（同一交易同一活动只记录一次）
+func RecordCampaignRedemption(db *gorm.DB, campaignID uint, transaction *models.Transaction, amount models.Money) error {
+	redemption := models.CampaignRedemption{
+		CampaignID:     campaignID,
+		RecordID:       transaction.RecordID,
+		CardID:         transaction.CardID,
+		RouteID:        transaction.RouteID,
+		DiscountAmount: amount,
+		RedeemedAt:     transaction.BoardTime,
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&redemption).Error; err != nil {
+		return fmt.Errorf("记录活动核销失败: %w", err)
+	}
+	return nil
+}
+
+// CampaignRedemptionReport 活动核销报表
+type CampaignRedemptionReport struct {
+	Campaign        models.Campaign         `json:"campaign"`
+	Usage           CampaignUsage           `json:"usage"`                      // 活动累计核销
+	RemainingBudget *models.Money           `json:"remaining_budget,omitempty"` // 剩余预算（按已预留金额计算，不限预算时为空）
+	RemainingRides  *int64                  `json:"remaining_rides,omitempty"`  // 剩余次数（按已预留次数计算，不限次数时为空）
+	Period          CampaignUsage           `json:"period"`                     // 查询时间段内的核销
+	ByDate          []CampaignRedemptionRow `json:"by_date"`                    // 按日期汇总
+	ByRoute         []CampaignRedemptionRow `json:"by_route"`                   // 按线路汇总
+}
+
+// CampaignRedemptionRow 活动核销汇总行
+type CampaignRedemptionRow struct {
+	Key    string       `json:"key"` // 日期（YYYY-MM-DD）或线路ID
+	Rides  int64        `json:"rides"`
+	Amount models.Money `json:"amount"`
+	Cards  int64        `json:"cards"`
+}
+
+// GetRedemptionReport 查询活动核销报表（start/end为空时统计全部核销，end不含）
+func (s *CampaignService) GetRedemptionReport(campaignID uint, start, end *time.Time) (*CampaignRedemptionReport, error) {
+	db := s.db
+	var campaign models.Campaign
+	if err := db.First(&campaign, campaignID).Error; err != nil {
+		return nil, fmt.Errorf("活动不存在")
+	}
+
+	report := &CampaignRedemptionReport{
+		Campaign: campaign,
+		Usage:    GetCampaignUsage(db, campaignID),
+	}
+	if campaign.Budget > 0 {
+		remaining := campaign.Budget - campaign.UsedAmount
+		report.RemainingBudget = &remaining
+	}
+	if campaign.MaxRides > 0 {
+		remaining := int64(campaign.MaxRides - campaign.UsedRides)
+		report.RemainingRides = &remaining
+	}
+
+	scoped := func() *gorm.DB {
+		query := db.Model(&models.CampaignRedemption{}).Where("campaign_id = ?", campaignID)
+		if start != nil {
+			query = query.Where("redeemed_at >= ?", *start)
+		}
+		if end != nil {
+			query = query.Where("redeemed_at < ?", *end)
+		}
+		return query
+	}
+
+	if err := scoped().Select(campaignUsageColumns).Scan(&report.Period).Error; err != nil {
+		return nil, fmt.Errorf("查询核销记录失败: %w", err)
+	}
+	if err := scoped().Select("TO_CHAR(redeemed_at, 'YYYY-MM-DD') AS key, " + campaignUsageColumns).
+		Group("key").Order("key ASC").Scan(&report.ByDate).Error; err != nil {
+		return nil, fmt.Errorf("查询核销记录失败: %w", err)
+	}
+	if err := scoped().Select("CAST(route_id AS TEXT) AS key, " + campaignUsageColumns).
+		Group("route_id").Order("route_id ASC").Scan(&report.ByRoute).Error; err != nil {
+		return nil, fmt.Errorf("查询核销记录失败: %w", err)
+	}
+	return report, nil
+}
@@ -19,7 +19,7 @@ type CardFilter struct {
 
 type CardDiscount struct {
 	DiscountRate   float64
-	DiscountAmount float64
+	DiscountAmount models.Money
 }
 
 func NewCardService(db *gorm.DB) *CardService {
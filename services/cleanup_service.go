@@ -38,6 +38,15 @@ func (s *CleanupService) CleanupTapEvents(retentionDays int) (int64, error) {
 	return result.RowsAffected, nil
 }
 
+// CleanupDeviceNonces 清理已过期的网关签名随机数
+func (s *CleanupService) CleanupDeviceNonces() (int64, error) {
+	result := s.db.Where("expires_at < ?", time.Now()).Delete(&models.DeviceNonce{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("清理签名随机数失败: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
 // StartCleanupTask 启动数据清理定时任务
 func (s *CleanupService) StartCleanupTask(intervalHours int, retentionDays int) {
 	if intervalHours <= 0 {
@@ -56,6 +65,13 @@ func (s *CleanupService) StartCleanupTask(intervalHours int, retentionDays int)
 			} else if count > 0 {
 				fmt.Printf("清理了 %d 条过期的TapEvent记录\n", count)
 			}
+
+			count, err = s.CleanupDeviceNonces()
+			if err != nil {
+				fmt.Printf("清理签名随机数失败: %v\n", err)
+			} else if count > 0 {
+				fmt.Printf("清理了 %d 条过期的签名随机数\n", count)
+			}
 		}
 	}()
 }
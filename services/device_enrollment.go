@@ -0,0 +1,164 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"TapTransit-backend/utils"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// defaultEnrollmentMinutes 注册令牌默认有效期（分钟）
+const defaultEnrollmentMinutes = 24 * 60
+
+// EnrollmentTokenRequest 签发注册令牌请求
+type EnrollmentTokenRequest struct {
+	DeviceID         string `json:"device_id" binding:"required"` // 网关设备ID（设备不存在时自动登记）
+	ExpiresInMinutes int    `json:"expires_in_minutes"`           // 有效期（分钟，默认1440）
+}
+
+// EnrollmentTokenResponse 签发的注册令牌（明文只返回一次）
+type EnrollmentTokenResponse struct {
+	DeviceID  string    `json:"device_id"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// EnrollRequest 网关兑换设备凭证请求
+type EnrollRequest struct {
+	EnrollmentToken string `json:"enrollment_token" binding:"required"` // 注册令牌
+	DeviceID        string `json:"device_id"`                           // 网关设备ID（可选，提供时须与令牌签发的设备一致）
+}
+
+// EnrollResponse 兑换的设备凭证与签名密钥（明文只返回一次）
+type EnrollResponse struct {
+	DeviceID      string    `json:"device_id"`
+	Credential    string    `json:"credential"`
+	SigningSecret string    `json:"signing_secret"` // 批量上传请求签名密钥
+	IssuedAt      time.Time `json:"issued_at"`
+}
+
+// CreateEnrollmentToken 为网关签发一次性注册令牌
+func (s *DeviceService) CreateEnrollmentToken(user *models.User, req EnrollmentTokenRequest) (*EnrollmentTokenResponse, error) {
+	if user == nil {
+		return nil, fmt.Errorf("未登录")
+	}
+	deviceID := strings.TrimSpace(req.DeviceID)
+	if deviceID == "" {
+		return nil, fmt.Errorf("设备ID不能为空")
+	}
+	minutes := req.ExpiresInMinutes
+	if minutes < 0 {
+		return nil, fmt.Errorf("有效期不能为负数")
+	}
+	if minutes == 0 {
+		minutes = defaultEnrollmentMinutes
+	}
+
+	device := models.Device{DeviceID: deviceID, DeviceType: "gateway", Status: "active"}
+	if err := s.db.Where("device_id = ?", deviceID).FirstOrCreate(&device).Error; err != nil {
+		return nil, fmt.Errorf("登记设备失败: %w", err)
+	}
+	if device.Status == "inactive" {
+		return nil, fmt.Errorf("设备%s已停用", deviceID)
+	}
+
+	token, err := utils.GenerateToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("生成令牌失败: %w", err)
+	}
+	enrollment := models.DeviceEnrollmentToken{
+		TokenHash: utils.HashToken(token),
+		DeviceID:  device.ID,
+		CreatedBy: user.ID,
+		ExpiresAt: time.Now().Add(time.Duration(minutes) * time.Minute),
+	}
+	if err := s.db.Create(&enrollment).Error; err != nil {
+		return nil, fmt.Errorf("保存注册令牌失败: %w", err)
+	}
+	return &EnrollmentTokenResponse{DeviceID: device.DeviceID, Token: token, ExpiresAt: enrollment.ExpiresAt}, nil
+}
+
+// Enroll 网关以注册令牌兑换设备凭证（令牌只能使用一次，设备原有凭证同时吊销）
+func (s *DeviceService) Enroll(req EnrollRequest) (*EnrollResponse, error) {
+	var enrollment models.DeviceEnrollmentToken
+	if err := s.db.Where("token_hash = ?", utils.HashToken(strings.TrimSpace(req.EnrollmentToken))).First(&enrollment).Error; err != nil {
+		return nil, fmt.Errorf("注册令牌无效")
+	}
+	if enrollment.UsedAt != nil {
+		return nil, fmt.Errorf("注册令牌已使用")
+	}
+	now := time.Now()
+	if !enrollment.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("注册令牌已过期")
+	}
+	var device models.Device
+	if err := s.db.First(&device, enrollment.DeviceID).Error; err != nil {
+		return nil, fmt.Errorf("设备不存在")
+	}
+	if req.DeviceID != "" && req.DeviceID != device.DeviceID {
+		return nil, fmt.Errorf("注册令牌不是为设备%s签发的", req.DeviceID)
+	}
+	if device.Status == "inactive" {
+		return nil, fmt.Errorf("设备%s已停用", device.DeviceID)
+	}
+
+	credential, err := utils.GenerateToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("生成凭证失败: %w", err)
+	}
+	signingSecret, err := utils.GenerateToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("生成签名密钥失败: %w", err)
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 条件更新保证并发兑换时只有一次成功
+		result := tx.Model(&models.DeviceEnrollmentToken{}).
+			Where("id = ? AND used_at IS NULL", enrollment.ID).Update("used_at", now)
+		if result.Error != nil {
+			return fmt.Errorf("更新注册令牌失败: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("注册令牌已使用")
+		}
+		if err := revokeDeviceCredentials(tx, device.ID, now); err != nil {
+			return err
+		}
+		return tx.Create(&models.DeviceCredential{
+			TokenHash:         utils.HashToken(credential),
+			SigningSecret:     signingSecret,
+			DeviceID:          device.ID,
+			EnrollmentTokenID: enrollment.ID,
+			Status:            "active",
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &EnrollResponse{DeviceID: device.DeviceID, Credential: credential, SigningSecret: signingSecret, IssuedAt: now}, nil
+}
+
+// RevokeCredentials 吊销设备的全部有效凭证（设备需重新注册）
+func (s *DeviceService) RevokeCredentials(id uint) (*models.Device, error) {
+	var device models.Device
+	if err := s.db.First(&device, id).Error; err != nil {
+		return nil, fmt.Errorf("设备不存在")
+	}
+	if err := revokeDeviceCredentials(s.db, device.ID, time.Now()); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// revokeDeviceCredentials 吊销设备的全部有效凭证
+func revokeDeviceCredentials(db *gorm.DB, deviceID uint, at time.Time) error {
+	err := db.Model(&models.DeviceCredential{}).
+		Where("device_id = ? AND status = ?", deviceID, "active").
+		Updates(map[string]interface{}{"status": "revoked", "revoked_at": at}).Error
+	if err != nil {
+		return fmt.Errorf("吊销设备凭证失败: %w", err)
+	}
+	return nil
+}
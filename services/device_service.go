@@ -0,0 +1,187 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 设备连接状态
+const (
+	DeviceConnectionUnknown = "unknown" // 从未上线
+	DeviceConnectionOnline  = "online"
+	DeviceConnectionOffline = "offline"
+)
+
+// DeviceService 网关设备心跳与在线状态
+type DeviceService struct {
+	db *gorm.DB
+}
+
+func NewDeviceService(db *gorm.DB) *DeviceService {
+	return &DeviceService{db: db}
+}
+
+// HeartbeatRequest 网关心跳请求
+type HeartbeatRequest struct {
+	GatewayID       string        `json:"gateway_id"`       // 网关设备ID（可选，以设备凭证对应的设备为准）
+	FirmwareVersion string        `json:"firmware_version"` // 固件版本
+	ConfigVersion   string        `json:"config_version"`   // 当前使用的配置版本
+	QueueDepth      int           `json:"queue_depth"`      // 待上传记录数
+	Clock           *FlexibleTime `json:"clock"`            // 设备当前时钟
+	Latitude        *float64      `json:"latitude"`         // GPS纬度
+	Longitude       *float64      `json:"longitude"`        // GPS经度
+}
+
+// HeartbeatResponse 心跳响应（网关可据此校准时钟）
+type HeartbeatResponse struct {
+	ServerTime  time.Time `json:"server_time"`  // 服务器时间
+	ClockOffset int64     `json:"clock_offset"` // 设备时钟与服务器时钟之差（秒）
+}
+
+// DeviceQuery 设备查询条件
+type DeviceQuery struct {
+	Status    string `form:"status"`     // online/offline/unknown按连接状态过滤，active/inactive/maintenance按设备状态过滤
+	VehicleID uint   `form:"vehicle_id"` // 安装的车辆ID
+}
+
+// Heartbeat 记录网关心跳
+func (s *DeviceService) Heartbeat(req HeartbeatRequest) (*HeartbeatResponse, error) {
+	now := time.Now()
+	if req.QueueDepth < 0 {
+		return nil, fmt.Errorf("待上传记录数不能为负数")
+	}
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		return nil, fmt.Errorf("GPS经纬度需同时上报")
+	}
+	if req.Latitude != nil && (*req.Latitude < -90 || *req.Latitude > 90 || *req.Longitude < -180 || *req.Longitude > 180) {
+		return nil, fmt.Errorf("GPS经纬度超出范围")
+	}
+
+	device, err := touchDevice(s.db, req.GatewayID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var clockOffset int64
+	if req.Clock != nil && !req.Clock.IsZero() {
+		clockOffset = int64(req.Clock.Sub(now).Seconds())
+	}
+	updates := map[string]interface{}{
+		"firmware_version": req.FirmwareVersion,
+		"config_version":   req.ConfigVersion,
+		"queue_depth":      req.QueueDepth,
+		"clock_offset":     clockOffset,
+		"last_heartbeat":   now,
+	}
+	// 未定位时保留最后一次上报的位置
+	if req.Latitude != nil {
+		updates["latitude"] = *req.Latitude
+		updates["longitude"] = *req.Longitude
+	}
+	if err := s.db.Model(device).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("保存心跳失败: %w", err)
+	}
+
+	return &HeartbeatResponse{ServerTime: now, ClockOffset: clockOffset}, nil
+}
+
+// ListDevices 查询设备
+func (s *DeviceService) ListDevices(query DeviceQuery) ([]models.Device, error) {
+	var devices []models.Device
+	db := s.db.Order("device_id ASC")
+	switch query.Status {
+	case "":
+	case DeviceConnectionOnline, DeviceConnectionOffline, DeviceConnectionUnknown:
+		db = db.Where("connection_status = ?", query.Status)
+	case "active", "inactive", "maintenance":
+		db = db.Where("status = ?", query.Status)
+	default:
+		return nil, fmt.Errorf("状态错误: %s（应为online、offline、unknown、active、inactive或maintenance）", query.Status)
+	}
+	if query.VehicleID > 0 {
+		db = db.Where("vehicle_id = ?", query.VehicleID)
+	}
+	err := db.Find(&devices).Error
+	return devices, err
+}
+
+// MarkOfflineDevices 将超过阈值未上线的启用设备标记为离线，返回新判定离线的设备
+func (s *DeviceService) MarkOfflineDevices(thresholdMinutes int) ([]models.Device, error) {
+	cutoff := time.Now().Add(-time.Duration(thresholdMinutes) * time.Minute)
+
+	var devices []models.Device
+	err := s.db.Where("status = ? AND connection_status = ? AND last_seen < ?", "active", DeviceConnectionOnline, cutoff).
+		Find(&devices).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询设备失败: %w", err)
+	}
+
+	var marked []models.Device
+	for _, device := range devices {
+		// 条件更新，避免覆盖检查期间刚刚上线的设备
+		result := s.db.Model(&models.Device{}).
+			Where("id = ? AND connection_status = ? AND last_seen < ?", device.ID, DeviceConnectionOnline, cutoff).
+			Updates(map[string]interface{}{
+				"connection_status": DeviceConnectionOffline,
+				"offline_since":     device.LastSeen,
+			})
+		if result.Error != nil {
+			return marked, fmt.Errorf("更新设备%s状态失败: %w", device.DeviceID, result.Error)
+		}
+		if result.RowsAffected > 0 {
+			device.ConnectionStatus = DeviceConnectionOffline
+			device.OfflineSince = device.LastSeen
+			marked = append(marked, device)
+		}
+	}
+	return marked, nil
+}
+
+// StartOfflineChecker 启动设备离线检查定时任务
+func (s *DeviceService) StartOfflineChecker(intervalMinutes int, thresholdMinutes int) {
+	if intervalMinutes <= 0 {
+		intervalMinutes = 1 // 默认每分钟检查一次
+	}
+	if thresholdMinutes <= 0 {
+		thresholdMinutes = 10 // 默认10分钟未上线视为离线
+	}
+
+	ticker := time.NewTicker(time.Duration(intervalMinutes) * time.Minute)
+	go func() {
+		for range ticker.C {
+			devices, err := s.MarkOfflineDevices(thresholdMinutes)
+			if err != nil {
+				fmt.Printf("设备离线检查失败: %v\n", err)
+			}
+			for _, device := range devices {
+				fmt.Printf("设备离线告警: 设备 %s（车辆 %s）最后在线时间 %s，已超过 %d 分钟未上线\n",
+					device.DeviceID, device.VehicleNumber, device.LastSeen.Format(time.RFC3339), thresholdMinutes)
+			}
+		}
+	}()
+}
+
+// touchDevice 刷新设备最后在线时间并标记为在线（设备未登记时自动登记为网关）
+func touchDevice(db *gorm.DB, gatewayID string, at time.Time) (*models.Device, error) {
+	gatewayID = strings.TrimSpace(gatewayID)
+	if gatewayID == "" {
+		return nil, fmt.Errorf("网关设备ID不能为空")
+	}
+	device := models.Device{DeviceID: gatewayID, DeviceType: "gateway", Status: "active"}
+	if err := db.Where("device_id = ?", gatewayID).FirstOrCreate(&device).Error; err != nil {
+		return nil, fmt.Errorf("登记设备失败: %w", err)
+	}
+	err := db.Model(&device).Updates(map[string]interface{}{
+		"last_seen":         at,
+		"connection_status": DeviceConnectionOnline,
+		"offline_since":     nil,
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("更新设备在线状态失败: %w", err)
+	}
+	return &device, nil
+}
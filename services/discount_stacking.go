@@ -0,0 +1,207 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"strings"
+)
+
+// 优惠类别（用于叠加策略中的顺序与互斥组配置）
+const (
+	DiscountKindCardType = "card_type" // 特殊票种
+	DiscountKindTransfer = "transfer"  // 换乘优惠
+	DiscountKindMonthly  = "monthly"   // 月度累计折扣
+)
+
+// 叠加策略选择模式
+const (
+	StackingModeStack  = "stack"   // 按顺序叠加
+	StackingModeBestOf = "best_of" // 只取单项最优
+)
+
+// stackingPolicy 解析后的优惠叠加策略
+type stackingPolicy struct {
+	rule   string // 策略来源（用于计费明细）
+	order  []string
+	groups [][]string
+}
+
+// defaultStackingPolicy 默认叠加策略：优惠活动 → 特殊票种 → 换乘优惠 → 月度折扣，全部叠加
+func defaultStackingPolicy() stackingPolicy {
+	return stackingPolicy{
+		rule:  "default_stacking",
+		order: []string{DiscountKindCampaign, DiscountKindCardType, DiscountKindTransfer, DiscountKindMonthly},
+	}
+}
+
+// getStackingPolicy 获取线路的优惠叠加策略（线路策略 > 线路所属运营方的策略 > 全局默认策略 > 内置默认）
+func (s *FareService) getStackingPolicy(route *models.Route) stackingPolicy {
+	var policies []models.DiscountStackingPolicy
+	err := s.db.Where("(route_id = ? OR route_id = 0) AND status = 'active'", route.ID).
+		Order("id ASC").Find(&policies).Error
+	if err != nil {
+		return defaultStackingPolicy()
+	}
+	policy, ok := selectStackingPolicy(policies, route)
+	if !ok {
+		return defaultStackingPolicy()
+	}
+	return parseStackingPolicy(policy)
+}
+
+// selectStackingPolicy 在候选策略中按线路 > 运营方 > 全局默认的优先级选择线路适用的策略
+// （同一级别有多条策略时取ID最小的一条）
+func selectStackingPolicy(policies []models.DiscountStackingPolicy, route *models.Route) (*models.DiscountStackingPolicy, bool) {
+	var operatorPolicy, globalPolicy *models.DiscountStackingPolicy
+	for i := range policies {
+		policy := &policies[i]
+		switch {
+		case policy.RouteID != 0:
+			if policy.RouteID == route.ID {
+				return policy, true
+			}
+		case policy.Operator == "":
+			if globalPolicy == nil {
+				globalPolicy = policy
+			}
+		case policy.Operator == route.Operator:
+			if operatorPolicy == nil {
+				operatorPolicy = policy
+			}
+		}
+	}
+	if operatorPolicy != nil {
+		return operatorPolicy, true
+	}
+	return globalPolicy, globalPolicy != nil
+}
+
+// parseStackingPolicy 将数据库中的叠加策略解析为顺序与互斥组
+func parseStackingPolicy(policy *models.DiscountStackingPolicy) stackingPolicy {
+	parsed := stackingPolicy{
+		rule:  fareRuleRef("discount_stacking_policies", policy.ID),
+		order: splitList(policy.ApplyOrder),
+	}
+	if len(parsed.order) == 0 {
+		return defaultStackingPolicy()
+	}
+	// 未配置优惠活动的策略：活动优惠最先应用（活动期间的免费/优惠出行不受原有策略影响）
+	if !listContains(policy.ApplyOrder, DiscountKindCampaign) {
+		parsed.order = append([]string{DiscountKindCampaign}, parsed.order...)
+	}
+
+	if policy.SelectionMode == StackingModeBestOf {
+		// best_of等价于所有优惠同属一个互斥组
+		parsed.groups = [][]string{parsed.order}
+		return parsed
+	}
+
+	for _, group := range strings.Split(policy.ExclusiveGroups, ";") {
+		kinds := splitList(group)
+		if len(kinds) > 1 {
+			parsed.groups = append(parsed.groups, kinds)
+		}
+	}
+	return parsed
+}
+
+// splitList 解析逗号分隔的配置列表（优惠类别、线路ID等，忽略空白项）
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// groupOf 返回优惠类别所属的互斥组下标（-1表示不属于任何互斥组）
+func (p stackingPolicy) groupOf(kind string) int {
+	for i, group := range p.groups {
+		for _, member := range group {
+			if member == kind {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// applyStackedDiscounts 按叠加策略依次应用优惠
+// 互斥组在顺序中第一次出现的位置统一择优：组内各项按当时的应付金额计算，只应用优惠金额最大的一项
+// 应付金额降为0后不再应用后续优惠
+func applyStackedDiscounts(policy stackingPolicy, rules map[string]DiscountRule, fc *FareContext) {
+	result := fc.Result
+	decidedGroups := make(map[int]bool)
+
+	for _, kind := range policy.order {
+		if result.ActualFare <= 0 {
+			return
+		}
+
+		candidates := []string{kind}
+		if group := policy.groupOf(kind); group >= 0 {
+			if decidedGroups[group] {
+				continue
+			}
+			decidedGroups[group] = true
+			candidates = policy.groups[group]
+		}
+
+		var best Discount
+		var evaluated map[string]interface{}
+		if len(candidates) > 1 {
+			evaluated = make(map[string]interface{}, len(candidates))
+		}
+		for _, candidate := range candidates {
+			rule, ok := rules[candidate]
+			if !ok {
+				continue
+			}
+			discount := rule.Evaluate(fc, result.ActualFare)
+			if evaluated != nil {
+				evaluated[candidate] = discount.Amount
+			}
+			if discount.Amount > best.Amount {
+				best = discount
+			}
+		}
+		if best.Amount <= 0 {
+			continue
+		}
+
+		if best.Amount > result.ActualFare {
+			best.Amount = result.ActualFare
+		}
+		before := result.ActualFare
+		result.ActualFare -= best.Amount
+		result.DiscountAmount += best.Amount
+		if result.DiscountType != "" {
+			result.DiscountType += "," + best.Type
+		} else {
+			result.DiscountType = best.Type
+		}
+
+		step := models.FareTraceStep{
+			Stage:       StageDiscounts,
+			Rule:        best.Rule,
+			Description: best.Description,
+			Before:      before,
+			After:       result.ActualFare,
+			Details: map[string]interface{}{
+				"discount_type":   best.Type,
+				"discount_amount": best.Amount,
+				"stacking_policy": policy.rule,
+			},
+		}
+		if evaluated != nil {
+			// 互斥组择优：记录组内各项的候选优惠金额
+			step.Details["exclusive_candidates"] = evaluated
+		}
+		fc.AddTrace(step)
+		if best.OnApply != nil {
+			best.OnApply(fc, best.Amount)
+		}
+	}
+}
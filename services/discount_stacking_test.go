@@ -0,0 +1,252 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"reflect"
+	"testing"
+)
+
+// stubDiscountRule 测试用优惠规则：固定金额或按当时应付金额的比例计算优惠
+type stubDiscountRule struct {
+	kind   string
+	amount models.Money
+	rate   float64
+}
+
+func (r stubDiscountRule) Kind() string { return r.kind }
+
+func (r stubDiscountRule) Evaluate(fc *FareContext, currentFare models.Money) Discount {
+	amount := r.amount
+	if r.rate > 0 {
+		amount = discountByRate(currentFare, r.rate, models.RoundDown)
+	}
+	if amount <= 0 {
+		return Discount{}
+	}
+	return Discount{Amount: amount, Type: r.kind, Rule: "stub:" + r.kind}
+}
+
+func stubRules(rules ...stubDiscountRule) map[string]DiscountRule {
+	result := make(map[string]DiscountRule, len(rules))
+	for _, rule := range rules {
+		result[rule.kind] = rule
+	}
+	return result
+}
+
+func TestParseStackingPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     models.DiscountStackingPolicy
+		wantOrder  []string
+		wantGroups [][]string
+	}{
+		{
+			name:      "未配置活动时活动最先应用",
+			policy:    models.DiscountStackingPolicy{ApplyOrder: "card_type, transfer,monthly"},
+			wantOrder: []string{"campaign", "card_type", "transfer", "monthly"},
+		},
+		{
+			name:      "保留配置的活动位置",
+			policy:    models.DiscountStackingPolicy{ApplyOrder: "card_type,campaign,monthly"},
+			wantOrder: []string{"card_type", "campaign", "monthly"},
+		},
+		{
+			name:       "互斥组忽略单项组",
+			policy:     models.DiscountStackingPolicy{ApplyOrder: "card_type,transfer,monthly", ExclusiveGroups: "card_type,monthly;transfer"},
+			wantOrder:  []string{"campaign", "card_type", "transfer", "monthly"},
+			wantGroups: [][]string{{"card_type", "monthly"}},
+		},
+		{
+			name:       "best_of为全部优惠同属一组",
+			policy:     models.DiscountStackingPolicy{ApplyOrder: "card_type,transfer", ExclusiveGroups: "card_type,transfer", SelectionMode: StackingModeBestOf},
+			wantOrder:  []string{"campaign", "card_type", "transfer"},
+			wantGroups: [][]string{{"campaign", "card_type", "transfer"}},
+		},
+		{
+			name:      "顺序为空时使用默认策略",
+			policy:    models.DiscountStackingPolicy{ApplyOrder: " , "},
+			wantOrder: defaultStackingPolicy().order,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseStackingPolicy(&tt.policy)
+			if !reflect.DeepEqual(got.order, tt.wantOrder) {
+				t.Errorf("order = %v，应为 %v", got.order, tt.wantOrder)
+			}
+			if !reflect.DeepEqual(got.groups, tt.wantGroups) {
+				t.Errorf("groups = %v，应为 %v", got.groups, tt.wantGroups)
+			}
+		})
+	}
+}
+
+func TestApplyStackedDiscounts(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       models.DiscountStackingPolicy
+		rules        map[string]DiscountRule
+		fare         models.Money
+		wantFare     models.Money
+		wantDiscount models.Money
+		wantType     string
+		wantSteps    int
+	}{
+		{
+			name:   "按顺序叠加，比例优惠按当时应付金额计算",
+			policy: models.DiscountStackingPolicy{ApplyOrder: "card_type,transfer,monthly"},
+			rules: stubRules(
+				stubDiscountRule{kind: DiscountKindCardType, rate: 0.5},
+				stubDiscountRule{kind: DiscountKindTransfer, amount: 50},
+				stubDiscountRule{kind: DiscountKindMonthly, rate: 0.2},
+			),
+			fare:         400,
+			wantFare:     120, // 400 → 200 → 150 → 120
+			wantDiscount: 280,
+			wantType:     "card_type,transfer,monthly",
+			wantSteps:    3,
+		},
+		{
+			name:   "顺序决定比例优惠的计算基数",
+			policy: models.DiscountStackingPolicy{ApplyOrder: "monthly,transfer"},
+			rules: stubRules(
+				stubDiscountRule{kind: DiscountKindTransfer, amount: 50},
+				stubDiscountRule{kind: DiscountKindMonthly, rate: 0.2},
+			),
+			fare:         400,
+			wantFare:     270, // 400 → 320 → 270
+			wantDiscount: 130,
+			wantType:     "monthly,transfer",
+			wantSteps:    2,
+		},
+		{
+			name:   "互斥组只取优惠金额最大的一项",
+			policy: models.DiscountStackingPolicy{ApplyOrder: "card_type,transfer,monthly", ExclusiveGroups: "card_type,monthly"},
+			rules: stubRules(
+				stubDiscountRule{kind: DiscountKindCardType, amount: 100},
+				stubDiscountRule{kind: DiscountKindTransfer, amount: 50},
+				stubDiscountRule{kind: DiscountKindMonthly, rate: 0.5},
+			),
+			fare:         400,
+			wantFare:     150, // 组内monthly 200 > card_type 100，在card_type的位置应用：400 → 200 → 150
+			wantDiscount: 250,
+			wantType:     "monthly,transfer",
+			wantSteps:    2,
+		},
+		{
+			name:   "best_of只应用单项最优",
+			policy: models.DiscountStackingPolicy{ApplyOrder: "card_type,transfer,monthly", SelectionMode: StackingModeBestOf},
+			rules: stubRules(
+				stubDiscountRule{kind: DiscountKindCampaign, amount: 30},
+				stubDiscountRule{kind: DiscountKindCardType, amount: 100},
+				stubDiscountRule{kind: DiscountKindTransfer, amount: 150},
+				stubDiscountRule{kind: DiscountKindMonthly, rate: 0.2},
+			),
+			fare:         400,
+			wantFare:     250,
+			wantDiscount: 150,
+			wantType:     "transfer",
+			wantSteps:    1,
+		},
+		{
+			name:   "优惠不超过应付金额，降为0后不再应用",
+			policy: models.DiscountStackingPolicy{ApplyOrder: "card_type,transfer,monthly"},
+			rules: stubRules(
+				stubDiscountRule{kind: DiscountKindCardType, amount: 150},
+				stubDiscountRule{kind: DiscountKindTransfer, amount: 100},
+				stubDiscountRule{kind: DiscountKindMonthly, amount: 100},
+			),
+			fare:         200,
+			wantFare:     0,
+			wantDiscount: 200,
+			wantType:     "card_type,transfer",
+			wantSteps:    2,
+		},
+		{
+			name:         "没有适用的优惠",
+			policy:       models.DiscountStackingPolicy{ApplyOrder: "card_type,transfer"},
+			rules:        stubRules(stubDiscountRule{kind: DiscountKindTransfer}),
+			fare:         200,
+			wantFare:     200,
+			wantDiscount: 0,
+			wantType:     "",
+			wantSteps:    0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := &FareContext{Result: &FareCalculationResult{BaseFare: tt.fare, ActualFare: tt.fare}}
+			applyStackedDiscounts(parseStackingPolicy(&tt.policy), tt.rules, fc)
+
+			if fc.Result.ActualFare != tt.wantFare {
+				t.Errorf("ActualFare = %s，应为 %s", fc.Result.ActualFare, tt.wantFare)
+			}
+			if fc.Result.DiscountAmount != tt.wantDiscount {
+				t.Errorf("DiscountAmount = %s，应为 %s", fc.Result.DiscountAmount, tt.wantDiscount)
+			}
+			if fc.Result.DiscountType != tt.wantType {
+				t.Errorf("DiscountType = %q，应为 %q", fc.Result.DiscountType, tt.wantType)
+			}
+			if len(fc.Result.Trace) != tt.wantSteps {
+				t.Errorf("计费明细 %d 步，应为 %d 步", len(fc.Result.Trace), tt.wantSteps)
+			}
+		})
+	}
+}
+
+func TestApplyStackedDiscountsRecordsExclusiveCandidates(t *testing.T) {
+	policy := parseStackingPolicy(&models.DiscountStackingPolicy{ApplyOrder: "card_type,monthly", ExclusiveGroups: "card_type,monthly"})
+	rules := stubRules(
+		stubDiscountRule{kind: DiscountKindCardType, amount: 100},
+		stubDiscountRule{kind: DiscountKindMonthly, amount: 80},
+	)
+	fc := &FareContext{Result: &FareCalculationResult{ActualFare: 400}}
+	applyStackedDiscounts(policy, rules, fc)
+
+	if len(fc.Result.Trace) != 1 {
+		t.Fatalf("计费明细 %d 步，应为 1 步", len(fc.Result.Trace))
+	}
+	candidates, ok := fc.Result.Trace[0].Details["exclusive_candidates"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("计费明细缺少互斥组候选优惠")
+	}
+	if candidates[DiscountKindCardType] != models.Money(100) || candidates[DiscountKindMonthly] != models.Money(80) {
+		t.Errorf("互斥组候选优惠 = %v", candidates)
+	}
+}
+
+func TestSelectStackingPolicy(t *testing.T) {
+	policies := []models.DiscountStackingPolicy{
+		{ID: 1, RouteID: 0, Operator: ""},
+		{ID: 2, RouteID: 0, Operator: "metro"},
+		{ID: 3, RouteID: 7},
+		{ID: 4, RouteID: 0, Operator: "metro"},
+		{ID: 5, RouteID: 0, Operator: "bus"},
+	}
+	tests := []struct {
+		name     string
+		policies []models.DiscountStackingPolicy
+		route    models.Route
+		wantID   uint
+		wantOK   bool
+	}{
+		{name: "线路策略优先", policies: policies, route: models.Route{ID: 7, Operator: "metro"}, wantID: 3, wantOK: true},
+		{name: "运营方策略优先于全局默认", policies: policies, route: models.Route{ID: 8, Operator: "metro"}, wantID: 2, wantOK: true},
+		{name: "其他运营方使用各自策略", policies: policies, route: models.Route{ID: 8, Operator: "bus"}, wantID: 5, wantOK: true},
+		{name: "运营方未配置时使用全局默认", policies: policies, route: models.Route{ID: 8, Operator: "ferry"}, wantID: 1, wantOK: true},
+		{name: "线路未设置运营方时使用全局默认", policies: policies, route: models.Route{ID: 8}, wantID: 1, wantOK: true},
+		{name: "没有全局默认时不使用其他运营方的策略", policies: policies[1:], route: models.Route{ID: 8, Operator: "ferry"}, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, ok := selectStackingPolicy(tt.policies, &tt.route)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v，应为 %v", ok, tt.wantOK)
+			}
+			if ok && policy.ID != tt.wantID {
+				t.Errorf("选择的策略 = %d，应为 %d", policy.ID, tt.wantID)
+			}
+		})
+	}
+}
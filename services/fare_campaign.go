@@ -0,0 +1,187 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// DiscountKindCampaign 优惠活动
+const DiscountKindCampaign = "campaign"
+
+// 活动优惠方式
+const (
+	CampaignDiscountFree      = "free"       // 免费
+	CampaignDiscountAmount    = "amount"     // 固定优惠金额
+	CampaignDiscountRate      = "rate"       // 按比例优惠
+	CampaignDiscountFixedFare = "fixed_fare" // 固定票价
+)
+
+// campaignRule 优惠活动（在活动时间窗口内按线路、站点、卡类型匹配，受预算和次数上限约束）
+type campaignRule struct {
+	fareService *FareService
+}
+
+func (r *campaignRule) Kind() string { return DiscountKindCampaign }
+
+func (r *campaignRule) Evaluate(fc *FareContext, currentFare models.Money) Discount {
+	s := r.fareService
+	var campaigns []models.Campaign
+	err := s.db.Where("status = 'active' AND start_time <= ? AND end_time > ?", fc.Request.BoardTime, fc.Request.BoardTime).
+		Order("priority DESC, id ASC").Find(&campaigns).Error
+	if err != nil || len(campaigns) == 0 {
+		return Discount{}
+	}
+
+	cardType := fc.CardType()
+	if cardType == "" {
+		cardType = "normal"
+	}
+	var best Discount
+	for i := range campaigns {
+		campaign := &campaigns[i]
+		if containsUint(fc.Request.ExcludedCampaigns, campaign.ID) ||
+			!listContains(campaign.RouteIDs, strconv.FormatUint(uint64(fc.Route.ID), 10)) ||
+			!listContains(campaign.StationIDs, strconv.FormatUint(uint64(fc.Request.StartStationID), 10)) ||
+			!listContains(campaign.CardTypes, cardType) {
+			continue
+		}
+		discount, ok := s.campaignDiscount(campaign, fc, currentFare)
+		if ok && discount.Amount > best.Amount {
+			best = discount
+		}
+	}
+	return best
+}
+
+// campaignDiscount 计算单个活动的优惠（检查首乘限制、次数上限与剩余预算）
+// 次数与预算按活动已预留的用量判断，记录交易时由ReserveCampaignUsage原子预留
+func (s *FareService) campaignDiscount(campaign *models.Campaign, fc *FareContext, currentFare models.Money) (Discount, bool) {
+	cardID := fc.Request.CardID
+	if (campaign.FirstRideOnly || campaign.MaxRidesPerCard > 0) && cardID == "" {
+		return Discount{}, false
+	}
+	if campaign.FirstRideOnly {
+		var previousRides int64
+		s.db.Model(&models.Transaction{}).
+			Where("card_id = ? AND status = 'completed' AND board_time < ?", cardID, fc.Request.BoardTime).
+			Count(&previousRides)
+		if previousRides > 0 {
+			return Discount{}, false
+		}
+	}
+	if campaign.MaxRidesPerCard > 0 {
+		var cardRides int64
+		s.db.Model(&models.CampaignRedemption{}).Where("campaign_id = ? AND card_id = ?", campaign.ID, cardID).Count(&cardRides)
+		if cardRides >= int64(campaign.MaxRidesPerCard) {
+			return Discount{}, false
+		}
+	}
+
+	if campaign.MaxRides > 0 && campaign.UsedRides >= campaign.MaxRides {
+		return Discount{}, false
+	}
+
+	var amount models.Money
+	var description string
+	switch campaign.DiscountType {
+	case CampaignDiscountFree:
+		amount = currentFare
+		description = fmt.Sprintf("活动%s：免费乘车", campaign.Name)
+	case CampaignDiscountAmount:
+		amount = campaign.DiscountAmount
+		description = fmt.Sprintf("活动%s：优惠%s元", campaign.Name, campaign.DiscountAmount)
+	case CampaignDiscountRate:
+		amount = discountByRate(currentFare, campaign.DiscountRate, s.roundingFor(&fc.Route).Mode)
+		description = fmt.Sprintf("活动%s：优惠比例%.4f", campaign.Name, campaign.DiscountRate)
+	case CampaignDiscountFixedFare:
+		amount = currentFare - campaign.DiscountAmount
+		description = fmt.Sprintf("活动%s：票价%s元", campaign.Name, campaign.DiscountAmount)
+	default:
+		return Discount{}, false
+	}
+	amount = amount.Min(currentFare)
+	if campaign.Budget > 0 {
+		remaining := campaign.Budget - campaign.UsedAmount
+		if remaining <= 0 {
+			return Discount{}, false
+		}
+		if amount > remaining {
+			amount = remaining
+			description += fmt.Sprintf("（受剩余预算%s元限制）", remaining)
+		}
+	}
+	if amount <= 0 {
+		return Discount{}, false
+	}
+
+	campaignID := campaign.ID
+	return Discount{
+		Amount:      amount,
+		Type:        DiscountKindCampaign,
+		Rule:        fareRuleRef("campaigns", campaign.ID),
+		Description: description,
+		OnApply: func(fc *FareContext, applied models.Money) {
+			fc.Result.CampaignID = &campaignID
+			fc.Result.CampaignDiscount = applied
+		},
+	}, true
+}
+
+// CalculateWithCampaignReservation 计算费用并原子预留命中活动的优惠（用于记录交易）；
+// 活动预算或次数已被并发计费用完时，排除该活动重新计费
+func (s *FareService) CalculateWithCampaignReservation(req FareRequest) (*FareCalculationResult, error) {
+	for {
+		result, err := s.Calculate(req)
+		if err != nil || result.CampaignID == nil {
+			return result, err
+		}
+		reserved, err := ReserveCampaignUsage(s.db, *result.CampaignID, result.CampaignDiscount)
+		if err != nil {
+			return nil, err
+		}
+		if reserved {
+			return result, nil
+		}
+		req.ExcludedCampaigns = append(req.ExcludedCampaigns, *result.CampaignID)
+	}
+}
+
+// ReserveCampaignUsage 原子预留活动的优惠次数与金额（不超过总预算和总次数上限），预算或次数已用完时返回false
+func ReserveCampaignUsage(db *gorm.DB, campaignID uint, amount models.Money) (bool, error) {
+	result := db.Model(&models.Campaign{}).
+		Where("id = ? AND (budget = 0 OR used_amount + ? <= budget) AND (max_rides = 0 OR used_rides < max_rides)", campaignID, amount).
+		Updates(map[string]interface{}{
+			"used_amount": gorm.Expr("used_amount + ?", amount),
+			"used_rides":  gorm.Expr("used_rides + 1"),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("预留活动优惠失败: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ReleaseCampaignUsage 释放已预留的活动优惠（交易未能保存时）
+func ReleaseCampaignUsage(db *gorm.DB, campaignID uint, amount models.Money) error {
+	err := db.Model(&models.Campaign{}).Where("id = ?", campaignID).
+		Updates(map[string]interface{}{
+			"used_amount": gorm.Expr("used_amount - ?", amount),
+			"used_rides":  gorm.Expr("used_rides - 1"),
+		}).Error
+	if err != nil {
+		return fmt.Errorf("释放活动优惠失败: %w", err)
+	}
+	return nil
+}
+
+// containsUint 列表是否包含指定ID
+func containsUint(list []uint, id uint) bool {
+	for _, candidate := range list {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
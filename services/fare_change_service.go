@@ -0,0 +1,557 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 变更申请状态
+const (
+	FareChangePending   = "pending"   // 待审批
+	FareChangeScheduled = "scheduled" // 已审批，待到达生效时间
+	FareChangeApplied   = "applied"   // 已生效
+	FareChangeRejected  = "rejected"  // 已驳回
+	FareChangeCancelled = "cancelled" // 已撤回
+	FareChangeFailed    = "failed"    // 生效失败（整体回滚，未修改任何配置）
+)
+
+// 变更操作
+const (
+	FareChangeCreate = "create"
+	FareChangeUpdate = "update"
+	FareChangeDelete = "delete"
+)
+
+// fareChangeEntity 可通过变更申请修改的票价配置
+type fareChangeEntity struct {
+	newModel func() interface{}                     // 创建模型实例
+	fields   []string                               // 允许修改的字段（为空表示除ID与时间戳外的全部字段）
+	actions  []string                               // 允许的操作
+	validate func(db *gorm.DB, v interface{}) error // 字段校验
+}
+
+// fareChangeEntities 变更对象（fare/transfer/discount_policy可新增、修改、删除；campaign可新增、修改（停用即修改status）；
+// route_max_fare只能修改线路的max_fare）
+var fareChangeEntities = map[string]fareChangeEntity{
+	"fare": {
+		newModel: func() interface{} { return &models.Fare{} },
+		actions:  []string{FareChangeCreate, FareChangeUpdate, FareChangeDelete},
+		validate: func(db *gorm.DB, v interface{}) error { return validateFare(db, v.(*models.Fare)) },
+	},
+	"transfer": {
+		newModel: func() interface{} { return &models.Transfer{} },
+		actions:  []string{FareChangeCreate, FareChangeUpdate, FareChangeDelete},
+		validate: func(db *gorm.DB, v interface{}) error { return validateTransfer(db, v.(*models.Transfer)) },
+	},
+	"discount_policy": {
+		newModel: func() interface{} { return &models.DiscountPolicy{} },
+		actions:  []string{FareChangeCreate, FareChangeUpdate, FareChangeDelete},
+		validate: func(db *gorm.DB, v interface{}) error {
+			return validateDiscountPolicy(db, v.(*models.DiscountPolicy))
+		},
+	},
+	"campaign": {
+		newModel: func() interface{} { return &models.Campaign{} },
+		fields: []string{"campaign_code", "name", "campaign_type", "route_ids", "station_ids", "card_types",
+			"start_time", "end_time", "discount_type", "discount_amount", "discount_rate", "first_ride_only",
+			"budget", "max_rides", "max_rides_per_card", "priority", "status"},
+		actions:  []string{FareChangeCreate, FareChangeUpdate},
+		validate: func(db *gorm.DB, v interface{}) error { return validateCampaign(db, v.(*models.Campaign)) },
+	},
+	"route_max_fare": {
+		newModel: func() interface{} { return &models.Route{} },
+		fields:   []string{"max_fare"},
+		actions:  []string{FareChangeUpdate},
+		validate: func(db *gorm.DB, v interface{}) error {
+			if v.(*models.Route).MaxFare < 0 {
+				return fmt.Errorf("线路最高票价不能为负数")
+			}
+			return nil
+		},
+	},
+}
+
+// fareChangeProtectedFields 不允许通过变更申请修改的字段
+var fareChangeProtectedFields = map[string]bool{"id": true, "created_at": true, "updated_at": true}
+
+type FareChangeService struct {
+	db *gorm.DB
+}
+
+func NewFareChangeService(db *gorm.DB) *FareChangeService {
+	return &FareChangeService{db: db}
+}
+
+// SubmitFareChangeRequest 提交变更申请请求
+type SubmitFareChangeRequest struct {
+	Title       string                  `json:"title" binding:"required"`   // 标题
+	Description string                  `json:"description"`                // 变更说明
+	Changes     []models.FareChangeItem `json:"changes" binding:"required"` // 变更列表
+	EffectiveAt *FlexibleTime           `json:"effective_at"`               // 计划生效时间（为空表示审批后立即生效）
+}
+
+// ReviewFareChangeRequest 审批变更申请请求
+type ReviewFareChangeRequest struct {
+	Note        string        `json:"note"`         // 审批意见
+	EffectiveAt *FlexibleTime `json:"effective_at"` // 调整生效时间（仅审批通过时，为空时使用申请的生效时间）
+}
+
+// Submit 提交变更申请（提交时校验变更对象与字段值，审批前不修改任何配置）
+func (s *FareChangeService) Submit(user *models.User, req SubmitFareChangeRequest) (*models.FareChangeRequest, error) {
+	if len(req.Changes) == 0 {
+		return nil, fmt.Errorf("变更列表为空")
+	}
+	for i, item := range req.Changes {
+		if err := s.checkChangeItem(s.db, item); err != nil {
+			return nil, fmt.Errorf("第%d项变更: %w", i+1, err)
+		}
+	}
+
+	request := models.FareChangeRequest{
+		Title:       req.Title,
+		Description: req.Description,
+		Changes:     req.Changes,
+		Status:      FareChangePending,
+		SubmittedBy: user.ID,
+	}
+	if req.EffectiveAt != nil && !req.EffectiveAt.IsZero() {
+		effectiveAt := req.EffectiveAt.Time
+		request.EffectiveAt = &effectiveAt
+	}
+	if err := s.db.Create(&request).Error; err != nil {
+		return nil, fmt.Errorf("保存变更申请失败: %w", err)
+	}
+	return &request, nil
+}
+
+// EntityChangeRequest 单项票价配置变更请求（由票价、换乘规则、折扣策略的管理接口提交）
+type EntityChangeRequest struct {
+	Title       string                 `json:"title"`        // 标题（为空时按变更内容生成）
+	Description string                 `json:"description"`  // 变更说明
+	EffectiveAt *FlexibleTime          `json:"effective_at"` // 计划生效时间（为空表示审批后立即生效）
+	Values      map[string]interface{} `json:"values"`       // 变更字段（删除时不需要）
+}
+
+// fareChangeEntityNames 变更对象名称（用于生成变更申请标题）
+var fareChangeEntityNames = map[string]string{
+	"fare":            "票价规则",
+	"transfer":        "换乘规则",
+	"discount_policy": "折扣策略",
+	"campaign":        "优惠活动",
+	"route_max_fare":  "线路最高票价",
+}
+
+// fareChangeActionNames 变更操作名称（用于生成变更申请标题）
+var fareChangeActionNames = map[string]string{
+	FareChangeCreate: "新增",
+	FareChangeUpdate: "修改",
+	FareChangeDelete: "删除",
+}
+
+// SubmitEntityChange 提交只包含一项变更的变更申请
+func (s *FareChangeService) SubmitEntityChange(user *models.User, entity, action string, entityID uint, req EntityChangeRequest) (*models.FareChangeRequest, error) {
+	title := req.Title
+	if title == "" {
+		title = fareChangeActionNames[action] + fareChangeEntityNames[entity]
+		if entityID != 0 {
+			title += fmt.Sprintf("#%d", entityID)
+		}
+	}
+	return s.Submit(user, SubmitFareChangeRequest{
+		Title:       title,
+		Description: req.Description,
+		EffectiveAt: req.EffectiveAt,
+		Changes: []models.FareChangeItem{
+			{Entity: entity, Action: action, EntityID: entityID, Values: req.Values},
+		},
+	})
+}
+
+// List 查询变更申请（status为空时返回全部）
+func (s *FareChangeService) List(status string) ([]models.FareChangeRequest, error) {
+	var requests []models.FareChangeRequest
+	query := s.db.Order("id DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&requests).Error
+	return requests, err
+}
+
+// Get 查询变更申请
+func (s *FareChangeService) Get(id uint) (*models.FareChangeRequest, error) {
+	var request models.FareChangeRequest
+	if err := s.db.First(&request, id).Error; err != nil {
+		return nil, fmt.Errorf("变更申请不存在")
+	}
+	return &request, nil
+}
+
+// Approve 审批通过（审批人不能是提交人）；已到生效时间的立即生效，否则等待定时任务生效
+func (s *FareChangeService) Approve(id uint, approver *models.User, req ReviewFareChangeRequest) (*models.FareChangeRequest, error) {
+	now := time.Now()
+	request, err := s.updateLocked(id, func(request *models.FareChangeRequest) error {
+		if request.Status != FareChangePending {
+			return fmt.Errorf("变更申请状态为%s，不能审批", request.Status)
+		}
+		if request.SubmittedBy == approver.ID {
+			return fmt.Errorf("不能审批自己提交的变更申请")
+		}
+		request.ReviewedBy = &approver.ID
+		request.ReviewedAt = &now
+		request.ReviewNote = req.Note
+		if req.EffectiveAt != nil && !req.EffectiveAt.IsZero() {
+			effectiveAt := req.EffectiveAt.Time
+			request.EffectiveAt = &effectiveAt
+		}
+		request.Status = FareChangeScheduled
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if request.EffectiveAt == nil || !request.EffectiveAt.After(now) {
+		s.apply(request)
+	}
+	return request, nil
+}
+
+// Reject 驳回变更申请（审批人不能是提交人）
+func (s *FareChangeService) Reject(id uint, reviewer *models.User, req ReviewFareChangeRequest) (*models.FareChangeRequest, error) {
+	return s.updateLocked(id, func(request *models.FareChangeRequest) error {
+		if request.Status != FareChangePending {
+			return fmt.Errorf("变更申请状态为%s，不能驳回", request.Status)
+		}
+		if request.SubmittedBy == reviewer.ID {
+			return fmt.Errorf("不能审批自己提交的变更申请")
+		}
+		now := time.Now()
+		request.ReviewedBy = &reviewer.ID
+		request.ReviewedAt = &now
+		request.ReviewNote = req.Note
+		request.Status = FareChangeRejected
+		return nil
+	})
+}
+
+// Cancel 撤回变更申请（仅提交人可撤回尚未生效的申请）
+func (s *FareChangeService) Cancel(id uint, user *models.User) (*models.FareChangeRequest, error) {
+	return s.updateLocked(id, func(request *models.FareChangeRequest) error {
+		if request.SubmittedBy != user.ID {
+			return fmt.Errorf("只能撤回自己提交的变更申请")
+		}
+		if request.Status != FareChangePending && request.Status != FareChangeScheduled {
+			return fmt.Errorf("变更申请状态为%s，不能撤回", request.Status)
+		}
+		request.Status = FareChangeCancelled
+		return nil
+	})
+}
+
+// updateLocked 在事务中锁定变更申请，检查状态并保存修改（避免并发审批、驳回、撤回与生效重复处理同一申请）
+func (s *FareChangeService) updateLocked(id uint, update func(request *models.FareChangeRequest) error) (*models.FareChangeRequest, error) {
+	var request models.FareChangeRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&request, id).Error; err != nil {
+			return fmt.Errorf("变更申请不存在")
+		}
+		if err := update(&request); err != nil {
+			return err
+		}
+		if err := tx.Save(&request).Error; err != nil {
+			return fmt.Errorf("保存变更申请失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// ApplyDueChanges 使已审批且到达生效时间的变更申请生效，返回生效的申请数
+func (s *FareChangeService) ApplyDueChanges() (int, error) {
+	var requests []models.FareChangeRequest
+	err := s.db.Where("status = ? AND (effective_at IS NULL OR effective_at <= ?)", FareChangeScheduled, time.Now()).
+		Order("effective_at ASC, id ASC").Find(&requests).Error
+	if err != nil {
+		return 0, fmt.Errorf("查询待生效的变更申请失败: %w", err)
+	}
+	applied := 0
+	for i := range requests {
+		if s.apply(&requests[i]) == nil {
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// StartScheduler 启动变更申请定时生效任务
+func (s *FareChangeService) StartScheduler(intervalMinutes int) {
+	if intervalMinutes <= 0 {
+		intervalMinutes = 1 // 默认每分钟检查一次
+	}
+
+	ticker := time.NewTicker(time.Duration(intervalMinutes) * time.Minute)
+	go func() {
+		for range ticker.C {
+			count, err := s.ApplyDueChanges()
+			if err != nil {
+				fmt.Printf("票价配置变更生效任务失败: %v\n", err)
+			} else if count > 0 {
+				fmt.Printf("生效了 %d 个票价配置变更申请\n", count)
+			}
+		}
+	}()
+}
+
+// errFareChangeHandled 变更申请已被处理（已生效或已撤回）
+var errFareChangeHandled = errors.New("变更申请已处理")
+
+// apply 在一个事务中使变更申请的全部变更生效并写入审计记录；任何一项失败则整体回滚并标记为生效失败
+func (s *FareChangeService) apply(request *models.FareChangeRequest) error {
+	appliedAt := time.Now()
+	approvedBy := uint(0)
+	if request.ReviewedBy != nil {
+		approvedBy = *request.ReviewedBy
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定申请，避免定时任务与审批同时生效同一申请
+		var current models.FareChangeRequest
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, request.ID).Error; err != nil {
+			return err
+		}
+		if current.Status != FareChangeScheduled {
+			return errFareChangeHandled
+		}
+
+		for i, item := range request.Changes {
+			before, after, entityID, err := s.applyChangeItem(tx, item)
+			if err != nil {
+				return fmt.Errorf("第%d项变更: %w", i+1, err)
+			}
+			audit := models.FareAuditLog{
+				ChangeRequestID: request.ID,
+				Entity:          item.Entity,
+				EntityID:        entityID,
+				Action:          item.Action,
+				Before:          before,
+				After:           after,
+				SubmittedBy:     request.SubmittedBy,
+				ApprovedBy:      approvedBy,
+				AppliedAt:       appliedAt,
+			}
+			if err := tx.Create(&audit).Error; err != nil {
+				return fmt.Errorf("写入审计记录失败: %w", err)
+			}
+		}
+		request.Status = FareChangeApplied
+		request.AppliedAt = &appliedAt
+		request.Error = ""
+		return tx.Save(request).Error
+	})
+	if errors.Is(err, errFareChangeHandled) {
+		return err
+	}
+	if err != nil {
+		request.Status = FareChangeFailed
+		request.Error = err.Error()
+		request.AppliedAt = nil
+		s.db.Save(request)
+	}
+	return err
+}
+
+// checkChangeItem 校验单项变更（变更对象、操作、字段以及变更后的值）
+func (s *FareChangeService) checkChangeItem(db *gorm.DB, item models.FareChangeItem) error {
+	entity, ok := fareChangeEntities[item.Entity]
+	if !ok {
+		return fmt.Errorf("不支持的变更对象: %s", item.Entity)
+	}
+	if !containsString(entity.actions, item.Action) {
+		return fmt.Errorf("变更对象%s不支持%s操作", item.Entity, item.Action)
+	}
+	if item.Action == FareChangeDelete {
+		if item.EntityID == 0 {
+			return fmt.Errorf("缺少entity_id")
+		}
+		if err := db.First(entity.newModel(), item.EntityID).Error; err != nil {
+			return fmt.Errorf("%s#%d不存在", item.Entity, item.EntityID)
+		}
+		return nil
+	}
+
+	if len(item.Values) == 0 {
+		return fmt.Errorf("缺少变更字段values")
+	}
+	allowed := entity.fields
+	if len(allowed) == 0 {
+		allowed = jsonFieldNames(entity.newModel())
+	}
+	for field := range item.Values {
+		if fareChangeProtectedFields[field] || !containsString(allowed, field) {
+			return fmt.Errorf("不允许修改字段%s", field)
+		}
+	}
+
+	model := entity.newModel()
+	if item.Action == FareChangeUpdate {
+		if item.EntityID == 0 {
+			return fmt.Errorf("缺少entity_id")
+		}
+		if err := db.First(model, item.EntityID).Error; err != nil {
+			return fmt.Errorf("%s#%d不存在", item.Entity, item.EntityID)
+		}
+	}
+	if err := mergeValues(model, item.Values); err != nil {
+		return err
+	}
+	return entity.validate(db, model)
+}
+
+// applyChangeItem 使单项变更生效，返回变更前后的值与变更对象ID
+func (s *FareChangeService) applyChangeItem(tx *gorm.DB, item models.FareChangeItem) (models.JSONB, models.JSONB, uint, error) {
+	// 生效时按当时的数据重新校验（提交后数据可能已被其他申请修改）
+	if err := s.checkChangeItem(tx, item); err != nil {
+		return nil, nil, 0, err
+	}
+	entity := fareChangeEntities[item.Entity]
+	model := entity.newModel()
+
+	switch item.Action {
+	case FareChangeCreate:
+		if err := mergeValues(model, item.Values); err != nil {
+			return nil, nil, 0, err
+		}
+		if err := tx.Create(model).Error; err != nil {
+			return nil, nil, 0, fmt.Errorf("新增%s失败: %w", item.Entity, err)
+		}
+		return nil, auditValues(model), modelID(model), nil
+	case FareChangeUpdate:
+		if err := tx.First(model, item.EntityID).Error; err != nil {
+			return nil, nil, 0, fmt.Errorf("%s#%d不存在", item.Entity, item.EntityID)
+		}
+		before := auditValues(model)
+		if err := mergeValues(model, item.Values); err != nil {
+			return nil, nil, 0, err
+		}
+		if err := saveChangedFields(tx, entity, model, item.Values); err != nil {
+			return nil, nil, 0, fmt.Errorf("修改%s#%d失败: %w", item.Entity, item.EntityID, err)
+		}
+		return before, auditValues(model), item.EntityID, nil
+	case FareChangeDelete:
+		if err := tx.First(model, item.EntityID).Error; err != nil {
+			return nil, nil, 0, fmt.Errorf("%s#%d不存在", item.Entity, item.EntityID)
+		}
+		before := auditValues(model)
+		if err := tx.Delete(model).Error; err != nil {
+			return nil, nil, 0, fmt.Errorf("删除%s#%d失败: %w", item.Entity, item.EntityID, err)
+		}
+		return before, nil, item.EntityID, nil
+	}
+	return nil, nil, 0, errors.New("不支持的操作: " + item.Action)
+}
+
+// saveChangedFields 保存修改；只允许修改部分字段的对象（如线路最高票价、活动）只更新变更的字段，
+// 不覆盖其他字段（线路的其他属性、活动计费时累加的已预留用量）
+func saveChangedFields(tx *gorm.DB, entity fareChangeEntity, model interface{}, values map[string]interface{}) error {
+	if len(entity.fields) == 0 {
+		return tx.Save(model).Error
+	}
+	columns := []string{"updated_at"}
+	for field := range values {
+		columns = append(columns, field)
+	}
+	return tx.Model(model).Select(columns).Updates(model).Error
+}
+
+// FareAuditQuery 审计记录查询条件
+type FareAuditQuery struct {
+	Entity          string
+	EntityID        uint
+	ChangeRequestID uint
+}
+
+// ListAuditLogs 查询审计记录（按生效时间倒序）
+func (s *FareChangeService) ListAuditLogs(query FareAuditQuery) ([]models.FareAuditLog, error) {
+	db := s.db.Order("applied_at DESC, id DESC")
+	if query.Entity != "" {
+		db = db.Where("entity = ?", query.Entity)
+	}
+	if query.EntityID != 0 {
+		db = db.Where("entity_id = ?", query.EntityID)
+	}
+	if query.ChangeRequestID != 0 {
+		db = db.Where("change_request_id = ?", query.ChangeRequestID)
+	}
+	var logs []models.FareAuditLog
+	err := db.Find(&logs).Error
+	return logs, err
+}
+
+// mergeValues 将字段值（JSON字段名）合并到模型
+func mergeValues(model interface{}, values map[string]interface{}) error {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("字段值格式错误: %w", err)
+	}
+	if err := json.Unmarshal(data, model); err != nil {
+		return fmt.Errorf("字段值格式错误: %w", err)
+	}
+	return nil
+}
+
+// auditValues 模型的字段值（用于审计记录）
+func auditValues(model interface{}) models.JSONB {
+	data, err := json.Marshal(model)
+	if err != nil {
+		return nil
+	}
+	var values models.JSONB
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil
+	}
+	return values
+}
+
+// modelID 模型的主键
+func modelID(model interface{}) uint {
+	field := reflect.Indirect(reflect.ValueOf(model)).FieldByName("ID")
+	if !field.IsValid() {
+		return 0
+	}
+	return uint(field.Uint())
+}
+
+// jsonFieldNames 模型的JSON字段名
+func jsonFieldNames(model interface{}) []string {
+	var names []string
+	t := reflect.Indirect(reflect.ValueOf(model)).Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// containsString 列表是否包含指定项
+func containsString(list []string, item string) bool {
+	for _, candidate := range list {
+		if candidate == item {
+			return true
+		}
+	}
+	return false
+}
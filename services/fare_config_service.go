@@ -0,0 +1,92 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// FareConfigService 票价规则、换乘规则与折扣策略查询（修改通过FareChangeService提交变更申请）
+type FareConfigService struct {
+	db *gorm.DB
+}
+
+func NewFareConfigService(db *gorm.DB) *FareConfigService {
+	return &FareConfigService{db: db}
+}
+
+// FareQuery 票价规则查询条件（字段为空或0表示不筛选）
+type FareQuery struct {
+	RouteID   uint   `form:"route_id"`   // 线路ID
+	StationID uint   `form:"station_id"` // 起始或结束站点ID
+	FareType  string `form:"fare_type"`  // 计价类型
+	Status    string `form:"status"`     // 状态
+}
+
+// TransferQuery 换乘规则查询条件（字段为空或0表示不筛选）
+type TransferQuery struct {
+	RouteID   uint   `form:"route_id"`   // 起始或换乘后线路ID
+	StationID uint   `form:"station_id"` // 起始或换乘后站点ID
+	Status    string `form:"status"`     // 状态
+}
+
+// DiscountPolicyQuery 折扣策略查询条件（字段为空表示不筛选）
+type DiscountPolicyQuery struct {
+	PolicyType string `form:"policy_type"` // 策略类型
+	CardType   string `form:"card_type"`   // 适用的卡类型（包括适用所有卡类型的策略）
+	Status     string `form:"status"`      // 状态
+}
+
+// ListFares 查询票价规则
+func (s *FareConfigService) ListFares(query FareQuery) ([]models.Fare, error) {
+	db := s.db.Order("route_id ASC, id ASC")
+	if query.RouteID != 0 {
+		db = db.Where("route_id = ?", query.RouteID)
+	}
+	if query.StationID != 0 {
+		db = db.Where("start_station = ? OR end_station = ?", query.StationID, query.StationID)
+	}
+	if query.FareType != "" {
+		db = db.Where("fare_type = ?", query.FareType)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	var fares []models.Fare
+	err := db.Find(&fares).Error
+	return fares, err
+}
+
+// ListTransfers 查询换乘规则
+func (s *FareConfigService) ListTransfers(query TransferQuery) ([]models.Transfer, error) {
+	db := s.db.Order("id ASC")
+	if query.RouteID != 0 {
+		db = db.Where("from_route_id = ? OR to_route_id = ?", query.RouteID, query.RouteID)
+	}
+	if query.StationID != 0 {
+		db = db.Where("from_station_id = ? OR to_station_id = ?", query.StationID, query.StationID)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	var transfers []models.Transfer
+	err := db.Find(&transfers).Error
+	return transfers, err
+}
+
+// ListDiscountPolicies 查询折扣策略
+func (s *FareConfigService) ListDiscountPolicies(query DiscountPolicyQuery) ([]models.DiscountPolicy, error) {
+	db := s.db.Order("policy_type ASC, threshold ASC, id ASC")
+	if query.PolicyType != "" {
+		db = db.Where("policy_type = ?", query.PolicyType)
+	}
+	if query.CardType != "" {
+		db = db.Where("card_type_filter = ? OR card_type_filter = ''", query.CardType)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	var policies []models.DiscountPolicy
+	err := db.Find(&policies).Error
+	return policies, err
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// validateRate 校验比例在0-1之间
+func validateRate(name string, rate float64) error {
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("%s必须在0-1之间", name)
+	}
+	return nil
+}
+
+// validateAmount 校验金额不为负数
+func validateAmount(name string, amount models.Money) error {
+	if amount < 0 {
+		return fmt.Errorf("%s不能为负数", name)
+	}
+	return nil
+}
+
+// validateStatus 校验状态取值（为空时按active保存）
+func validateStatus(status string) error {
+	if status != "" && status != "active" && status != "inactive" {
+		return fmt.Errorf("状态错误: %s（应为active或inactive）", status)
+	}
+	return nil
+}
+
+// isActiveStatus 状态是否为启用（为空时按数据库默认值active）
+func isActiveStatus(status string) bool {
+	return status == "" || status == "active"
+}
+
+// validateReference 校验引用的记录存在（id为0表示不限定，不校验）
+func validateReference(db *gorm.DB, name string, model interface{}, id uint) error {
+	if id == 0 {
+		return nil
+	}
+	var count int64
+	if err := db.Model(model).Where("id = ?", id).Count(&count).Error; err != nil {
+		return fmt.Errorf("查询%s失败: %w", name, err)
+	}
+	if count == 0 {
+		return fmt.Errorf("%s#%d不存在", name, id)
+	}
+	return nil
+}
+
+// validateNoOverlap 校验不存在与当前规则匹配条件相同的其他启用规则
+func validateNoOverlap(db *gorm.DB, table string, id uint, conditions map[string]interface{}) error {
+	var existing []uint
+	query := db.Table(table).Where("deleted_at IS NULL AND status = 'active' AND id <> ?", id)
+	for column, value := range conditions {
+		query = query.Where(column+" = ?", value)
+	}
+	if err := query.Order("id ASC").Pluck("id", &existing).Error; err != nil {
+		return fmt.Errorf("查询重复规则失败: %w", err)
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("已存在匹配条件相同的启用规则%s", fareRuleRef(table, existing[0]))
+	}
+	return nil
+}
+
+// validateFare 校验票价规则
+func validateFare(db *gorm.DB, fare *models.Fare) error {
+	for name, amount := range map[string]models.Money{"基础票价": fare.BasePrice, "续程价": fare.ExtraPrice, "封顶票价": fare.CapPrice} {
+		if err := validateAmount(name, amount); err != nil {
+			return err
+		}
+	}
+	if fare.SegmentCount < 0 || fare.BaseDistanceKm < 0 || fare.BandKm < 0 {
+		return fmt.Errorf("区段数与里程不能为负数")
+	}
+	if fare.FareType != "" && !containsString([]string{"uniform", "segment", "distance", "zone_count"}, fare.FareType) {
+		return fmt.Errorf("计价类型错误: %s（应为uniform、segment、distance或zone_count）", fare.FareType)
+	}
+	if err := validateStatus(fare.Status); err != nil {
+		return err
+	}
+	if err := validateReference(db, "线路", &models.Route{}, fare.RouteID); err != nil {
+		return err
+	}
+	if err := validateReference(db, "起始站点", &models.Station{}, fare.StartStation); err != nil {
+		return err
+	}
+	if err := validateReference(db, "结束站点", &models.Station{}, fare.EndStation); err != nil {
+		return err
+	}
+	if !isActiveStatus(fare.Status) {
+		return nil
+	}
+	return validateNoOverlap(db, "fares", fare.ID, map[string]interface{}{
+		"route_id":      fare.RouteID,
+		"start_station": fare.StartStation,
+		"end_station":   fare.EndStation,
+		"fare_type":     defaultString(fare.FareType, "uniform"),
+	})
+}
+
+// validateTransfer 校验换乘规则
+func validateTransfer(db *gorm.DB, transfer *models.Transfer) error {
+	if err := validateAmount("优惠金额", transfer.DiscountAmount); err != nil {
+		return err
+	}
+	if err := validateRate("优惠比例", transfer.DiscountRate); err != nil {
+		return err
+	}
+	if transfer.TimeWindow < 0 || transfer.JourneyWindow < 0 || transfer.MaxLegs < 0 || transfer.MaxDiscountedTransfers < 0 {
+		return fmt.Errorf("时间窗口与次数不能为负数")
+	}
+	if err := validateStatus(transfer.Status); err != nil {
+		return err
+	}
+	references := []struct {
+		name  string
+		model interface{}
+		id    uint
+	}{
+		{"起始线路", &models.Route{}, transfer.FromRouteID},
+		{"起始线路组", &models.RouteGroup{}, transfer.FromRouteGroupID},
+		{"起始站点", &models.Station{}, transfer.FromStationID},
+		{"换乘后线路", &models.Route{}, transfer.ToRouteID},
+		{"换乘后线路组", &models.RouteGroup{}, transfer.ToRouteGroupID},
+		{"换乘后站点", &models.Station{}, transfer.ToStationID},
+	}
+	for _, ref := range references {
+		if err := validateReference(db, ref.name, ref.model, ref.id); err != nil {
+			return err
+		}
+	}
+	if !isActiveStatus(transfer.Status) {
+		return nil
+	}
+	return validateNoOverlap(db, "transfers", transfer.ID, map[string]interface{}{
+		"from_route_id":       transfer.FromRouteID,
+		"from_route_group_id": transfer.FromRouteGroupID,
+		"from_station_id":     transfer.FromStationID,
+		"to_route_id":         transfer.ToRouteID,
+		"to_route_group_id":   transfer.ToRouteGroupID,
+		"to_station_id":       transfer.ToStationID,
+	})
+}
+
+// validateDiscountPolicy 校验折扣策略（同一策略类型、卡类型可按不同阈值配置多档）
+func validateDiscountPolicy(db *gorm.DB, policy *models.DiscountPolicy) error {
+	if policy.PolicyName == "" || policy.PolicyType == "" {
+		return fmt.Errorf("策略名称和策略类型不能为空")
+	}
+	if err := validateAmount("阈值", policy.Threshold); err != nil {
+		return err
+	}
+	if err := validateAmount("固定优惠金额", policy.DiscountAmount); err != nil {
+		return err
+	}
+	if err := validateRate("折扣比例", policy.DiscountRate); err != nil {
+		return err
+	}
+	if err := validateStatus(policy.Status); err != nil {
+		return err
+	}
+	if !isActiveStatus(policy.Status) {
+		return nil
+	}
+	return validateNoOverlap(db, "discount_policies", policy.ID, map[string]interface{}{
+		"policy_type":      policy.PolicyType,
+		"card_type_filter": policy.CardTypeFilter,
+		"threshold":        policy.Threshold,
+	})
+}
+
+// validateCampaign 校验优惠活动（活动编号不能重复）
+func validateCampaign(db *gorm.DB, campaign *models.Campaign) error {
+	if campaign.CampaignCode == "" || campaign.Name == "" {
+		return fmt.Errorf("活动编号和活动名称不能为空")
+	}
+	if campaign.CampaignType != "" && campaign.CampaignType != "service" && campaign.CampaignType != "promotion" {
+		return fmt.Errorf("活动类型错误: %s（应为service或promotion）", campaign.CampaignType)
+	}
+	if campaign.StartTime.IsZero() || !campaign.EndTime.After(campaign.StartTime) {
+		return fmt.Errorf("结束时间必须晚于开始时间")
+	}
+	switch campaign.DiscountType {
+	case CampaignDiscountFree:
+	case CampaignDiscountAmount:
+		if campaign.DiscountAmount <= 0 {
+			return fmt.Errorf("固定优惠金额必须大于0")
+		}
+	case CampaignDiscountRate:
+		if campaign.DiscountRate <= 0 || campaign.DiscountRate > 1 {
+			return fmt.Errorf("优惠比例必须在0-1之间")
+		}
+	case CampaignDiscountFixedFare:
+		if err := validateAmount("固定票价", campaign.DiscountAmount); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("优惠方式错误: %s（应为free、amount、rate或fixed_fare）", campaign.DiscountType)
+	}
+	if campaign.Budget < 0 || campaign.MaxRides < 0 || campaign.MaxRidesPerCard < 0 {
+		return fmt.Errorf("预算和次数上限不能为负数")
+	}
+	for _, id := range splitList(campaign.RouteIDs) {
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			return fmt.Errorf("线路ID格式错误: %s", id)
+		}
+	}
+	for _, id := range splitList(campaign.StationIDs) {
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			return fmt.Errorf("站点ID格式错误: %s", id)
+		}
+	}
+	if err := validateStatus(campaign.Status); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := db.Model(&models.Campaign{}).Where("campaign_code = ? AND id <> ?", campaign.CampaignCode, campaign.ID).Count(&count).Error; err != nil {
+		return fmt.Errorf("查询活动失败: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("活动编号已存在: %s", campaign.CampaignCode)
+	}
+	return nil
+}
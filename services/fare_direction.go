@@ -0,0 +1,329 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"fmt"
+)
+
+// 线路方向模式（routes.direction_mode）
+const (
+	DirectionModeSingle = "single" // 单向
+	DirectionModeBoth   = "both"   // 双向
+	DirectionModeLoop   = "loop"   // 环线
+)
+
+// 环线计价策略（routes.loop_fare_policy）
+const (
+	LoopFareShortest = "shortest" // 按较短一侧计价
+	LoopFareTravel   = "travel"   // 按实际行驶方向计价
+)
+
+// 行程方向的判定来源
+const (
+	directionSourceGateway  = "gateway"   // 网关上报的行驶方向
+	directionSourceTapOrder = "tap_order" // 按刷卡先后（上车站在下车站之前的站序方向）
+	directionSourceSequence = "sequence"  // 无匹配方向时按站序差计算
+)
+
+// routePattern 线路某一方向的站序
+type routePattern struct {
+	direction    string
+	stations     []models.RouteStation // 按站序排列（闭合环线去掉与首站重复的末站）
+	loop         bool                  // 是否为环线
+	loopLengthKm *float64              // 环线全长（闭合环线末站的累计距离）
+}
+
+// indexOf 站点在站序中的位置（不存在返回-1）
+func (p *routePattern) indexOf(stationID uint) int {
+	for i, rs := range p.stations {
+		if rs.StationID == stationID {
+			return i
+		}
+	}
+	return -1
+}
+
+// forwardStops 沿站序方向从from到to经过的站数（环线可经过起点）
+func (p *routePattern) forwardStops(from, to int) int {
+	if p.loop {
+		n := len(p.stations)
+		return (to - from + n) % n
+	}
+	return to - from
+}
+
+// forwardPath 沿站序方向从from到to经过的站点（含上下车站）
+func (p *routePattern) forwardPath(from, to int) []models.RouteStation {
+	stops := p.forwardStops(from, to)
+	path := make([]models.RouteStation, 0, stops+1)
+	for i := 0; i <= stops; i++ {
+		path = append(path, p.stations[(from+i)%len(p.stations)])
+	}
+	return path
+}
+
+// edgeMeters 站序中第i站到下一站的距离（米）
+func (p *routePattern) edgeMeters(i int) (int64, bool) {
+	current := p.stations[i]
+	if current.DistanceKm == nil {
+		return 0, false
+	}
+	if i+1 < len(p.stations) {
+		next := p.stations[i+1]
+		if next.DistanceKm == nil {
+			return 0, false
+		}
+		return kmToMeters(*next.DistanceKm) - kmToMeters(*current.DistanceKm), true
+	}
+	// 环线末站回到首站
+	if !p.loop || p.loopLengthKm == nil || p.stations[0].DistanceKm == nil {
+		return 0, false
+	}
+	return kmToMeters(*p.loopLengthKm) - kmToMeters(*current.DistanceKm) + kmToMeters(*p.stations[0].DistanceKm), true
+}
+
+// forwardMeters 沿站序方向从from到to的距离（米）
+func (p *routePattern) forwardMeters(from, to int) (int64, bool) {
+	var total int64
+	for i := 0; i < p.forwardStops(from, to); i++ {
+		meters, ok := p.edgeMeters((from + i) % len(p.stations))
+		if !ok {
+			return 0, false
+		}
+		total += meters
+	}
+	return total, true
+}
+
+// validateDistances 校验站序的累计距离单调不减（环线全长不小于末站累计距离）
+func (p *routePattern) validateDistances() error {
+	if err := validateDistanceSequence(p.stations); err != nil {
+		return err
+	}
+	n := len(p.stations)
+	if p.loopLengthKm != nil && n > 0 && p.stations[n-1].DistanceKm != nil && *p.loopLengthKm < *p.stations[n-1].DistanceKm {
+		return fmt.Errorf("方向%s环线全长%.3f公里小于第%d站的%.3f公里",
+			p.direction, *p.loopLengthKm, p.stations[n-1].Sequence, *p.stations[n-1].DistanceKm)
+	}
+	return nil
+}
+
+// tripPath 行程在线路站序上的路径
+type tripPath struct {
+	pattern    *routePattern
+	from, to   int    // 上下车站在站序中的位置
+	forward    bool   // 是否沿站序方向行驶
+	source     string // 方向判定来源
+	loopPolicy string // 环线计价策略（仅环线）
+}
+
+// Direction 行驶方向（线路站点的direction）
+func (t *tripPath) Direction() string {
+	return t.pattern.direction
+}
+
+// Stops 行程经过的站数
+func (t *tripPath) Stops() int {
+	if t.forward {
+		return t.pattern.forwardStops(t.from, t.to)
+	}
+	return t.pattern.forwardStops(t.to, t.from)
+}
+
+// Stations 按行驶顺序经过的站点（含上下车站）
+func (t *tripPath) Stations() []models.RouteStation {
+	if t.forward {
+		return t.pattern.forwardPath(t.from, t.to)
+	}
+	path := t.pattern.forwardPath(t.to, t.from)
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// Board 上车站点
+func (t *tripPath) Board() models.RouteStation {
+	return t.pattern.stations[t.from]
+}
+
+// Alight 下车站点
+func (t *tripPath) Alight() models.RouteStation {
+	return t.pattern.stations[t.to]
+}
+
+// Meters 行程距离（米），站点缺少距离数据时返回false
+func (t *tripPath) Meters() (int64, bool) {
+	if t.forward {
+		return t.pattern.forwardMeters(t.from, t.to)
+	}
+	return t.pattern.forwardMeters(t.to, t.from)
+}
+
+// wrapped 是否经过环线起点
+func (t *tripPath) wrapped() bool {
+	if !t.pattern.loop {
+		return false
+	}
+	if t.forward {
+		return t.to < t.from
+	}
+	return t.from < t.to
+}
+
+// details 计费明细中的方向信息
+func (t *tripPath) details() map[string]interface{} {
+	details := map[string]interface{}{
+		"direction":        t.pattern.direction,
+		"direction_source": t.source,
+	}
+	if t.pattern.loop {
+		details["loop_wrapped"] = t.wrapped()
+		details["loop_fare_policy"] = t.loopPolicy
+	}
+	return details
+}
+
+// loadRoutePatterns 加载线路各方向的站序
+func (s *FareService) loadRoutePatterns(route *models.Route) []*routePattern {
+	var routeStations []models.RouteStation
+	s.db.Where("route_id = ?", route.ID).Order("direction ASC, sequence ASC").Find(&routeStations)
+	return buildRoutePatterns(route, routeStations)
+}
+
+// buildRoutePatterns 按方向拆分线路站点（站点需按方向、站序排列）
+func buildRoutePatterns(route *models.Route, routeStations []models.RouteStation) []*routePattern {
+	var patterns []*routePattern
+	byDirection := make(map[string]*routePattern)
+	for _, rs := range routeStations {
+		pattern, ok := byDirection[rs.Direction]
+		if !ok {
+			pattern = &routePattern{direction: rs.Direction, loop: route.DirectionMode == DirectionModeLoop}
+			byDirection[rs.Direction] = pattern
+			patterns = append(patterns, pattern)
+		}
+		pattern.stations = append(pattern.stations, rs)
+	}
+
+	// 闭合环线（末站与首站相同）：末站累计距离即环线全长
+	for _, pattern := range patterns {
+		n := len(pattern.stations)
+		if pattern.loop && n > 1 && pattern.stations[0].StationID == pattern.stations[n-1].StationID {
+			pattern.loopLengthKm = pattern.stations[n-1].DistanceKm
+			pattern.stations = pattern.stations[:n-1]
+		}
+	}
+	return patterns
+}
+
+// resolveTripPath 确定行程的行驶方向与经过的站点
+// 依次按网关上报的方向、刷卡先后（上车站在下车站之前的站序方向）、站序差判断；
+// 环线按线路的环线计价策略选择较短一侧或实际行驶方向
+func (s *FareService) resolveTripPath(route *models.Route, startStationID, endStationID uint, direction string) (*tripPath, bool) {
+	return selectTripPath(route, s.loadRoutePatterns(route), startStationID, endStationID, direction)
+}
+
+// selectTripPath 在线路各方向的站序中选择行程路径
+func selectTripPath(route *models.Route, patterns []*routePattern, startStationID, endStationID uint, direction string) (*tripPath, bool) {
+	var candidates []*tripPath
+	for _, pattern := range patterns {
+		from, to := pattern.indexOf(startStationID), pattern.indexOf(endStationID)
+		if from >= 0 && to >= 0 {
+			candidates = append(candidates, &tripPath{pattern: pattern, from: from, to: to, forward: true})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, false
+	}
+
+	if route.DirectionMode == DirectionModeLoop {
+		return resolveLoopPath(route, candidates, direction), true
+	}
+
+	// 网关上报的方向（上下车站点与该方向站序一致时采用）
+	if direction != "" {
+		for _, candidate := range candidates {
+			if candidate.pattern.direction == direction && candidate.from <= candidate.to {
+				candidate.source = directionSourceGateway
+				return candidate, true
+			}
+		}
+	}
+
+	// 刷卡先后：上车站在下车站之前的方向
+	for _, candidate := range candidates {
+		if candidate.from <= candidate.to {
+			candidate.source = directionSourceTapOrder
+			return candidate, true
+		}
+	}
+
+	// 各方向站序均与刷卡先后相反（如仅维护了单一方向站序的双向线路），按站序差计算
+	candidate := candidates[0]
+	candidate.forward = false
+	candidate.source = directionSourceSequence
+	return candidate, true
+}
+
+// resolveLoopPath 环线行程：按实际行驶方向计价时沿网关上报方向（缺省为站序方向）行驶；
+// 按较短一侧计价时取两侧中经过站数较少的一侧
+func resolveLoopPath(route *models.Route, candidates []*tripPath, direction string) *tripPath {
+	path := candidates[0]
+	path.source = directionSourceTapOrder
+	for _, candidate := range candidates {
+		if direction != "" && candidate.pattern.direction == direction {
+			path = candidate
+			path.source = directionSourceGateway
+			break
+		}
+	}
+
+	if route.LoopFarePolicy == LoopFareTravel {
+		path.loopPolicy = LoopFareTravel
+		return path
+	}
+
+	path.loopPolicy = LoopFareShortest
+	reverse := &tripPath{pattern: path.pattern, from: path.from, to: path.to, forward: false, source: path.source, loopPolicy: LoopFareShortest}
+	if reverse.Stops() < path.Stops() {
+		return reverse
+	}
+	return path
+}
+
+// resolveFurthestPath 单次刷卡（无下车站点）时，按上车站到行驶方向上最远站点的路径
+// 环线按实际行驶方向计价时为绕行一周，按较短一侧计价时为环线对侧站点
+func (s *FareService) resolveFurthestPath(route *models.Route, startStationID uint, direction string) (*tripPath, bool) {
+	var board *routePattern
+	from := -1
+	for _, pattern := range s.loadRoutePatterns(route) {
+		index := pattern.indexOf(startStationID)
+		if index < 0 {
+			continue
+		}
+		if board == nil || (direction != "" && pattern.direction == direction) {
+			board, from = pattern, index
+		}
+	}
+	if board == nil {
+		return nil, false
+	}
+
+	source := directionSourceSequence
+	if direction != "" && board.direction == direction {
+		source = directionSourceGateway
+	}
+	n := len(board.stations)
+	to := n - 1
+	loopPolicy := ""
+	if board.loop {
+		if route.LoopFarePolicy == LoopFareTravel {
+			to = (from + n - 1) % n
+			loopPolicy = LoopFareTravel
+		} else {
+			to = (from + n/2) % n
+			loopPolicy = LoopFareShortest
+		}
+	}
+	return &tripPath{pattern: board, from: from, to: to, forward: true, source: source, loopPolicy: loopPolicy}, true
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"reflect"
+	"testing"
+)
+
+// testRouteStations 按方向、站序生成线路站点，站点ID依次为stationIDs，累计距离为distances（公里）
+func testRouteStations(direction string, stationIDs []uint, distances []float64) []models.RouteStation {
+	stations := make([]models.RouteStation, 0, len(stationIDs))
+	for i, id := range stationIDs {
+		rs := models.RouteStation{StationID: id, Sequence: i + 1, Direction: direction}
+		if distances != nil {
+			km := distances[i]
+			rs.DistanceKm = &km
+		}
+		stations = append(stations, rs)
+	}
+	return stations
+}
+
+func stationIDsOf(path []models.RouteStation) []uint {
+	ids := make([]uint, 0, len(path))
+	for _, rs := range path {
+		ids = append(ids, rs.StationID)
+	}
+	return ids
+}
+
+func TestSelectTripPath(t *testing.T) {
+	both := &models.Route{DirectionMode: DirectionModeBoth}
+	bothStations := append(
+		testRouteStations("down", []uint{5, 4, 3, 2, 1}, []float64{0, 1, 2, 3, 4}),
+		testRouteStations("up", []uint{1, 2, 3, 4, 5}, []float64{0, 1, 2, 3, 4})...,
+	)
+	upOnly := testRouteStations("up", []uint{1, 2, 3, 4, 5}, []float64{0, 1, 2, 3, 4})
+
+	// 闭合环线：末站回到首站，末站累计距离即环线全长
+	loopStations := testRouteStations("cw", []uint{1, 2, 3, 4, 5, 6, 1}, []float64{0, 1, 2, 3, 4, 5, 6})
+	loopShortest := &models.Route{DirectionMode: DirectionModeLoop, LoopFarePolicy: LoopFareShortest}
+	loopTravel := &models.Route{DirectionMode: DirectionModeLoop, LoopFarePolicy: LoopFareTravel}
+
+	tests := []struct {
+		name          string
+		route         *models.Route
+		stations      []models.RouteStation
+		start, end    uint
+		direction     string
+		wantOK        bool
+		wantDirection string
+		wantSource    string
+		wantPolicy    string
+		wantStations  []uint
+		wantMeters    int64
+		wantWrapped   bool
+	}{
+		{
+			name: "按刷卡先后选择方向", route: both, stations: bothStations, start: 2, end: 4,
+			wantOK: true, wantDirection: "up", wantSource: directionSourceTapOrder,
+			wantStations: []uint{2, 3, 4}, wantMeters: 2000,
+		},
+		{
+			name: "反向行程选择下行", route: both, stations: bothStations, start: 4, end: 2,
+			wantOK: true, wantDirection: "down", wantSource: directionSourceTapOrder,
+			wantStations: []uint{4, 3, 2}, wantMeters: 2000,
+		},
+		{
+			name: "采用网关上报的方向", route: both, stations: bothStations, start: 2, end: 4, direction: "up",
+			wantOK: true, wantDirection: "up", wantSource: directionSourceGateway,
+			wantStations: []uint{2, 3, 4}, wantMeters: 2000,
+		},
+		{
+			name: "网关方向与刷卡先后矛盾时按刷卡先后", route: both, stations: bothStations, start: 2, end: 4, direction: "down",
+			wantOK: true, wantDirection: "up", wantSource: directionSourceTapOrder,
+			wantStations: []uint{2, 3, 4}, wantMeters: 2000,
+		},
+		{
+			name: "仅维护单一方向时按站序差计算", route: both, stations: upOnly, start: 4, end: 2,
+			wantOK: true, wantDirection: "up", wantSource: directionSourceSequence,
+			wantStations: []uint{4, 3, 2}, wantMeters: 2000,
+		},
+		{
+			name: "站点不在线路上", route: both, stations: bothStations, start: 2, end: 9,
+			wantOK: false,
+		},
+		{
+			name: "环线按较短一侧计价经过起点", route: loopShortest, stations: loopStations, start: 2, end: 6,
+			wantOK: true, wantDirection: "cw", wantSource: directionSourceTapOrder, wantPolicy: LoopFareShortest,
+			wantStations: []uint{2, 1, 6}, wantMeters: 2000, wantWrapped: true,
+		},
+		{
+			name: "环线按较短一侧计价沿站序方向", route: loopShortest, stations: loopStations, start: 2, end: 4,
+			wantOK: true, wantDirection: "cw", wantSource: directionSourceTapOrder, wantPolicy: LoopFareShortest,
+			wantStations: []uint{2, 3, 4}, wantMeters: 2000,
+		},
+		{
+			name: "环线按实际行驶方向计价", route: loopTravel, stations: loopStations, start: 2, end: 6,
+			wantOK: true, wantDirection: "cw", wantSource: directionSourceTapOrder, wantPolicy: LoopFareTravel,
+			wantStations: []uint{2, 3, 4, 5, 6}, wantMeters: 4000,
+		},
+		{
+			name: "环线按实际行驶方向计价经过起点", route: loopTravel, stations: loopStations, start: 6, end: 2, direction: "cw",
+			wantOK: true, wantDirection: "cw", wantSource: directionSourceGateway, wantPolicy: LoopFareTravel,
+			wantStations: []uint{6, 1, 2}, wantMeters: 2000, wantWrapped: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, ok := selectTripPath(tt.route, buildRoutePatterns(tt.route, tt.stations), tt.start, tt.end, tt.direction)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v，应为 %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if path.Direction() != tt.wantDirection {
+				t.Errorf("方向 = %s，应为 %s", path.Direction(), tt.wantDirection)
+			}
+			if path.source != tt.wantSource {
+				t.Errorf("方向判定来源 = %s，应为 %s", path.source, tt.wantSource)
+			}
+			if path.loopPolicy != tt.wantPolicy {
+				t.Errorf("环线计价策略 = %q，应为 %q", path.loopPolicy, tt.wantPolicy)
+			}
+			if got := stationIDsOf(path.Stations()); !reflect.DeepEqual(got, tt.wantStations) {
+				t.Errorf("经过站点 = %v，应为 %v", got, tt.wantStations)
+			}
+			if path.Stops() != len(tt.wantStations)-1 {
+				t.Errorf("经过站数 = %d，应为 %d", path.Stops(), len(tt.wantStations)-1)
+			}
+			if meters, ok := path.Meters(); !ok || meters != tt.wantMeters {
+				t.Errorf("距离 = %d（%v），应为 %d", meters, ok, tt.wantMeters)
+			}
+			if path.wrapped() != tt.wantWrapped {
+				t.Errorf("经过环线起点 = %v，应为 %v", path.wrapped(), tt.wantWrapped)
+			}
+		})
+	}
+}
+
+func TestBuildRoutePatternsClosedLoop(t *testing.T) {
+	route := &models.Route{DirectionMode: DirectionModeLoop}
+	patterns := buildRoutePatterns(route, testRouteStations("cw", []uint{1, 2, 3, 1}, []float64{0, 1, 2, 3.5}))
+	if len(patterns) != 1 {
+		t.Fatalf("方向数 = %d，应为 1", len(patterns))
+	}
+	pattern := patterns[0]
+	if got := stationIDsOf(pattern.stations); !reflect.DeepEqual(got, []uint{1, 2, 3}) {
+		t.Errorf("站序 = %v，应去掉与首站重复的末站", got)
+	}
+	if pattern.loopLengthKm == nil || *pattern.loopLengthKm != 3.5 {
+		t.Errorf("环线全长 = %v，应为 3.5", pattern.loopLengthKm)
+	}
+	if meters, ok := pattern.forwardMeters(2, 0); !ok || meters != 1500 {
+		t.Errorf("末站回到首站距离 = %d（%v），应为 1500", meters, ok)
+	}
+}
+
+func TestCountZonesCrossed(t *testing.T) {
+	zone := func(id string) *string { return &id }
+	tests := []struct {
+		name  string
+		zones []*string
+		want  int
+	}{
+		{name: "无站点", zones: nil, want: 0},
+		{name: "站点均未设置分区", zones: []*string{nil, nil}, want: 0},
+		{name: "同一分区", zones: []*string{zone("A"), zone("A")}, want: 1},
+		{name: "依次经过分区", zones: []*string{zone("A"), zone("A"), zone("B"), zone("C"), zone("C")}, want: 3},
+		{name: "返回已经过的分区重新计数", zones: []*string{zone("A"), zone("B"), zone("A")}, want: 3},
+		{name: "跳过未设置分区的站点", zones: []*string{zone("A"), nil, zone("A"), nil, zone("B")}, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := make([]models.RouteStation, 0, len(tt.zones))
+			for _, z := range tt.zones {
+				path = append(path, models.RouteStation{ZoneID: z})
+			}
+			if got := countZonesCrossed(path); got != tt.want {
+				t.Errorf("countZonesCrossed = %d，应为 %d", got, tt.want)
+			}
+		})
+	}
+}
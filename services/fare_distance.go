@@ -0,0 +1,113 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"fmt"
+	"log"
+	"math"
+
+	"gorm.io/gorm"
+)
+
+// ValidateRouteDistances 校验线路各方向站点的累计距离沿站序单调不减
+// 只校验已填写距离的站点；返回第一处不满足单调性的位置
+func ValidateRouteDistances(db *gorm.DB, routeID uint) error {
+	var routeStations []models.RouteStation
+	if err := db.Where("route_id = ?", routeID).Order("direction ASC, sequence ASC").Find(&routeStations).Error; err != nil {
+		return fmt.Errorf("查询线路站点失败: %w", err)
+	}
+	return validateDistanceSequence(routeStations)
+}
+
+// validateDistanceSequence 校验按方向、站序排列的线路站点累计距离单调不减
+func validateDistanceSequence(routeStations []models.RouteStation) error {
+	lastDistance := make(map[string]float64)
+	lastSequence := make(map[string]int)
+	for _, rs := range routeStations {
+		if rs.DistanceKm == nil {
+			continue
+		}
+		if *rs.DistanceKm < 0 {
+			return fmt.Errorf("方向%s第%d站累计距离不能为负数", rs.Direction, rs.Sequence)
+		}
+		if previous, ok := lastDistance[rs.Direction]; ok && *rs.DistanceKm < previous {
+			return fmt.Errorf("方向%s第%d站累计距离%.3f公里小于第%d站的%.3f公里",
+				rs.Direction, rs.Sequence, *rs.DistanceKm, lastSequence[rs.Direction], previous)
+		}
+		lastDistance[rs.Direction] = *rs.DistanceKm
+		lastSequence[rs.Direction] = rs.Sequence
+	}
+	return nil
+}
+
+// kmToMeters 公里转换为米（距离计算统一使用整数米，避免浮点误差）
+func kmToMeters(km float64) int64 {
+	return int64(math.Round(km * 1000))
+}
+
+// calculateDistanceFare 按距离计价（tap_in_out模式）
+// 票价 = 基础票价（覆盖起步里程） + 向上取整((里程 - 起步里程) / 续程区间) × 续程价，不超过封顶票价
+// 缺少距离计价规则或无法确定行程路径时退回按站数分段计价；站点距离缺失或不合法时按线路max_fare计价
+// （未设置max_fare时按站数分段计价），并在计费明细中记录原因，不因主数据问题丢弃行程
+// （站序写入时已由ValidateRouteDistances严格校验，计价时只检查本次行程所在方向已加载的站序，不再查询数据库）
+func (s *FareService) calculateDistanceFare(route *models.Route, startStationID, endStationID uint, direction string) (models.Money, fareSource) {
+	routeID := route.ID
+	var fare models.Fare
+	err := s.db.Where(
+		"route_id = ? AND fare_type = 'distance' AND status = 'active' AND start_station = 0 AND end_station = 0",
+		routeID,
+	).First(&fare).Error
+	if err != nil {
+		return s.calculateSegmentFareByStations(route, startStationID, endStationID, direction)
+	}
+
+	path, ok := s.resolveTripPath(route, startStationID, endStationID, direction)
+	if !ok {
+		return s.calculateSegmentFareByStations(route, startStationID, endStationID, direction)
+	}
+	if err := path.pattern.validateDistances(); err != nil {
+		return s.distanceFallbackFare(route, path, fmt.Sprintf("累计距离数据不合法: %v", err))
+	}
+	distance, ok := path.Meters()
+	if !ok {
+		return s.distanceFallbackFare(route, path, fmt.Sprintf("方向%s缺少站点累计距离", path.Direction()))
+	}
+
+	included := kmToMeters(fare.BaseDistanceKm)
+	band := kmToMeters(fare.BandKm)
+	if band <= 0 {
+		band = 1000 // 默认按每公里计价
+	}
+
+	price := fare.BasePrice
+	var bands int64
+	if distance > included && fare.ExtraPrice > 0 {
+		bands = (distance - included + band - 1) / band
+		price += models.Money(bands) * fare.ExtraPrice
+	}
+
+	details := path.details()
+	details["distance_km"] = float64(distance) / 1000
+	details["base_distance_km"] = fare.BaseDistanceKm
+	details["band_km"] = float64(band) / 1000
+	details["extra_bands"] = bands
+	details["extra_price"] = fare.ExtraPrice
+	if fare.CapPrice > 0 && price > fare.CapPrice {
+		details["capped_from"] = price
+		price = fare.CapPrice
+	}
+	return price, fareSource{Rule: fareRuleRef("fares", fare.ID), Method: "distance", Details: details, Direction: path.Direction()}
+}
+
+// distanceFallbackFare 站点距离数据无法用于计价时的兜底票价：线路max_fare，未设置时按站数分段计价
+func (s *FareService) distanceFallbackFare(route *models.Route, path *tripPath, reason string) (models.Money, fareSource) {
+	log.Printf("线路%s无法按距离计价（%s），改用兜底票价", route.RouteID, reason)
+	if route.MaxFare > 0 {
+		details := path.details()
+		details["distance_fallback"] = reason
+		return route.MaxFare, fareSource{Rule: "route.max_fare", Method: "max_fare", Details: details, Direction: path.Direction()}
+	}
+	price, source := s.calculateSegmentFareByStations(route, path.Board().StationID, path.Alight().StationID, path.Direction())
+	source.Details["distance_fallback"] = reason
+	return price, source
+}
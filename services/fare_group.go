@@ -0,0 +1,209 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"fmt"
+)
+
+// StagePassengers 同行乘客阶段名称
+const StagePassengers = "passengers"
+
+// 乘客类别
+const (
+	PassengerCardholder = "cardholder" // 持卡人
+	PassengerAdult      = "adult"      // 普通成人
+	PassengerCompanion  = "companion"  // 陪同人员（按持卡人卡类型的陪同规则优惠）
+)
+
+// PassengerGroup 同行乘客（持卡人之外由同一张卡付费的乘客）
+type PassengerGroup struct {
+	Category string `json:"category"` // 乘客类别：adult, companion, 或按同名卡类型计费的类别（student, elder, child等）
+	Count    int    `json:"count"`    // 人数
+}
+
+// passengerCardType 乘客类别对应的计费卡类型
+func passengerCardType(category string) string {
+	switch category {
+	case "", PassengerAdult, PassengerCompanion:
+		return "normal"
+	default:
+		return category
+	}
+}
+
+// defaultCardTypes 有内置默认优惠的卡类型（见getDefaultCardDiscount）
+var defaultCardTypes = []string{"normal", "student", "elder", "disabled"}
+
+// knownPassengerCategory 同行乘客类别是否可计费：adult、companion、有内置默认优惠的卡类型，
+// 或有生效中特殊票种优惠策略的卡类型
+func (s *FareService) knownPassengerCategory(category string) bool {
+	switch category {
+	case PassengerAdult, PassengerCompanion:
+		return true
+	}
+	for _, cardType := range defaultCardTypes {
+		if category == cardType {
+			return true
+		}
+	}
+	var count int64
+	s.db.Model(&models.DiscountPolicy{}).Where("card_type_filter = ? AND status = 'active'", category).Count(&count)
+	return count > 0
+}
+
+// passengersFromComposition 从已记录的同行乘客构成还原同行乘客（不含持卡人）
+func passengersFromComposition(composition models.GroupComposition) []PassengerGroup {
+	counts := make(map[string]int)
+	var order []string
+	for _, entry := range composition {
+		if entry.Category == PassengerCardholder {
+			continue
+		}
+		if _, ok := counts[entry.Category]; !ok {
+			order = append(order, entry.Category)
+		}
+		counts[entry.Category] += entry.Count
+	}
+	var passengers []PassengerGroup
+	for _, category := range order {
+		passengers = append(passengers, PassengerGroup{Category: category, Count: counts[category]})
+	}
+	return passengers
+}
+
+// getCompanionRule 获取持卡人卡类型的陪同人员优惠规则
+func (s *FareService) getCompanionRule(cardType string) (*models.CompanionRule, bool) {
+	var rule models.CompanionRule
+	if err := s.db.Where("card_type = ? AND status = 'active'", cardType).First(&rule).Error; err != nil {
+		return nil, false
+	}
+	return &rule, true
+}
+
+// passengerStage 同行乘客阶段（一张卡为多名乘客付费时，逐一为持卡人之外的乘客计费并计入合计）
+// 同行乘客按各自类别对应的卡类型执行同一条计费流水线（不享受持卡人的换乘、月度与票卡优惠，也不参与优惠活动），
+// 陪同人员按持卡人卡类型的陪同规则优惠
+type passengerStage struct {
+	fareService *FareService
+}
+
+func (st *passengerStage) Name() string { return StagePassengers }
+
+func (st *passengerStage) Apply(fc *FareContext) error {
+	if len(fc.Request.Passengers) == 0 {
+		return nil
+	}
+	result := fc.Result
+	holderCardType := fc.CardType()
+	if holderCardType == "" {
+		holderCardType = "normal"
+	}
+
+	composition := models.GroupComposition{{
+		Category:   PassengerCardholder,
+		CardType:   holderCardType,
+		Count:      1,
+		Fare:       result.BaseFare,
+		ActualFare: result.ActualFare,
+	}}
+	var companionRule *models.CompanionRule
+	hasCompanionRule, companionRuleLoaded := false, false
+	discountedCompanions := 0
+
+	for _, group := range fc.Request.Passengers {
+		if group.Count <= 0 {
+			continue
+		}
+		cardType := passengerCardType(group.Category)
+		passengerResult, err := st.fareService.pricePassenger(fc, cardType)
+		if err != nil {
+			return err
+		}
+		entry := models.PassengerFare{
+			Category:   group.Category,
+			CardType:   cardType,
+			Count:      group.Count,
+			Fare:       passengerResult.BaseFare,
+			ActualFare: passengerResult.ActualFare,
+		}
+		if group.Category == PassengerCompanion && !companionRuleLoaded {
+			companionRule, hasCompanionRule = st.fareService.getCompanionRule(holderCardType)
+			companionRuleLoaded = true
+		}
+		if group.Category == PassengerCompanion && hasCompanionRule && !result.PenaltyFare {
+			eligible := companionRule.MaxCompanions - discountedCompanions
+			if eligible > group.Count {
+				eligible = group.Count
+			}
+			if eligible > 0 {
+				discounted := entry
+				discounted.Count = eligible
+				discounted.ActualFare -= discountByRate(entry.ActualFare, companionRule.DiscountRate, st.fareService.roundingFor(&fc.Route).Mode)
+				discounted.Rule = fareRuleRef("companion_rules", companionRule.ID)
+				composition = append(composition, discounted)
+				discountedCompanions += eligible
+				entry.Count -= eligible
+			}
+		}
+		if entry.Count > 0 {
+			composition = append(composition, entry)
+		}
+	}
+
+	before := result.ActualFare
+	passengerCount := 0
+	for i, entry := range composition {
+		passengerCount += entry.Count
+		if i == 0 {
+			continue
+		}
+		count := models.Money(entry.Count)
+		result.BaseFare += entry.Fare * count
+		result.ActualFare += entry.ActualFare * count
+		result.DiscountAmount += (entry.Fare - entry.ActualFare) * count
+	}
+	if discountedCompanions > 0 {
+		if result.DiscountType != "" {
+			result.DiscountType += "," + PassengerCompanion
+		} else {
+			result.DiscountType = PassengerCompanion
+		}
+	}
+	result.PassengerCount = passengerCount
+	result.Passengers = composition
+
+	fc.AddTrace(models.FareTraceStep{
+		Stage:       StagePassengers,
+		Description: fmt.Sprintf("同行%d人，逐一计费后合计", passengerCount),
+		Before:      before,
+		After:       result.ActualFare,
+		Details: map[string]interface{}{
+			"passengers":            composition,
+			"discounted_companions": discountedCompanions,
+		},
+	})
+	return nil
+}
+
+// pricePassenger 按指定卡类型为一名同行乘客计费（不关联卡片，因此不享受换乘、月度与票卡优惠）
+// 同行乘客不参与优惠活动：活动的预算、次数与核销记录只按持卡人的优惠预留，避免一次刷卡按人数重复消耗活动
+func (s *FareService) pricePassenger(fc *FareContext, cardType string) (*FareCalculationResult, error) {
+	passenger := &FareContext{
+		Request: FareRequest{
+			RouteID:        fc.Request.RouteID,
+			StartStationID: fc.Request.StartStationID,
+			EndStationID:   fc.Request.EndStationID,
+			BoardTime:      fc.Request.BoardTime,
+			PenaltyFare:    fc.Request.PenaltyFare,
+			Direction:      fc.Request.Direction,
+			CardType:       cardType,
+			SkipCampaigns:  true,
+		},
+		Route:  fc.Route,
+		Result: &FareCalculationResult{},
+	}
+	if err := s.pipeline.Run(passenger); err != nil {
+		return nil, fmt.Errorf("同行乘客计费失败: %w", err)
+	}
+	return passenger.Result, nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"testing"
+)
+
+// flatFareStage 测试用基础票价阶段（固定票价）
+type flatFareStage struct {
+	fare models.Money
+}
+
+func (st *flatFareStage) Name() string { return StageBaseFare }
+
+func (st *flatFareStage) Apply(fc *FareContext) error {
+	fc.Result.BaseFare = st.fare
+	fc.Result.ActualFare = st.fare
+	return nil
+}
+
+// defaultPolicyDiscountStage 测试用优惠阶段（使用内置默认叠加策略，规则选择与discountStage相同）
+type defaultPolicyDiscountStage struct {
+	discountStage
+}
+
+func (st *defaultPolicyDiscountStage) Apply(fc *FareContext) error {
+	applyStackedDiscounts(defaultStackingPolicy(), st.rulesFor(fc), fc)
+	return nil
+}
+
+// budgetCampaignRule 测试用限总预算的活动（优惠实际应用后扣减剩余预算）
+type budgetCampaignRule struct {
+	remaining models.Money
+	rides     int
+}
+
+func (r *budgetCampaignRule) Kind() string { return DiscountKindCampaign }
+
+func (r *budgetCampaignRule) Evaluate(fc *FareContext, currentFare models.Money) Discount {
+	amount := currentFare.Min(r.remaining)
+	if amount <= 0 {
+		return Discount{}
+	}
+	return Discount{
+		Amount: amount,
+		Type:   "campaign",
+		Rule:   "campaigns#1",
+		OnApply: func(fc *FareContext, applied models.Money) {
+			r.remaining -= applied
+			r.rides++
+		},
+	}
+}
+
+func TestPassengersDoNotConsumeCampaign(t *testing.T) {
+	tests := []struct {
+		name          string
+		passengers    []PassengerGroup
+		budget        models.Money
+		wantActual    models.Money
+		wantDiscount  models.Money
+		wantRemaining models.Money
+		wantCount     int
+	}{
+		{
+			name:          "活动预算只按持卡人消耗",
+			passengers:    []PassengerGroup{{Category: PassengerAdult, Count: 4}},
+			budget:        300,
+			wantActual:    800, // 持卡人免费，4名同行乘客各200
+			wantDiscount:  200,
+			wantRemaining: 100,
+			wantCount:     5,
+		},
+		{
+			name:          "预算不足时同行乘客也不占用剩余预算",
+			passengers:    []PassengerGroup{{Category: PassengerAdult, Count: 1}, {Category: "student", Count: 2}},
+			budget:        50,
+			wantActual:    750, // 持卡人优惠50，3名同行乘客各200
+			wantDiscount:  50,
+			wantRemaining: 0,
+			wantCount:     4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			campaign := &budgetCampaignRule{remaining: tt.budget}
+			s := &FareService{rounding: roundingPolicy{Mode: models.RoundDown, Unit: 1}}
+			s.pipeline = NewFarePipeline(
+				&flatFareStage{fare: 200},
+				&defaultPolicyDiscountStage{discountStage{fareService: s, rules: map[string]DiscountRule{DiscountKindCampaign: campaign}}},
+				&passengerStage{fareService: s},
+			)
+			fc := &FareContext{
+				Request: FareRequest{CardType: "normal", Passengers: tt.passengers},
+				Result:  &FareCalculationResult{},
+			}
+			if err := s.pipeline.Run(fc); err != nil {
+				t.Fatalf("计费失败: %v", err)
+			}
+			if fc.Result.ActualFare != tt.wantActual {
+				t.Errorf("ActualFare = %s，应为 %s", fc.Result.ActualFare, tt.wantActual)
+			}
+			if fc.Result.DiscountAmount != tt.wantDiscount {
+				t.Errorf("DiscountAmount = %s，应为 %s", fc.Result.DiscountAmount, tt.wantDiscount)
+			}
+			if campaign.remaining != tt.wantRemaining || campaign.rides != 1 {
+				t.Errorf("活动剩余预算 = %s、使用次数 = %d，应为 %s、1", campaign.remaining, campaign.rides, tt.wantRemaining)
+			}
+			if fc.Result.PassengerCount != tt.wantCount {
+				t.Errorf("PassengerCount = %d，应为 %d", fc.Result.PassengerCount, tt.wantCount)
+			}
+			for _, entry := range fc.Result.Passengers[1:] {
+				if entry.ActualFare != entry.Fare {
+					t.Errorf("同行乘客%s享受了活动优惠: %+v", entry.Category, entry)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StageJourney 行程阶段名称
+const StageJourney = "journey"
+
+// JourneyLink 本程所属行程（通过换乘连接的多程乘车）
+type JourneyLink struct {
+	ID               string           // 行程ID（无卡片时为空）
+	Leg              int              // 本程是行程的第几程（从1开始）
+	Previous         *PreviousLeg     // 换乘的上一程（新行程为nil）
+	Transfer         *models.Transfer // 命中的换乘规则（新行程为nil）
+	DiscountEligible bool             // 是否可享受换乘优惠（未超过每个行程的换乘优惠次数）
+}
+
+// newJourneyID 生成行程ID（卡片ID+首程上车时间）
+func newJourneyID(cardID string, start time.Time) string {
+	if cardID == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s_%d", cardID, start.Unix())
+}
+
+// journeyStage 行程阶段（判断本程是否为上一程的换乘，确定所属行程与命中的换乘规则）
+type journeyStage struct {
+	fareService *FareService
+}
+
+func (st *journeyStage) Name() string { return StageJourney }
+
+func (st *journeyStage) Apply(fc *FareContext) error {
+	req := fc.Request
+	link := &JourneyLink{ID: newJourneyID(req.CardID, req.BoardTime), Leg: 1}
+	fc.Journey = link
+
+	previous := req.PreviousLeg
+	if previous == nil {
+		previous = st.fareService.findPreviousLeg(req.CardID, req.BoardTime, req.StartStationID)
+	} else {
+		st.fareService.completeSingleTapLeg(previous, req.BoardTime, req.StartStationID)
+	}
+	details := map[string]interface{}{}
+	description := "新行程"
+	rule := ""
+	if previous != nil {
+		transfer, reason := st.fareService.matchTransfer(previous, fc.Route.ID, req.StartStationID, req.BoardTime)
+		if transfer != nil {
+			journeyID := previous.JourneyID
+			if journeyID == "" {
+				journeyID = newJourneyID(req.CardID, previous.journeyStart())
+			}
+			link.ID = journeyID
+			link.Leg = previous.journeyLegs() + 1
+			link.Previous = previous
+			link.Transfer = transfer
+			link.DiscountEligible = transfer.MaxDiscountedTransfers <= 0 || previous.DiscountedTransfers < transfer.MaxDiscountedTransfers
+			rule = fareRuleRef("transfers", transfer.ID)
+			description = fmt.Sprintf("换乘上一程%s，行程第%d程", previous.RecordID, link.Leg)
+			if !link.DiscountEligible {
+				description += fmt.Sprintf("，已达每个行程%d次换乘优惠上限", transfer.MaxDiscountedTransfers)
+			}
+			details["discounted_transfers"] = previous.DiscountedTransfers
+		} else {
+			description = "新行程（" + reason + "）"
+		}
+		details["previous_record_id"] = previous.RecordID
+		if previous.AlightEstimated {
+			details["previous_alight_estimated"] = previous.AlightTime
+		}
+		if previous.StationInferred {
+			details["previous_station_inferred"] = previous.EndStation
+		}
+	}
+	details["journey_id"] = link.ID
+	details["journey_leg"] = link.Leg
+
+	fc.Result.JourneyID = link.ID
+	fc.Result.JourneyLeg = link.Leg
+	fc.AddTrace(models.FareTraceStep{
+		Stage:       StageJourney,
+		Rule:        rule,
+		Description: description,
+		Before:      fc.Result.ActualFare,
+		After:       fc.Result.ActualFare,
+		Details:     details,
+	})
+	return nil
+}
+
+// journeyStart 行程首程上车时间（未知时取上一程上车时间，仍未知时取上一程下车时间）
+func (p *PreviousLeg) journeyStart() time.Time {
+	if !p.JourneyStart.IsZero() {
+		return p.JourneyStart
+	}
+	if !p.BoardTime.IsZero() {
+		return p.BoardTime
+	}
+	return p.AlightTime
+}
+
+// journeyLegs 行程已有乘次（未知时按1计）
+func (p *PreviousLeg) journeyLegs() int {
+	if p.JourneyLegs > 0 {
+		return p.JourneyLegs
+	}
+	return 1
+}
+
+// completeSingleTapLeg 补全single_tap上一程的下车信息
+// 下车时间按上一程线路的最长乘车时间估算（不晚于本程上车时间）；
+// 开启下车站点推断时，本程上车站在上一程线路上则视为在该站下车
+func (s *FareService) completeSingleTapLeg(previous *PreviousLeg, boardTime time.Time, boardStationID uint) {
+	if previous.AlightTime.IsZero() && !previous.BoardTime.IsZero() {
+		maxRideMinutes := s.defaultMaxRideMinutes
+		var route models.Route
+		if err := s.db.First(&route, previous.RouteID).Error; err == nil && route.MaxRideMinutes > 0 {
+			maxRideMinutes = route.MaxRideMinutes
+		}
+		alightTime := previous.BoardTime.Add(time.Duration(maxRideMinutes) * time.Minute)
+		if alightTime.After(boardTime) {
+			alightTime = boardTime
+		}
+		previous.AlightTime = alightTime
+		previous.AlightEstimated = true
+	}
+
+	if previous.EndStation == 0 && s.inferAlightStation && boardStationID != 0 {
+		var count int64
+		s.db.Model(&models.RouteStation{}).Where("route_id = ? AND station_id = ?", previous.RouteID, boardStationID).Count(&count)
+		if count > 0 {
+			previous.EndStation = boardStationID
+			previous.StationInferred = true
+		}
+	}
+}
+
+// loadJourneyStats 加载上一程所属行程的乘次、首程上车时间与已享受换乘优惠次数
+func (s *FareService) loadJourneyStats(previous *PreviousLeg) {
+	previous.JourneyStart = previous.BoardTime
+	previous.JourneyLegs = 1
+	if previous.JourneyID == "" {
+		return
+	}
+	var stats struct {
+		Legs       int
+		Start      time.Time
+		Discounted int
+	}
+	// discount_type为逗号分隔的优惠类型，按完整类型匹配换乘优惠（不匹配包含transfer字样的其他类型）
+	err := s.db.Model(&models.Transaction{}).
+		Select("COUNT(*) AS legs, MIN(board_time) AS start, "+
+			"COALESCE(SUM(CASE WHEN ',' || discount_type || ',' LIKE ? THEN 1 ELSE 0 END), 0) AS discounted", "%,"+transferDiscountType+",%").
+		Where("journey_id = ? AND status = 'completed'", previous.JourneyID).
+		Scan(&stats).Error
+	if err != nil || stats.Legs == 0 {
+		return
+	}
+	previous.JourneyLegs = stats.Legs
+	previous.JourneyStart = stats.Start
+	previous.DiscountedTransfers = stats.Discounted
+}
+
+// routeGroupIDs 查询线路所属的线路组
+func (s *FareService) routeGroupIDs(routeID uint) []uint {
+	var groupIDs []uint
+	s.db.Model(&models.RouteGroupMember{}).
+		Joins("JOIN route_groups ON route_groups.id = route_group_members.route_group_id AND route_groups.deleted_at IS NULL").
+		Where("route_group_members.route_id = ? AND route_groups.status = 'active'", routeID).
+		Pluck("route_group_members.route_group_id", &groupIDs)
+	return groupIDs
+}
+
+// transferSideScope 按换乘规则一侧（from/to）的线路、线路组与站点筛选可能匹配的规则（0表示任意）
+func transferSideScope(side string, routeID, stationID uint, groupIDs []uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		routeColumn, groupColumn, stationColumn := side+"_route_id", side+"_route_group_id", side+"_station_id"
+		if len(groupIDs) > 0 {
+			db = db.Where("("+routeColumn+" = ? OR ("+routeColumn+" = 0 AND ("+groupColumn+" = 0 OR "+groupColumn+" IN ?)))", routeID, groupIDs)
+		} else {
+			db = db.Where("("+routeColumn+" = ? OR ("+routeColumn+" = 0 AND "+groupColumn+" = 0))", routeID)
+		}
+		return db.Where("("+stationColumn+" = 0 OR "+stationColumn+" = ?)", stationID)
+	}
+}
+
+// transferSideScore 换乘规则一端（线路/线路组/站点）的匹配度（不匹配返回-1）
+// 指定站点 > 指定线路 > 指定线路组 > 任意
+func transferSideScore(ruleRouteID, ruleGroupID, ruleStationID, routeID, stationID uint, groups map[uint]bool) int {
+	score := 0
+	switch {
+	case ruleRouteID != 0:
+		if ruleRouteID != routeID {
+			return -1
+		}
+		score += 2
+	case ruleGroupID != 0:
+		if !groups[ruleGroupID] {
+			return -1
+		}
+		score++
+	}
+	if ruleStationID != 0 {
+		if ruleStationID != stationID {
+			return -1
+		}
+		score += 4
+	}
+	return score
+}
+
+// matchTransfer 查找适用的换乘规则：按匹配度从高到低依次检查换乘时间窗口、行程总时间窗口与行程最多乘次
+// 无适用规则时返回不适用原因
+// 线路、站点与换乘时间窗口在查询中筛选，只加载可能适用的规则
+func (s *FareService) matchTransfer(previous *PreviousLeg, routeID uint, stationID uint, boardTime time.Time) (*models.Transfer, string) {
+	fromGroupIDs := s.routeGroupIDs(previous.RouteID)
+	toGroupIDs := s.routeGroupIDs(routeID)
+	matching := s.db.Model(&models.Transfer{}).Where("status = 'active'").Scopes(
+		transferSideScope("from", previous.RouteID, previous.EndStation, fromGroupIDs),
+		transferSideScope("to", routeID, stationID, toGroupIDs),
+	)
+
+	// 换乘时间窗口为0时按60分钟计算
+	elapsed := boardTime.Sub(previous.AlightTime).Minutes()
+	var transfers []models.Transfer
+	if err := matching.Session(&gorm.Session{}).Where("COALESCE(NULLIF(time_window, 0), 60) >= ?", elapsed).Find(&transfers).Error; err != nil {
+		return nil, "查询换乘规则失败"
+	}
+	if len(transfers) == 0 {
+		var widest models.Transfer
+		if err := matching.Session(&gorm.Session{}).Order("COALESCE(NULLIF(time_window, 0), 60) DESC").First(&widest).Error; err == nil {
+			return nil, fmt.Sprintf("超过换乘时间窗口%d分钟", transferTimeWindow(widest))
+		}
+		return nil, "无匹配的换乘规则"
+	}
+
+	fromGroups := uintSet(fromGroupIDs)
+	toGroups := uintSet(toGroupIDs)
+	type candidate struct {
+		transfer models.Transfer
+		score    int
+	}
+	var candidates []candidate
+	for _, t := range transfers {
+		fromScore := transferSideScore(t.FromRouteID, t.FromRouteGroupID, t.FromStationID, previous.RouteID, previous.EndStation, fromGroups)
+		toScore := transferSideScore(t.ToRouteID, t.ToRouteGroupID, t.ToStationID, routeID, stationID, toGroups)
+		if fromScore < 0 || toScore < 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{transfer: t, score: fromScore + toScore})
+	}
+	if len(candidates) == 0 {
+		return nil, "无匹配的换乘规则"
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].transfer.ID < candidates[j].transfer.ID
+	})
+
+	reason := ""
+	for _, c := range candidates {
+		t := c.transfer
+		if t.JourneyWindow > 0 && boardTime.Sub(previous.journeyStart()).Minutes() > float64(t.JourneyWindow) {
+			if reason == "" {
+				reason = fmt.Sprintf("超过行程总时间窗口%d分钟", t.JourneyWindow)
+			}
+			continue
+		}
+		if t.MaxLegs > 0 && previous.journeyLegs() >= t.MaxLegs {
+			if reason == "" {
+				reason = fmt.Sprintf("行程已达最多%d程", t.MaxLegs)
+			}
+			continue
+		}
+		return &t, ""
+	}
+	return nil, reason
+}
+
+// transferTimeWindow 换乘时间窗口（分钟，未配置时为60）
+func transferTimeWindow(t models.Transfer) int {
+	if t.TimeWindow == 0 {
+		return 60
+	}
+	return t.TimeWindow
+}
+
+// uintSet 将ID列表转换为集合
+func uintSet(ids []uint) map[uint]bool {
+	set := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// transferDiscountType 换乘优惠写入交易discount_type的类型
+const transferDiscountType = "transfer"
+
+// transferDiscount 计算换乘优惠（优惠形式优先级：discount_amount > discount_rate）
+func (s *FareService) transferDiscount(link *JourneyLink, baseFare models.Money, mode models.RoundingMode) Discount {
+	transfer := link.Transfer
+	timeWindowMinutes := transfer.TimeWindow
+	if timeWindowMinutes == 0 {
+		timeWindowMinutes = 60
+	}
+	previousDesc := "上一程"
+	if link.Previous.RecordID != "" {
+		previousDesc = "上一程交易" + link.Previous.RecordID
+	}
+	discount := Discount{
+		Type: transferDiscountType,
+		Rule: fareRuleRef("transfers", transfer.ID),
+	}
+	if transfer.DiscountAmount > 0 {
+		discount.Amount = transfer.DiscountAmount
+		discount.Description = fmt.Sprintf("%s，%d分钟内换乘固定优惠%s元", previousDesc, timeWindowMinutes, transfer.DiscountAmount)
+	} else if transfer.DiscountRate >= 0 {
+		discount.Amount = discountByRate(baseFare, transfer.DiscountRate, mode)
+		discount.Description = fmt.Sprintf("%s，%d分钟内换乘优惠比例%.4f", previousDesc, timeWindowMinutes, transfer.DiscountRate)
+	}
+	return discount
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// StagePass 票卡产品阶段名称
+const StagePass = "pass"
+
+// 卡片产品状态
+const (
+	CardProductPendingActivation = "pending_activation" // 待首次乘车激活
+	CardProductActive            = "active"
+	CardProductCancelled         = "cancelled"
+)
+
+// passStage 票卡产品阶段（卡片持有覆盖本程的有效月票、周票等时，本程免费并记录使用的票卡）
+type passStage struct {
+	fareService *FareService
+}
+
+func (st *passStage) Name() string { return StagePass }
+
+func (st *passStage) Apply(fc *FareContext) error {
+	if fc.Result.PenaltyFare || fc.Request.CardID == "" {
+		return nil
+	}
+	cardProduct, activate, ok := st.fareService.findCoveringPass(fc)
+	if !ok {
+		return nil
+	}
+
+	before := fc.Result.ActualFare
+	fc.Result.DiscountAmount += before
+	fc.Result.ActualFare = 0
+	if fc.Result.DiscountType != "" {
+		fc.Result.DiscountType += ",pass"
+	} else {
+		fc.Result.DiscountType = "pass"
+	}
+	passID := cardProduct.ID
+	fc.Result.PassID = &passID
+	fc.Result.PassActivation = activate
+
+	description := fmt.Sprintf("使用%s，本程免费", cardProduct.Product.Name)
+	details := map[string]interface{}{
+		"product_code": cardProduct.Product.ProductCode,
+	}
+	if activate {
+		validFrom, validUntil := passValidity(&cardProduct.Product, fc.Request.BoardTime)
+		description += "（首次乘车激活）"
+		details["valid_from"] = validFrom
+		details["valid_until"] = validUntil
+	} else {
+		details["valid_until"] = cardProduct.ValidUntil
+	}
+	fc.AddTrace(models.FareTraceStep{
+		Stage:       StagePass,
+		Rule:        fareRuleRef("card_products", cardProduct.ID),
+		Description: description,
+		Before:      before,
+		After:       fc.Result.ActualFare,
+		Details:     details,
+	})
+	return nil
+}
+
+// passValidity 计算票卡从start开始的有效期（失效时间不含）
+func passValidity(product *models.Product, start time.Time) (time.Time, time.Time) {
+	switch {
+	case product.ValidityDays > 0:
+		return start, start.AddDate(0, 0, product.ValidityDays)
+	case product.PassType == "weekly":
+		return start, start.AddDate(0, 0, 7)
+	default:
+		return start, start.AddDate(0, 1, 0)
+	}
+}
+
+// listContains 逗号分隔的配置列表是否包含指定项（列表为空表示全部）
+func listContains(list string, item string) bool {
+	items := splitList(list)
+	if len(items) == 0 {
+		return true
+	}
+	for _, candidate := range items {
+		if candidate == item {
+			return true
+		}
+	}
+	return false
+}
+
+// findCoveringPass 查找覆盖本程的有效票卡（优先使用已生效且最早到期的票卡，其次使用待激活的票卡）
+// 返回的activate表示本程将激活待激活的票卡
+func (s *FareService) findCoveringPass(fc *FareContext) (*models.CardProduct, bool, bool) {
+	var cardProducts []models.CardProduct
+	err := s.db.Preload("Product").
+		Where("card_id = ? AND status IN ?", fc.Request.CardID, []string{CardProductActive, CardProductPendingActivation}).
+		Order("valid_until ASC, id ASC").
+		Find(&cardProducts).Error
+	if err != nil || len(cardProducts) == 0 {
+		return nil, false, false
+	}
+
+	boardTime := fc.Request.BoardTime
+	var pending *models.CardProduct
+	for i := range cardProducts {
+		cp := &cardProducts[i]
+		if cp.Product.Status != "active" || !listContains(cp.Product.AllowedCardTypes, fc.CardType()) || !s.passCoversTrip(&cp.Product, fc) {
+			continue
+		}
+		switch cp.Status {
+		case CardProductActive:
+			if cp.ValidFrom != nil && cp.ValidUntil != nil && !boardTime.Before(*cp.ValidFrom) && boardTime.Before(*cp.ValidUntil) {
+				return cp, false, true
+			}
+		case CardProductPendingActivation:
+			if pending == nil && cp.Product.ActivateOnFirstUse && !boardTime.Before(cp.PurchaseTime) {
+				pending = cp
+			}
+		}
+	}
+	if pending != nil {
+		return pending, true, true
+	}
+	return nil, false, false
+}
+
+// passCoversTrip 票卡是否覆盖本程的线路与分区（上车站与下车站的分区都需在适用范围内）
+func (s *FareService) passCoversTrip(product *models.Product, fc *FareContext) bool {
+	if !listContains(product.CoveredRoutes, strconv.FormatUint(uint64(fc.Route.ID), 10)) {
+		return false
+	}
+	if len(splitList(product.CoveredZones)) == 0 {
+		return true
+	}
+	stationIDs := []uint{fc.Request.StartStationID}
+	if fc.Request.EndStationID != nil {
+		stationIDs = append(stationIDs, *fc.Request.EndStationID)
+	}
+	for _, stationID := range stationIDs {
+		var routeStation models.RouteStation
+		err := s.db.Where("route_id = ? AND station_id = ?", fc.Route.ID, stationID).First(&routeStation).Error
+		if err != nil || routeStation.ZoneID == nil || !listContains(product.CoveredZones, *routeStation.ZoneID) {
+			return false
+		}
+	}
+	return true
+}
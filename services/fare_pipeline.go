@@ -0,0 +1,84 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"fmt"
+)
+
+// FareContext 计费上下文（在各计费阶段之间传递）
+type FareContext struct {
+	Request FareRequest
+	Route   models.Route
+	Card    *models.Card // 卡片不存在时为nil
+	Journey *JourneyLink // 本程所属行程（由行程阶段确定）
+	Result  *FareCalculationResult
+}
+
+// CardType 返回本次计费使用的卡类型（优先使用请求指定的卡类型，卡片不存在时为空）
+func (fc *FareContext) CardType() string {
+	if fc.Request.CardType != "" {
+		return fc.Request.CardType
+	}
+	if fc.Card == nil {
+		return ""
+	}
+	return fc.Card.CardType
+}
+
+// AddTrace 记录计费明细步骤
+func (fc *FareContext) AddTrace(step models.FareTraceStep) {
+	fc.Result.Trace = append(fc.Result.Trace, step)
+}
+
+// FareStage 计费阶段，所有计费规则都以阶段的形式注册到流水线
+type FareStage interface {
+	// Name 阶段名称（唯一，用于插入定位与问题排查）
+	Name() string
+	// Apply 在计费上下文上应用本阶段规则
+	Apply(fc *FareContext) error
+}
+
+// FarePipeline 计费流水线（按注册顺序依次执行各计费阶段）
+type FarePipeline struct {
+	stages []FareStage
+}
+
+// NewFarePipeline 创建计费流水线
+func NewFarePipeline(stages ...FareStage) *FarePipeline {
+	return &FarePipeline{stages: stages}
+}
+
+// Register 在流水线末尾注册计费阶段
+func (p *FarePipeline) Register(stage FareStage) {
+	p.stages = append(p.stages, stage)
+}
+
+// InsertBefore 在指定阶段之前插入计费阶段（找不到指定阶段时追加到末尾）
+func (p *FarePipeline) InsertBefore(name string, stage FareStage) {
+	for i, existing := range p.stages {
+		if existing.Name() == name {
+			p.stages = append(p.stages[:i], append([]FareStage{stage}, p.stages[i:]...)...)
+			return
+		}
+	}
+	p.Register(stage)
+}
+
+// Stages 返回已注册的阶段名称（按执行顺序）
+func (p *FarePipeline) Stages() []string {
+	names := make([]string, 0, len(p.stages))
+	for _, stage := range p.stages {
+		names = append(names, stage.Name())
+	}
+	return names
+}
+
+// Run 依次执行所有计费阶段
+func (p *FarePipeline) Run(fc *FareContext) error {
+	for _, stage := range p.stages {
+		if err := stage.Apply(fc); err != nil {
+			return fmt.Errorf("计费阶段 %s 执行失败: %w", stage.Name(), err)
+		}
+	}
+	return nil
+}
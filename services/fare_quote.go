@@ -0,0 +1,107 @@
+package services
+
+import (
+	"fmt"
+	"time"
+)
+
+// FareQuoteRequest 票价报价请求（乘车前查询票价，不产生任何交易或累计）
+type FareQuoteRequest struct {
+	RouteID     uint              `json:"route_id" binding:"required"`
+	FromStation string            `json:"from_station" binding:"required"` // 上车站点（编号或名称）
+	ToStation   string            `json:"to_station"`                      // 下车站点（编号或名称，single_tap线路可为空）
+	CardType    string            `json:"card_type"`                       // 卡类型（与card_id二选一，同时提供时以card_type为准）
+	CardID      string            `json:"card_id"`                         // 卡片ID（提供时按卡片的卡类型、月度累计和乘车记录报价）
+	BoardTime   *FlexibleTime     `json:"board_time"`                      // 上车时间（为空时取当前时间）
+	Direction   string            `json:"direction"`                       // 行驶方向（up/down，为空时按上下车站点推断）
+	Passengers  []PassengerGroup  `json:"passengers"`                      // 同行乘客（不含持卡人）
+	PreviousLeg *QuotePreviousLeg `json:"previous_leg"`                    // 上一程（用于换乘报价）
+}
+
+// QuotePreviousLeg 报价请求中的上一程信息
+type QuotePreviousLeg struct {
+	RouteID       uint          `json:"route_id"`
+	AlightStation string        `json:"alight_station"` // 下车站点（编号或名称，single_tap上一程可为空）
+	AlightTime    FlexibleTime  `json:"alight_time"`    // 下车时间（single_tap上一程可为空，按上车时间估算）
+	BoardTime     *FlexibleTime `json:"board_time"`     // 上车时间（single_tap上一程使用）
+}
+
+// FareQuoteResult 票价报价结果
+type FareQuoteResult struct {
+	*FareCalculationResult
+	RouteID         uint      `json:"route_id"`
+	FromStationID   uint      `json:"from_station_id"`
+	FromStationName string    `json:"from_station_name"`
+	ToStationID     *uint     `json:"to_station_id,omitempty"`
+	ToStationName   string    `json:"to_station_name,omitempty"`
+	BoardTime       time.Time `json:"board_time"`
+}
+
+// Quote 票价报价：与实际扣费执行同一条计费流水线，但只读取配置和历史数据，不写入任何记录
+func (s *FareService) Quote(req FareQuoteRequest) (*FareQuoteResult, error) {
+	fromStationID, fromStationName, err := findStation(s.db, req.FromStation)
+	if err != nil {
+		return nil, err
+	}
+
+	fareReq := FareRequest{
+		CardID:         req.CardID,
+		RouteID:        req.RouteID,
+		StartStationID: fromStationID,
+		BoardTime:      time.Now(),
+		CardType:       req.CardType,
+		Direction:      req.Direction,
+		Passengers:     req.Passengers,
+	}
+	if req.BoardTime != nil && !req.BoardTime.IsZero() {
+		fareReq.BoardTime = req.BoardTime.Time
+	}
+	if fareReq.CardType == "" && fareReq.CardID == "" {
+		fareReq.CardType = "normal"
+	}
+
+	result := &FareQuoteResult{
+		RouteID:         req.RouteID,
+		FromStationID:   fromStationID,
+		FromStationName: fromStationName,
+		BoardTime:       fareReq.BoardTime,
+	}
+
+	if req.ToStation != "" {
+		toStationID, toStationName, err := findStation(s.db, req.ToStation)
+		if err != nil {
+			return nil, err
+		}
+		fareReq.EndStationID = &toStationID
+		result.ToStationID = &toStationID
+		result.ToStationName = toStationName
+	}
+
+	if req.PreviousLeg != nil {
+		previous := &PreviousLeg{
+			RouteID:    req.PreviousLeg.RouteID,
+			AlightTime: req.PreviousLeg.AlightTime.Time,
+		}
+		if req.PreviousLeg.BoardTime != nil {
+			previous.BoardTime = req.PreviousLeg.BoardTime.Time
+		}
+		if previous.AlightTime.IsZero() && previous.BoardTime.IsZero() {
+			return nil, fmt.Errorf("上一程缺少上车或下车时间")
+		}
+		if req.PreviousLeg.AlightStation != "" {
+			prevStationID, _, err := findStation(s.db, req.PreviousLeg.AlightStation)
+			if err != nil {
+				return nil, fmt.Errorf("上一程%w", err)
+			}
+			previous.EndStation = prevStationID
+		}
+		fareReq.PreviousLeg = previous
+	}
+
+	fareResult, err := s.Calculate(fareReq)
+	if err != nil {
+		return nil, err
+	}
+	result.FareCalculationResult = fareResult
+	return result, nil
+}
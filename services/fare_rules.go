@@ -0,0 +1,265 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"fmt"
+	"time"
+)
+
+// defaultBaseFare 系统默认票价（无任何票价规则时使用，2元）
+const defaultBaseFare models.Money = 200
+
+// 月度累计阶梯阈值
+const (
+	monthlyTierLow  models.Money = 20000 // 200元
+	monthlyTierHigh models.Money = 50000 // 500元
+)
+
+// fareSource 基础票价的来源（用于计费明细）
+type fareSource struct {
+	Rule    string                 // 命中的规则，如"fares#12"；兜底时为"route.max_fare"或"system_default"
+	Method  string                 // 计价方式
+	Details map[string]interface{} // 计算参数（站数、续程价等）
+
+	Direction string // 计价使用的行驶方向（按站序计价时）
+}
+
+// Discount 优惠规则的计算结果
+type Discount struct {
+	Amount      models.Money // 优惠金额
+	Type        string       // 优惠类型（写入交易的discount_type）
+	Rule        string       // 命中的规则，如"transfers#3"、"discount_policies#5"
+	Description string       // 说明
+
+	OnApply func(fc *FareContext, applied models.Money) // 优惠实际应用后的回调（applied为封顶后的金额，可为nil）
+}
+
+// fareRuleRef 生成规则引用（表名#ID）
+func fareRuleRef(table string, id uint) string {
+	return fmt.Sprintf("%s#%d", table, id)
+}
+
+// calculateBaseFare 计算基础票价（支持新的计费规则）
+// direction为网关上报的行驶方向（可为空，为空时按刷卡先后与站序推断）
+func (s *FareService) calculateBaseFare(route *models.Route, startStationID uint, endStationID *uint, direction string) (models.Money, fareSource, error) {
+	switch route.FareType {
+	case "uniform":
+		fare, source := s.getUniformFare(route.ID, route.MaxFare)
+		return fare, source, nil
+	case "segment":
+		if endStationID != nil && *endStationID > 0 {
+			if fare, source, ok := s.getStationPairFare(route.ID, startStationID, *endStationID); ok {
+				return fare, source, nil
+			}
+			fare, source := s.calculateSegmentFareByStations(route, startStationID, *endStationID, direction)
+			return fare, source, nil
+		} else {
+			fare, source := s.calculateSegmentFareByZone(route, startStationID, direction)
+			return fare, source, nil
+		}
+	case "zone":
+		fare, source := s.calculateZoneFare(route, startStationID, endStationID, direction)
+		return fare, source, nil
+	case "distance":
+		if endStationID == nil {
+			fare, source := s.getUniformFare(route.ID, route.MaxFare)
+			return fare, source, nil
+		}
+		fare, source := s.calculateDistanceFare(route, startStationID, *endStationID, direction)
+		return fare, source, nil
+	default:
+		fare, source := s.getUniformFare(route.ID, route.MaxFare)
+		return fare, source, nil
+	}
+}
+
+// getUniformFare 获取统一票价（无匹配则用max_fare兜底）
+func (s *FareService) getUniformFare(routeID uint, maxFare models.Money) (models.Money, fareSource) {
+	var fare models.Fare
+	err := s.db.Where("route_id = ? AND fare_type = 'uniform' AND status = 'active'", routeID).First(&fare).Error
+	if err == nil {
+		return fare.BasePrice, fareSource{Rule: fareRuleRef("fares", fare.ID), Method: "uniform"}
+	}
+	if maxFare > 0 {
+		return maxFare, fareSource{Rule: "route.max_fare", Method: "uniform"}
+	}
+	return defaultBaseFare, fareSource{Rule: "system_default", Method: "uniform"}
+}
+
+// getStationPairFare 获取站点对定价（优先匹配）
+func (s *FareService) getStationPairFare(routeID uint, startStationID, endStationID uint) (models.Money, fareSource, bool) {
+	var fare models.Fare
+	err := s.db.Where("route_id = ? AND start_station = ? AND end_station = ? AND status = 'active'",
+		routeID, startStationID, endStationID).First(&fare).Error
+	if err == nil && fare.BasePrice > 0 {
+		return fare.BasePrice, fareSource{Rule: fareRuleRef("fares", fare.ID), Method: "station_pair"}, true
+	}
+	return 0, fareSource{}, false
+}
+
+// calculateSegmentFareByZone 分段计价（single_tap模式，按上车站zone_id匹配zone定价）
+// 配置了分区票价矩阵或按分区数计价规则时，按上车分区到最远分区计价；否则匹配上车站的站点定价
+func (s *FareService) calculateSegmentFareByZone(route *models.Route, startStationID uint, direction string) (models.Money, fareSource) {
+	if price, source, ok := s.priceZoneToFurthest(route, startStationID, direction); ok {
+		return price, source
+	}
+	routeID, maxFare := route.ID, route.MaxFare
+	var routeStation models.RouteStation
+	err := s.db.Where("route_id = ? AND station_id = ?", routeID, startStationID).First(&routeStation).Error
+	if err != nil || routeStation.ZoneID == nil {
+		return s.getUniformFare(routeID, maxFare)
+	}
+	details := map[string]interface{}{"zone_id": *routeStation.ZoneID}
+	var fare models.Fare
+	err = s.db.Where("route_id = ? AND start_station = ? AND status = 'active'", routeID, startStationID).First(&fare).Error
+	if err == nil {
+		return fare.BasePrice, fareSource{Rule: fareRuleRef("fares", fare.ID), Method: "zone", Details: details}
+	}
+	if maxFare > 0 {
+		return maxFare, fareSource{Rule: "route.max_fare", Method: "zone", Details: details}
+	}
+	return s.getUniformFare(routeID, maxFare)
+}
+
+// calculateSegmentFareByStations 分段计价（tap_in_out模式，按站数阶梯计费）
+// 阶梯计费：5站以内2块，10站以内4块，15站以内8块，剩下的12块
+func (s *FareService) calculateSegmentFareByStations(route *models.Route, startStationID, endStationID uint, direction string) (models.Money, fareSource) {
+	routeID := route.ID
+	segmentCount := 0
+	details := map[string]interface{}{}
+	if path, ok := s.resolveTripPath(route, startStationID, endStationID, direction); ok {
+		segmentCount = path.Stops()
+		details = path.details()
+		direction = path.Direction()
+	}
+	details["segment_count"] = segmentCount
+	if segmentCount <= 0 {
+		return defaultBaseFare, fareSource{Rule: "system_default", Method: "segment", Details: details}
+	}
+	var fare models.Fare
+	err := s.db.Where(
+		"route_id = ? AND fare_type = 'segment' AND status = 'active' AND start_station = 0 AND end_station = 0",
+		routeID,
+	).First(&fare).Error
+	if err != nil {
+		return defaultBaseFare, fareSource{Rule: "system_default", Method: "segment", Details: details}
+	}
+	base := fare.BasePrice
+	if base <= 0 {
+		base = defaultBaseFare
+	}
+	extra := fare.ExtraPrice
+	included := fare.SegmentCount
+	if included <= 0 {
+		included = 1
+	}
+	details["included_segments"] = included
+	details["extra_price"] = extra
+	source := fareSource{Rule: fareRuleRef("fares", fare.ID), Method: "segment", Details: details, Direction: direction}
+	if segmentCount <= included || extra <= 0 {
+		return base, source
+	}
+	return base + models.Money(segmentCount-included)*extra, source
+}
+
+// checkCardTypeDiscount 检查卡类型折扣（默认值：学生8折、长者5折、爱心0元）
+func (s *FareService) checkCardTypeDiscount(cardType string, currentFare models.Money, mode models.RoundingMode) Discount {
+	if cardType == "normal" {
+		return Discount{}
+	}
+	var policy models.DiscountPolicy
+	err := s.db.Where("policy_type = ? AND (card_type_filter = ? OR card_type_filter = '') AND status = 'active'",
+		cardType, cardType).First(&policy).Error
+	if err != nil {
+		return s.getDefaultCardDiscount(cardType, currentFare, mode)
+	}
+	discount := Discount{
+		Type: cardType + "_discount",
+		Rule: fareRuleRef("discount_policies", policy.ID),
+	}
+	if policy.DiscountAmount > 0 {
+		discount.Amount = policy.DiscountAmount
+		discount.Description = fmt.Sprintf("%s：固定优惠%s元", policy.PolicyName, policy.DiscountAmount)
+	} else if policy.DiscountRate >= 0 {
+		discount.Amount = discountByRate(currentFare, policy.DiscountRate, mode)
+		discount.Description = fmt.Sprintf("%s：优惠比例%.4f", policy.PolicyName, policy.DiscountRate)
+	}
+	return discount
+}
+
+// getDefaultCardDiscount 获取默认卡类型折扣
+func (s *FareService) getDefaultCardDiscount(cardType string, currentFare models.Money, mode models.RoundingMode) Discount {
+	switch cardType {
+	case "student":
+		return Discount{Amount: discountByRate(currentFare, 0.2, mode), Type: "student_discount", Rule: "default:student", Description: "学生卡默认8折"}
+	case "elder":
+		return Discount{Amount: discountByRate(currentFare, 0.5, mode), Type: "elder_discount", Rule: "default:elder", Description: "长者卡默认5折"}
+	case "disabled":
+		return Discount{Amount: currentFare, Type: "disabled_discount", Rule: "default:disabled", Description: "爱心卡默认免费"}
+	default:
+		return Discount{}
+	}
+}
+
+// findPreviousLeg 查询卡片在本程上车之前最近一次已完成的乘车（不含罚款计费）
+// single_tap上一程没有下车记录，按线路最长乘车时间估算下车时间，并可推断下车站点
+func (s *FareService) findPreviousLeg(cardID string, boardTime time.Time, boardStationID uint) *PreviousLeg {
+	if cardID == "" {
+		return nil
+	}
+	var lastTransaction models.Transaction
+	err := s.db.Where("card_id = ? AND status = 'completed' AND penalty_fare = ? AND board_time < ?", cardID, false, boardTime).
+		Order("board_time DESC").First(&lastTransaction).Error
+	if err != nil {
+		return nil
+	}
+	previous := &PreviousLeg{
+		RecordID:  lastTransaction.RecordID,
+		RouteID:   lastTransaction.RouteID,
+		BoardTime: lastTransaction.BoardTime,
+		JourneyID: lastTransaction.JourneyID,
+	}
+	if lastTransaction.AlightTime != nil {
+		previous.AlightTime = *lastTransaction.AlightTime
+	}
+	if lastTransaction.EndStation != nil {
+		previous.EndStation = *lastTransaction.EndStation
+	}
+	s.completeSingleTapLeg(previous, boardTime, boardStationID)
+	s.loadJourneyStats(previous)
+	return previous
+}
+
+// checkMonthlyDiscount 检查月度累计折扣（阈值：≥ 200 元 8 折，≥ 500 元 5 折）
+// monthlySpent 为本次乘车前的当月累计消费
+func checkMonthlyDiscount(monthlySpent models.Money, currentAmountAfterDiscounts models.Money, mode models.RoundingMode) Discount {
+	totalAmount := monthlySpent + currentAmountAfterDiscounts
+	if totalAmount >= monthlyTierHigh {
+		return Discount{
+			Amount:      discountByRate(currentAmountAfterDiscounts, 0.5, mode),
+			Type:        "monthly_discount",
+			Rule:        "monthly_tier:500",
+			Description: fmt.Sprintf("当月累计%s元（含本次），达到500元阶梯享5折", totalAmount),
+		}
+	} else if totalAmount >= monthlyTierLow {
+		return Discount{
+			Amount:      discountByRate(currentAmountAfterDiscounts, 0.2, mode),
+			Type:        "monthly_discount",
+			Rule:        "monthly_tier:200",
+			Description: fmt.Sprintf("当月累计%s元（含本次），达到200元阶梯享8折", totalAmount),
+		}
+	}
+	return Discount{}
+}
+
+// discountByRate 按比例计算优惠金额：先按舍入模式计算优惠后的应付金额，再以差额作为优惠金额
+// （向下舍入时零头让利给乘客，与原先对实收金额向下保留两位小数的规则一致）
+func discountByRate(fare models.Money, rate float64, mode models.RoundingMode) models.Money {
+	if rate <= 0 || fare <= 0 {
+		return 0
+	}
+	if rate >= 1 {
+		return fare
+	}
+	return fare - fare.MulRate(1-rate, mode)
+}
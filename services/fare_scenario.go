@@ -0,0 +1,380 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"TapTransit-backend/utils"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"gorm.io/gorm"
+)
+
+// FareScenarioSuite 计费场景文件（JSON或YAML）
+// 场景在独立的数据库事务中执行：票价配置与场景写入的表以同名临时表遮蔽（不锁定、不修改实际数据），
+// 先以候选配置填充临时表，再按顺序上传各场景的刷卡记录，比对每次刷卡完成的乘次与期望票价，执行结束后回滚
+type FareScenarioSuite struct {
+	Name      string         `json:"name"`             // 场景集名称
+	Config    *FareConfigSet `json:"config,omitempty"` // 候选票价配置（为空时使用现有配置）
+	Scenarios []FareScenario `json:"scenarios"`        // 场景列表
+}
+
+// FareConfigSet 候选票价配置
+// 提供的表（包括空列表）在场景执行期间整表替换为候选配置，未提供的表复制现有配置
+type FareConfigSet struct {
+	Fares                    []models.Fare                   `json:"fares,omitempty"`
+	Transfers                []models.Transfer               `json:"transfers,omitempty"`
+	DiscountPolicies         []models.DiscountPolicy         `json:"discount_policies,omitempty"`
+	DiscountStackingPolicies []models.DiscountStackingPolicy `json:"discount_stacking_policies,omitempty"`
+}
+
+// FareScenario 计费场景（一张卡按顺序刷卡）
+type FareScenario struct {
+	Name         string            `json:"name"`          // 场景名称
+	CardID       string            `json:"card_id"`       // 卡片ID（为空时按场景序号生成）
+	CardType     string            `json:"card_type"`     // 卡类型（默认normal）
+	MonthlySpent models.Money      `json:"monthly_spent"` // 当月已累计消费（用于验证月度阶梯折扣）
+	Taps         []FareScenarioTap `json:"taps"`          // 按顺序的刷卡记录
+}
+
+// FareScenarioTap 场景中的一次刷卡（与网关上传的记录格式一致）
+type FareScenarioTap struct {
+	RouteID       uint             `json:"route_id"`
+	BoardStation  string           `json:"board_station"`
+	BoardTime     FlexibleTime     `json:"board_time"`
+	AlightStation string           `json:"alight_station"`
+	AlightTime    *FlexibleTime    `json:"alight_time"`
+	Direction     string           `json:"direction"`
+	Passengers    []PassengerGroup `json:"passengers"`
+
+	Expect *FareScenarioExpectation `json:"expect,omitempty"` // 本次刷卡完成的乘次的期望票价（为空时不校验，如tap_in_out的上车刷卡）
+}
+
+// FareScenarioExpectation 期望票价（只校验提供的字段）
+type FareScenarioExpectation struct {
+	BaseFare       *models.Money `json:"base_fare,omitempty"`       // 基础票价
+	DiscountAmount *models.Money `json:"discount_amount,omitempty"` // 优惠金额
+	DiscountType   *string       `json:"discount_type,omitempty"`   // 优惠类型
+	ActualFare     *models.Money `json:"actual_fare,omitempty"`     // 实收金额
+	Rules          []string      `json:"rules,omitempty"`           // 计费明细中必须命中的规则（如"transfers#3"）
+}
+
+// FareScenarioReport 场景执行报告
+type FareScenarioReport struct {
+	Suite     string               `json:"suite"`
+	Passed    int                  `json:"passed"` // 通过的场景数
+	Failed    int                  `json:"failed"` // 未通过的场景数
+	Scenarios []FareScenarioResult `json:"scenarios"`
+}
+
+// FareScenarioResult 单个场景的执行结果
+type FareScenarioResult struct {
+	Name   string                  `json:"name"`
+	CardID string                  `json:"card_id"`
+	Passed bool                    `json:"passed"`
+	Error  string                  `json:"error,omitempty"` // 场景无法执行的原因
+	Legs   []FareScenarioLegResult `json:"legs"`
+}
+
+// FareScenarioLegResult 单次刷卡的比对结果
+type FareScenarioLegResult struct {
+	Tap      int                `json:"tap"`                 // 刷卡序号（从1开始）
+	RecordID string             `json:"record_id,omitempty"` // 完成的乘次交易记录ID
+	Passed   bool               `json:"passed"`
+	Actual   *FareScenarioFare  `json:"actual,omitempty"` // 实际计费结果
+	Diffs    []FareScenarioDiff `json:"diffs,omitempty"`  // 与期望不一致的字段
+	Trace    models.FareTrace   `json:"trace,omitempty"`  // 计费明细（未通过时便于定位规则）
+}
+
+// FareScenarioFare 乘次的计费结果
+type FareScenarioFare struct {
+	BaseFare       models.Money `json:"base_fare"`
+	DiscountAmount models.Money `json:"discount_amount"`
+	DiscountType   string       `json:"discount_type"`
+	ActualFare     models.Money `json:"actual_fare"`
+}
+
+// FareScenarioDiff 期望与实际不一致的字段
+type FareScenarioDiff struct {
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// ParseFareScenarioSuite 解析场景文件（支持JSON和YAML）
+func ParseFareScenarioSuite(data []byte) (*FareScenarioSuite, error) {
+	trimmed := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(trimmed, "{") {
+		converted, err := yaml.YAMLToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("解析场景文件失败: %w", err)
+		}
+		data = converted
+	}
+	var suite FareScenarioSuite
+	if err := json.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("解析场景文件失败: %w", err)
+	}
+	return &suite, nil
+}
+
+type FareScenarioRunner struct {
+	db *gorm.DB
+}
+
+func NewFareScenarioRunner(db *gorm.DB) *FareScenarioRunner {
+	return &FareScenarioRunner{db: db}
+}
+
+// Run 在独立事务中执行场景集（执行结束后回滚）
+func (r *FareScenarioRunner) Run(suite *FareScenarioSuite) (*FareScenarioReport, error) {
+	if len(suite.Scenarios) == 0 {
+		return nil, fmt.Errorf("场景列表为空")
+	}
+
+	tx := r.db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("开启事务失败: %w", tx.Error)
+	}
+	defer tx.Rollback()
+
+	config := suite.Config
+	if config == nil {
+		config = &FareConfigSet{}
+	}
+	if err := shadowScenarioTables(tx, config); err != nil {
+		return nil, err
+	}
+
+	report := &FareScenarioReport{Suite: suite.Name}
+	for i, scenario := range suite.Scenarios {
+		// 每个场景执行后回滚到保存点，场景之间互不影响
+		if err := tx.SavePoint("fare_scenario").Error; err != nil {
+			return nil, fmt.Errorf("创建保存点失败: %w", err)
+		}
+		result := runFareScenario(tx, i, scenario)
+		if err := tx.RollbackTo("fare_scenario").Error; err != nil {
+			return nil, fmt.Errorf("回滚场景失败: %w", err)
+		}
+
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Scenarios = append(report.Scenarios, result)
+	}
+	return report, nil
+}
+
+// scenarioHistoryTables 场景写入的乘车记录表（以空的临时表遮蔽，场景卡片不带入历史乘车记录）
+var scenarioHistoryTables = []string{
+	"cards", "card_products", "transactions", "journeys", "tap_events", "campaign_redemptions", "monthly_aggregates",
+}
+
+// shadowScenarioTables 在场景事务中以临时表遮蔽票价配置、活动与场景写入的乘车记录表
+func shadowScenarioTables(tx *gorm.DB, set *FareConfigSet) error {
+	schema, err := currentSchema(tx)
+	if err != nil {
+		return err
+	}
+	if err := shadowFareConfigTables(tx, schema, set); err != nil {
+		return err
+	}
+	// 活动在计费时累加已预留用量，复制后在临时表中累加
+	if err := shadowTable(tx, schema, "campaigns", true); err != nil {
+		return err
+	}
+	for _, table := range scenarioHistoryTables {
+		if err := shadowTable(tx, schema, table, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shadowFareConfigTables 以临时表遮蔽票价配置表，并填充候选配置（未提供候选配置的表复制现有配置）
+// PostgreSQL优先在临时模式中解析表名，事务内的查询和写入只作用于临时表，实际表不被锁定或修改
+func shadowFareConfigTables(tx *gorm.DB, schema string, set *FareConfigSet) error {
+	configTables := []struct {
+		table string
+		rows  interface{}
+		count int
+		set   bool
+	}{
+		{"fares", &set.Fares, len(set.Fares), set.Fares != nil},
+		{"transfers", &set.Transfers, len(set.Transfers), set.Transfers != nil},
+		{"discount_policies", &set.DiscountPolicies, len(set.DiscountPolicies), set.DiscountPolicies != nil},
+		{"discount_stacking_policies", &set.DiscountStackingPolicies, len(set.DiscountStackingPolicies), set.DiscountStackingPolicies != nil},
+	}
+	for _, config := range configTables {
+		if err := shadowTable(tx, schema, config.table, !config.set); err != nil {
+			return err
+		}
+		if config.count > 0 {
+			if err := tx.Create(config.rows).Error; err != nil {
+				return fmt.Errorf("写入候选%s失败: %w", config.table, err)
+			}
+		}
+	}
+	return nil
+}
+
+// currentSchema 实际表所在的数据库模式
+func currentSchema(tx *gorm.DB) (string, error) {
+	var schema string
+	if err := tx.Raw("SELECT current_schema()").Scan(&schema).Error; err != nil {
+		return "", fmt.Errorf("查询数据库模式失败: %w", err)
+	}
+	return schema, nil
+}
+
+// shadowTable 创建与实际表结构相同的临时表（ID使用临时表自己的序列，不占用实际表的序列），copyRows为true时复制现有数据
+func shadowTable(tx *gorm.DB, schema, table string, copyRows bool) error {
+	statements := []string{
+		fmt.Sprintf("CREATE TEMP TABLE %s (LIKE %s.%s INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING INDEXES) ON COMMIT DROP", table, schema, table),
+		fmt.Sprintf("ALTER TABLE pg_temp.%s ALTER COLUMN id DROP DEFAULT", table),
+		fmt.Sprintf("ALTER TABLE pg_temp.%s ALTER COLUMN id ADD GENERATED BY DEFAULT AS IDENTITY", table),
+	}
+	if copyRows {
+		statements = append(statements,
+			fmt.Sprintf("INSERT INTO pg_temp.%s SELECT * FROM %s.%s", table, schema, table),
+			fmt.Sprintf("SELECT setval(pg_get_serial_sequence('pg_temp.%s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM pg_temp.%s", table, table),
+		)
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return fmt.Errorf("创建临时表%s失败: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// runFareScenario 执行单个场景：创建场景卡片，按顺序上传刷卡记录并比对完成的乘次
+func runFareScenario(tx *gorm.DB, index int, scenario FareScenario) FareScenarioResult {
+	result := FareScenarioResult{Name: scenario.Name, CardID: scenario.CardID}
+	if result.Name == "" {
+		result.Name = fmt.Sprintf("场景%d", index+1)
+	}
+	if result.CardID == "" {
+		result.CardID = fmt.Sprintf("SCENARIO%04d", index+1)
+	}
+	cardType := scenario.CardType
+	if cardType == "" {
+		cardType = "normal"
+	}
+
+	fail := func(format string, args ...interface{}) FareScenarioResult {
+		result.Error = fmt.Sprintf(format, args...)
+		return result
+	}
+
+	// 场景卡片（已存在的卡片按场景卡类型计费，不影响其历史记录的使用）
+	var card models.Card
+	if err := tx.Where("card_id = ?", result.CardID).FirstOrInit(&card).Error; err != nil {
+		return fail("查询卡片失败: %v", err)
+	}
+	card.CardID = result.CardID
+	card.CardType = cardType
+	card.Status = "active"
+	if err := tx.Save(&card).Error; err != nil {
+		return fail("创建场景卡片失败: %v", err)
+	}
+	if scenario.MonthlySpent > 0 {
+		if err := utils.IncrementMonthlyAggregate(tx, result.CardID, scenario.MonthlySpent); err != nil {
+			return fail("写入月度累计失败: %v", err)
+		}
+	}
+
+	uploadService := NewUploadService(tx, NewFareService(tx))
+	result.Passed = true
+	for i, tap := range scenario.Taps {
+		leg := FareScenarioLegResult{Tap: i + 1, Passed: true}
+
+		var completedBefore int64
+		tx.Model(&models.Transaction{}).Where("card_id = ? AND status = 'completed'", result.CardID).Count(&completedBefore)
+
+		record := BatchRecordRequest{
+			RecordID:      fmt.Sprintf("%s_%d", result.CardID, i+1),
+			CardID:        result.CardID,
+			BoardTime:     tap.BoardTime,
+			BoardStation:  tap.BoardStation,
+			AlightTime:    tap.AlightTime,
+			AlightStation: tap.AlightStation,
+			RouteID:       tap.RouteID,
+			GatewayID:     "scenario",
+			Direction:     tap.Direction,
+			Passengers:    tap.Passengers,
+		}
+		if err := uploadService.processSingleRecord(record); err != nil {
+			leg.Passed = false
+			leg.Diffs = append(leg.Diffs, FareScenarioDiff{Field: "upload", Expected: "ok", Actual: err.Error()})
+			result.Legs = append(result.Legs, leg)
+			result.Passed = false
+			continue
+		}
+
+		var completedAfter int64
+		tx.Model(&models.Transaction{}).Where("card_id = ? AND status = 'completed'", result.CardID).Count(&completedAfter)
+		if completedAfter > completedBefore {
+			var transaction models.Transaction
+			tx.Where("card_id = ? AND status = 'completed'", result.CardID).
+				Order("updated_at DESC, id DESC").First(&transaction)
+			leg.RecordID = transaction.RecordID
+			leg.Actual = &FareScenarioFare{
+				BaseFare:       transaction.Fare,
+				DiscountAmount: transaction.DiscountAmount,
+				DiscountType:   transaction.DiscountType,
+				ActualFare:     transaction.ActualFare,
+			}
+			leg.Trace = transaction.FareTrace
+		}
+
+		if tap.Expect != nil {
+			leg.Diffs = compareScenarioFare(tap.Expect, leg.Actual, leg.Trace)
+			leg.Passed = len(leg.Diffs) == 0
+		}
+		if leg.Passed {
+			leg.Trace = nil
+		} else {
+			result.Passed = false
+		}
+		result.Legs = append(result.Legs, leg)
+	}
+	return result
+}
+
+// compareScenarioFare 比对期望与实际计费结果
+func compareScenarioFare(expect *FareScenarioExpectation, actual *FareScenarioFare, trace models.FareTrace) []FareScenarioDiff {
+	if actual == nil {
+		return []FareScenarioDiff{{Field: "leg", Expected: "completed", Actual: "本次刷卡未完成乘次"}}
+	}
+
+	var diffs []FareScenarioDiff
+	compareMoney := func(field string, expected *models.Money, value models.Money) {
+		if expected != nil && *expected != value {
+			diffs = append(diffs, FareScenarioDiff{Field: field, Expected: expected.String(), Actual: value.String()})
+		}
+	}
+	compareMoney("base_fare", expect.BaseFare, actual.BaseFare)
+	compareMoney("discount_amount", expect.DiscountAmount, actual.DiscountAmount)
+	compareMoney("actual_fare", expect.ActualFare, actual.ActualFare)
+	if expect.DiscountType != nil && *expect.DiscountType != actual.DiscountType {
+		diffs = append(diffs, FareScenarioDiff{Field: "discount_type", Expected: *expect.DiscountType, Actual: actual.DiscountType})
+	}
+
+	for _, rule := range expect.Rules {
+		found := false
+		for _, step := range trace {
+			if step.Rule == rule {
+				found = true
+				break
+			}
+		}
+		if !found {
+			diffs = append(diffs, FareScenarioDiff{Field: "rules", Expected: rule, Actual: "未命中"})
+		}
+	}
+	return diffs
+}
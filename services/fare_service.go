@@ -1,237 +1,175 @@
 package services
 
 import (
+	"TapTransit-backend/config"
 	"TapTransit-backend/models"
-	"TapTransit-backend/utils"
 	"fmt"
+	"log"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type FareService struct {
-	db *gorm.DB
-}
+	db       *gorm.DB
+	pipeline *FarePipeline
 
-func NewFareService(db *gorm.DB) *FareService {
-	return &FareService{db: db}
+	rounding          roundingPolicy            // 默认舍入规则
+	operatorRoundings map[string]roundingPolicy // 按运营方配置的舍入规则
+
+	defaultMaxRideMinutes int  // single_tap线路默认最长乘车时间（分钟）
+	inferAlightStation    bool // single_tap上一程是否推断下车站点
 }
 
-// CalculateFare 计算单次乘车费用
-func (s *FareService) CalculateFare(cardID string, routeID uint, startStationID, endStationID uint, boardTime time.Time) (*FareCalculationResult, error) {
-	result := &FareCalculationResult{
-		BaseFare:       0,
-		DiscountAmount: 0,
-		DiscountType:   "",
-		ActualFare:     0,
-	}
+// roundingPolicy 舍入规则
+type roundingPolicy struct {
+	Mode models.RoundingMode // 舍入模式（按比例计算优惠与实收金额舍入时使用）
+	Unit models.Money        // 实收金额舍入单位
+}
 
-	// 1. 计算基础票价
-	baseFare, err := s.calculateBaseFare(routeID, startStationID, endStationID)
-	if err != nil {
-		return nil, fmt.Errorf("计算基础票价失败: %w", err)
-	}
-	result.BaseFare = baseFare
-	result.ActualFare = baseFare
-
-	// 2. 检查换乘优惠
-	transferDiscount, transferType, err := s.checkTransferDiscount(cardID, routeID, startStationID, boardTime, baseFare)
-	if err == nil && transferDiscount > 0 {
-		result.DiscountAmount += transferDiscount
-		result.DiscountType = transferType
-		result.ActualFare -= transferDiscount
-		if result.ActualFare < 0 {
-			result.ActualFare = 0
-		}
-	}
+// defaultMaxRideMinutes 系统默认最长乘车时间（分钟）
+const defaultMaxRideMinutes = 60
 
-	// 3. 检查月度累计折扣
-	monthlyDiscount, monthlyType, err := s.checkMonthlyDiscount(cardID, baseFare)
-	if err == nil && monthlyDiscount > 0 {
-		// 如果已有换乘优惠，月度折扣在基础票价上计算，但实际优惠金额需要考虑已享受的换乘优惠
-		discountAmount := baseFare * monthlyDiscount
-		result.DiscountAmount += discountAmount
-		if result.DiscountType != "" {
-			result.DiscountType += "," + monthlyType
-		} else {
-			result.DiscountType = monthlyType
+func NewFareService(db *gorm.DB) *FareService {
+	s := &FareService{
+		db:                    db,
+		rounding:              roundingPolicy{Mode: models.RoundDown, Unit: 1},
+		operatorRoundings:     make(map[string]roundingPolicy),
+		defaultMaxRideMinutes: defaultMaxRideMinutes,
+	}
+	if config.AppConfig != nil {
+		s.rounding = parseRoundingPolicy(config.AppConfig.Fare.RoundingMode, config.AppConfig.Fare.RoundingUnit, s.rounding)
+		for operator, operatorConfig := range config.AppConfig.Fare.Operators {
+			s.operatorRoundings[operator] = parseRoundingPolicy(operatorConfig.RoundingMode, operatorConfig.RoundingUnit, s.rounding)
 		}
-		result.ActualFare -= discountAmount
-		if result.ActualFare < 0 {
-			result.ActualFare = 0
+		if config.AppConfig.Fare.DefaultMaxRideMinutes > 0 {
+			s.defaultMaxRideMinutes = config.AppConfig.Fare.DefaultMaxRideMinutes
 		}
+		s.inferAlightStation = config.AppConfig.Fare.InferAlightStation
 	}
+	s.pipeline = s.defaultFarePipeline()
+	return s
+}
 
-	// 4. 检查其他优惠（学生卡、老人卡等）
-	cardDiscount, cardType, err := s.checkCardTypeDiscount(cardID, baseFare)
-	if err == nil && cardDiscount > 0 {
-		discountAmount := baseFare * cardDiscount
-		result.DiscountAmount += discountAmount
-		if result.DiscountType != "" {
-			result.DiscountType += "," + cardType
+// parseRoundingPolicy 解析舍入配置（未配置或配置错误的项使用fallback）
+func parseRoundingPolicy(modeValue string, unit int64, fallback roundingPolicy) roundingPolicy {
+	policy := fallback
+	if modeValue != "" {
+		mode, err := models.ParseRoundingMode(modeValue)
+		if err != nil {
+			log.Printf("计费舍入模式配置错误（使用%s）: %v", fallback.Mode, err)
 		} else {
-			result.DiscountType = cardType
-		}
-		result.ActualFare -= discountAmount
-		if result.ActualFare < 0 {
-			result.ActualFare = 0
+			policy.Mode = mode
 		}
 	}
-
-	return result, nil
+	if unit > 0 {
+		policy.Unit = models.Money(unit)
+	}
+	return policy
 }
 
-// calculateBaseFare 计算基础票价
-func (s *FareService) calculateBaseFare(routeID uint, startStationID, endStationID uint) (float64, error) {
-	// 先查找特定线路和站点的票价规则
-	var fare models.Fare
-	err := s.db.Where("route_id = ? AND ((start_station = ? AND end_station = ?) OR (start_station = 0 AND end_station = 0)) AND status = 'active'",
-		routeID, startStationID, endStationID).
-		Order("start_station DESC"). // 优先使用具体站点规则
-		First(&fare).Error
-
-	if err == nil {
-		if fare.FareType == "uniform" {
-			return fare.BasePrice, nil
-		} else if fare.FareType == "segment" {
-			// 分段计价：需要计算站点间距离
-			segmentCount := s.calculateSegmentCount(routeID, startStationID, endStationID)
-			if segmentCount <= 0 {
-				return fare.BasePrice, nil
-			}
-			if segmentCount == 1 {
-				return fare.BasePrice, nil
-			}
-			// 起步价 + (段数-1) * 续程价
-			return fare.BasePrice + float64(segmentCount-1)*fare.ExtraPrice, nil
+// roundingFor 返回线路所属运营方的舍入规则（运营方未单独配置时使用默认规则）
+func (s *FareService) roundingFor(route *models.Route) roundingPolicy {
+	if route != nil && route.Operator != "" {
+		if policy, ok := s.operatorRoundings[route.Operator]; ok {
+			return policy
 		}
 	}
-
-	// 如果找不到特定规则，使用线路的默认统一票价
-	err = s.db.Where("route_id = ? AND start_station = 0 AND end_station = 0 AND status = 'active'", routeID).
-		First(&fare).Error
-	if err == nil {
-		return fare.BasePrice, nil
-	}
-
-	// 如果还没有，使用系统默认票价
-	return 2.0, nil // 默认2元
+	return s.rounding
 }
 
-// calculateSegmentCount 计算两个站点间的区段数
-func (s *FareService) calculateSegmentCount(routeID uint, startStationID, endStationID uint) int {
-	var startRS, endRS models.RouteStation
-	s.db.Where("route_id = ? AND station_id = ?", routeID, startStationID).First(&startRS)
-	s.db.Where("route_id = ? AND station_id = ?", routeID, endStationID).First(&endRS)
-
-	if startRS.ID == 0 || endRS.ID == 0 {
-		return 0
-	}
-
-	diff := endRS.Sequence - startRS.Sequence
-	if diff < 0 {
-		diff = -diff
-	}
-	return diff
+// Pipeline 返回计费流水线（可注册新的计费阶段）
+func (s *FareService) Pipeline() *FarePipeline {
+	return s.pipeline
 }
 
-// checkTransferDiscount 检查换乘优惠
-func (s *FareService) checkTransferDiscount(cardID string, routeID uint, stationID uint, boardTime time.Time, baseFare float64) (float64, string, error) {
-	// 从Redis获取最近一次下车信息
-	onboardInfo, err := utils.GetCardOnboardInfo(cardID)
-	if err != nil {
-		return 0, "", nil // 没有上车记录，不是换乘
-	}
-
-	// 解析上车信息（格式：routeID:stationID:timestamp）
-	var prevRouteID, prevStationID uint
-	var prevTimestamp int64
-	_, err = fmt.Sscanf(onboardInfo, "%d:%d:%d", &prevRouteID, &prevStationID, &prevTimestamp)
-	if err != nil {
-		return 0, "", nil
-	}
-
-	prevAlightTime := time.Unix(prevTimestamp, 0)
+// FareRequest 计费请求
+type FareRequest struct {
+	CardID         string    // 卡片ID
+	RouteID        uint      // 线路ID
+	StartStationID uint      // 上车站点ID
+	EndStationID   *uint     // 下车站点ID（single_tap或缺少下车刷卡时为nil）
+	BoardTime      time.Time // 上车时间
+	PenaltyFare    bool      // 是否为罚款计费
+	Direction      string    // 行驶方向（网关上报，可为空，为空时按刷卡先后与站序推断）
 
-	// 查找换乘规则
-	var transfer models.Transfer
-	err = s.db.Where("from_route_id = ? AND from_station_id = ? AND to_route_id = ? AND to_station_id = ? AND status = 'active'",
-		prevRouteID, prevStationID, routeID, stationID).
-		First(&transfer).Error
+	CardType    string       // 指定卡类型（为空时使用卡片的卡类型，用于报价等无卡场景）
+	PreviousLeg *PreviousLeg // 指定上一程（为nil时从交易记录查询，用于报价与模拟）
 
-	if err != nil {
-		return 0, "", nil // 没有匹配的换乘规则
-	}
+	MonthlySpent      *models.Money // 指定当月已累计消费（为nil时读取卡片的月度累计，用于模拟）
+	ExcludedCampaigns []uint        // 不参与计费的活动ID（活动预算或次数已被并发计费用完时重新计费）
+	SkipCampaigns     bool          // 不参与优惠活动（同行乘客计费时使用，活动用量只按持卡人预留与核销）
 
-	// 检查时间窗口
-	if boardTime.Sub(prevAlightTime).Minutes() > float64(transfer.TimeWindow) {
-		return 0, "", nil // 超过时间窗口
-	}
+	Passengers []PassengerGroup // 同行乘客（持卡人之外由同一张卡付费的乘客）
+}
 
-	// 计算优惠金额
-	if transfer.DiscountRate > 0 {
-		// 使用折扣比例
-		discountAmount := baseFare * transfer.DiscountRate
-		return discountAmount, "transfer", nil
-	}
-	return transfer.DiscountAmount, "transfer", nil
+// PreviousLeg 上一程乘车信息（用于换乘判断）
+type PreviousLeg struct {
+	RecordID   string    // 上一程交易记录ID（报价时为空）
+	RouteID    uint      // 线路ID
+	EndStation uint      // 下车站点ID（single_tap上一程未推断下车站点时为0）
+	AlightTime time.Time // 下车时间（single_tap上一程为按最长乘车时间估算的下车时间）
+
+	BoardTime       time.Time // 上车时间
+	AlightEstimated bool      // 下车时间是否为估算（single_tap上一程）
+	StationInferred bool      // 下车站点是否为推断
+
+	JourneyID           string    // 上一程所属行程ID（为空时以上一程为行程首程）
+	JourneyStart        time.Time // 行程首程上车时间（为零值时取上一程下车时间）
+	JourneyLegs         int       // 行程已有乘次（为0时按1计）
+	DiscountedTransfers int       // 行程已享受换乘优惠的次数
 }
 
-// checkMonthlyDiscount 检查月度累计折扣（使用数据库）
-func (s *FareService) checkMonthlyDiscount(cardID string, currentAmountAfterDiscounts float64) (float64, string, error) {
-	// 从数据库获取当月累计金额（不包含本次交易）
-	currentAmount, err := utils.GetCurrentMonthAggregate(s.db, cardID)
-	if err != nil {
-		// 查询失败时返回0折扣，不影响主流程
-		return 0, "", nil
+// Calculate 计算单次乘车费用（唯一计费入口，依次执行计费流水线中的各阶段）
+func (s *FareService) Calculate(req FareRequest) (*FareCalculationResult, error) {
+	fc := &FareContext{
+		Request: req,
+		Result:  &FareCalculationResult{},
 	}
 
-	// 查询折扣策略
-	var policies []models.DiscountPolicy
-	s.db.Where("policy_type = 'monthly_accumulate' AND status = 'active'").
-		Order("threshold DESC"). // 从高到低排序
-		Find(&policies)
-
-	// 注意：设计文档要求按"扣除特殊票种和换乘优惠后的金额"累计
-	// 这里传入的currentAmountAfterDiscounts是本次交易扣除优惠后的金额，用于判断本次是否触发阈值
-	// currentAmount是之前已累计的金额
-
-	// 计算累计金额（之前累计 + 本次应付金额）
-	totalAmount := currentAmount + currentAmountAfterDiscounts
-
-	for _, policy := range policies {
-		if totalAmount >= policy.Threshold {
-			return policy.DiscountRate, "monthly_discount", nil
-		}
+	// 获取线路信息
+	if err := s.db.First(&fc.Route, req.RouteID).Error; err != nil {
+		return nil, fmt.Errorf("线路不存在: %w", err)
 	}
 
-	return 0, "", nil
-}
-
-// checkCardTypeDiscount 检查卡类型折扣（学生卡、老人卡等）
-func (s *FareService) checkCardTypeDiscount(cardID string, baseFare float64) (float64, string, error) {
+	// 获取卡片信息（卡片不存在时按普通卡计费）
 	var card models.Card
-	err := s.db.Where("card_id = ?", cardID).First(&card).Error
-	if err != nil {
-		return 0, "", nil
+	if err := s.db.Where("card_id = ?", req.CardID).First(&card).Error; err == nil {
+		fc.Card = &card
 	}
 
-	var policy models.DiscountPolicy
-	err = s.db.Where("policy_type = ? AND (card_type_filter = ? OR card_type_filter = '') AND status = 'active'",
-		card.CardType, card.CardType).
-		First(&policy).Error
-
-	if err != nil {
-		return 0, "", nil
+	if err := s.pipeline.Run(fc); err != nil {
+		return nil, err
+	}
+	fc.Result.CardType = fc.CardType()
+	if fc.Result.CardType == "" {
+		fc.Result.CardType = "normal"
+	}
+	if fc.Result.PassengerCount == 0 {
+		fc.Result.PassengerCount = 1
 	}
-	return policy.DiscountRate, card.CardType + "_discount", nil
+	return fc.Result, nil
 }
 
 // FareCalculationResult 计费结果
 type FareCalculationResult struct {
-	BaseFare       float64 `json:"base_fare"`       // 基础票价
-	DiscountAmount float64 `json:"discount_amount"` // 优惠金额
-	DiscountType   string  `json:"discount_type"`   // 优惠类型
-	ActualFare     float64 `json:"actual_fare"`     // 实收金额
-	PenaltyFare    bool    `json:"penalty_fare"`    // 是否为罚款计费
+	BaseFare       models.Money `json:"base_fare"`         // 基础票价
+	DiscountAmount models.Money `json:"discount_amount"`   // 优惠金额
+	DiscountType   string       `json:"discount_type"`     // 优惠类型
+	ActualFare     models.Money `json:"actual_fare"`       // 实收金额
+	PenaltyFare    bool         `json:"penalty_fare"`      // 是否为罚款计费
+	CardType       string       `json:"card_type"`         // 计费使用的卡类型
+	Direction      string       `json:"direction"`         // 计价使用的行驶方向
+	JourneyID      string       `json:"journey_id"`        // 所属行程ID
+	JourneyLeg     int          `json:"journey_leg"`       // 本程是行程的第几程
+	PassID         *uint        `json:"pass_id,omitempty"` // 使用的票卡（card_products.id）
+	PassActivation bool         `json:"pass_activation"`   // 本程是否激活待激活的票卡（由上传处理时写入）
+
+	CampaignID       *uint        `json:"campaign_id,omitempty"` // 命中的优惠活动
+	CampaignDiscount models.Money `json:"campaign_discount"`     // 活动优惠金额
+
+	PassengerCount int                     `json:"passenger_count"`      // 乘客人数（含持卡人）
+	Passengers     models.GroupComposition `json:"passengers,omitempty"` // 同行乘客构成（多人同行时）
+
+	Trace models.FareTrace `json:"trace"` // 计费明细（各阶段的规则与金额变化）
 }
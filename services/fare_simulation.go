@@ -0,0 +1,375 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FareSimulationRequest 票价调整模拟请求
+type FareSimulationRequest struct {
+	StartDate string         `json:"start_date" binding:"required"` // 开始日期（YYYY-MM-DD）
+	EndDate   string         `json:"end_date" binding:"required"`   // 结束日期（YYYY-MM-DD，包含当天）
+	RouteIDs  []uint         `json:"route_ids"`                     // 限定线路（为空表示全部线路）
+	Config    *FareConfigSet `json:"config"`                        // 拟调整的票价配置（为空时只按现有配置重算）
+}
+
+// FareSimulationReport 票价调整模拟报告
+type FareSimulationReport struct {
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Trips     int    `json:"trips"`  // 参与模拟的乘次
+	Errors    int    `json:"errors"` // 无法重算的乘次（如线路已删除）
+
+	Recorded     models.Money `json:"recorded"`      // 实际收入（交易记录的实收金额）
+	Baseline     models.Money `json:"baseline"`      // 按现有配置重算的收入
+	Proposed     models.Money `json:"proposed"`      // 按拟调整配置重算的收入
+	Delta        models.Money `json:"delta"`         // 收入变化（拟调整 - 现有）
+	DeltaPercent float64      `json:"delta_percent"` // 收入变化百分比
+
+	ByRoute      []FareSimulationGroup  `json:"by_route"`     // 按线路汇总
+	ByCardType   []FareSimulationGroup  `json:"by_card_type"` // 按卡类型汇总
+	Distribution []FareSimulationBucket `json:"distribution"` // 单程票价变化分布
+}
+
+// FareSimulationGroup 按线路或卡类型汇总的收入变化
+type FareSimulationGroup struct {
+	Key      string       `json:"key"` // 线路ID或卡类型
+	Trips    int          `json:"trips"`
+	Baseline models.Money `json:"baseline"`
+	Proposed models.Money `json:"proposed"`
+	Delta    models.Money `json:"delta"`
+}
+
+// FareSimulationBucket 单程票价变化区间
+type FareSimulationBucket struct {
+	Range string `json:"range"` // 变化区间（元）
+	Trips int    `json:"trips"`
+}
+
+// FareSimulationTrip 单程模拟结果
+type FareSimulationTrip struct {
+	RecordID  string
+	CardID    string
+	CardType  string
+	RouteID   uint
+	BoardTime time.Time
+	Recorded  models.Money
+	Baseline  models.Money
+	Proposed  models.Money
+	Error     string
+}
+
+// fareSimulationBuckets 票价变化分布的区间（按上限从小到大，上限包含在区间内）
+var fareSimulationBuckets = []struct {
+	label string
+	max   models.Money
+}{
+	{"< -2.00", -201},
+	{"-2.00 ~ -1.01", -101},
+	{"-1.00 ~ -0.51", -51},
+	{"-0.50 ~ -0.01", -1},
+	{"0.00", 0},
+	{"0.01 ~ 0.50", 50},
+	{"0.51 ~ 1.00", 100},
+	{"1.01 ~ 2.00", 200},
+	{"> 2.00", math.MaxInt64},
+}
+
+// 模拟时间段上限与每批读取的交易记录数
+const (
+	maxSimulationDays   = 31
+	simulationBatchSize = 1000
+)
+
+type FareSimulator struct {
+	db *gorm.DB
+}
+
+func NewFareSimulator(db *gorm.DB) *FareSimulator {
+	return &FareSimulator{db: db}
+}
+
+// Simulate 将时间段内已完成的历史乘次分别按现有配置和拟调整配置重新计费，比较收入变化
+// 时间段不超过31天，交易记录分批读取并按记录顺序重放；拟调整配置写入事务内的同名临时表，结束后回滚，不写入任何数据；
+// 月度累计按重放的实收金额逐程累加（月初至开始日期前的部分取实际交易记录），通过计费请求传入，不修改月度累计表；
+// 报告只保留汇总，onTrip不为nil时逐程回调单程结果（用于流式导出逐程明细，回调返回错误时中止模拟）
+func (s *FareSimulator) Simulate(req FareSimulationRequest, onTrip func(FareSimulationTrip) error) (*FareSimulationReport, error) {
+	start, err := time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("开始日期格式错误，应为YYYY-MM-DD")
+	}
+	end, err := time.ParseInLocation("2006-01-02", req.EndDate, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("结束日期格式错误，应为YYYY-MM-DD")
+	}
+	end = end.Add(24 * time.Hour)
+	if !end.After(start) {
+		return nil, fmt.Errorf("结束日期不能早于开始日期")
+	}
+	if end.Sub(start) > maxSimulationDays*24*time.Hour {
+		return nil, fmt.Errorf("模拟时间段不能超过%d天", maxSimulationDays)
+	}
+
+	baselineService := NewFareService(s.db)
+	proposedService := baselineService
+	if req.Config != nil {
+		tx := s.db.Begin()
+		if tx.Error != nil {
+			return nil, fmt.Errorf("开启事务失败: %w", tx.Error)
+		}
+		defer tx.Rollback()
+		schema, err := currentSchema(tx)
+		if err != nil {
+			return nil, err
+		}
+		if err := shadowFareConfigTables(tx, schema, req.Config); err != nil {
+			return nil, err
+		}
+		proposedService = NewFareService(tx)
+	}
+
+	replay := &simulationReplay{db: s.db, start: start, prior: make(map[string]models.Money)}
+	baselineMonthly := make(map[string]models.Money)
+	proposedMonthly := make(map[string]models.Money)
+
+	report := &FareSimulationReport{StartDate: req.StartDate, EndDate: req.EndDate}
+	byRoute := make(map[string]*FareSimulationGroup)
+	byCardType := make(map[string]*FareSimulationGroup)
+	buckets := make([]int, len(fareSimulationBuckets))
+
+	query := s.db.Where("status = 'completed' AND board_time >= ? AND board_time < ?", start, end)
+	if len(req.RouteIDs) > 0 {
+		query = query.Where("route_id IN ?", req.RouteIDs)
+	}
+	var batch []models.Transaction
+	err = query.FindInBatches(&batch, simulationBatchSize, func(_ *gorm.DB, _ int) error {
+		for _, t := range batch {
+			baseline := replay.replayTrip(baselineService, baselineMonthly, t)
+			proposed := baseline
+			if req.Config != nil {
+				proposed = replay.replayTrip(proposedService, proposedMonthly, t)
+			}
+
+			trip := FareSimulationTrip{
+				RecordID:  t.RecordID,
+				CardID:    t.CardID,
+				RouteID:   t.RouteID,
+				BoardTime: t.BoardTime,
+				Recorded:  t.ActualFare,
+			}
+			if baseline.err != nil || proposed.err != nil {
+				report.Errors++
+				if baseline.err != nil {
+					trip.Error = baseline.err.Error()
+				} else {
+					trip.Error = proposed.err.Error()
+				}
+				if onTrip != nil {
+					if err := onTrip(trip); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			trip.CardType = baseline.cardType
+			trip.Baseline = baseline.fare
+			trip.Proposed = proposed.fare
+			if onTrip != nil {
+				if err := onTrip(trip); err != nil {
+					return err
+				}
+			}
+
+			report.Trips++
+			report.Recorded += trip.Recorded
+			report.Baseline += trip.Baseline
+			report.Proposed += trip.Proposed
+			addSimulationGroup(byRoute, strconv.FormatUint(uint64(trip.RouteID), 10), trip)
+			addSimulationGroup(byCardType, trip.CardType, trip)
+			buckets[simulationBucket(trip.Proposed-trip.Baseline)]++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询交易记录失败: %w", err)
+	}
+
+	report.Delta = report.Proposed - report.Baseline
+	if report.Baseline > 0 {
+		report.DeltaPercent = float64(report.Delta) / float64(report.Baseline) * 100
+	}
+	report.ByRoute = sortedSimulationGroups(byRoute)
+	report.ByCardType = sortedSimulationGroups(byCardType)
+	for i, count := range buckets {
+		report.Distribution = append(report.Distribution, FareSimulationBucket{Range: fareSimulationBuckets[i].label, Trips: count})
+	}
+	return report, nil
+}
+
+// simulatedFare 单程重算结果
+type simulatedFare struct {
+	fare     models.Money
+	cardType string
+	err      error
+}
+
+// simulationReplay 乘次重放（现有配置与拟调整配置共用开始日期前的当月实际消费）
+type simulationReplay struct {
+	db    *gorm.DB
+	start time.Time
+	prior map[string]models.Money // 卡片ID+月份 → 月初至开始日期前的实际消费
+}
+
+// replayTrip 重算单程；monthly为重放的月度累计（卡片ID+月份 → 累计实收金额），随重放逐程累加
+func (r *simulationReplay) replayTrip(fareService *FareService, monthly map[string]models.Money, t models.Transaction) simulatedFare {
+	key := t.CardID + "_" + t.BoardTime.Format("2006-01")
+	spent, ok := monthly[key]
+	if !ok {
+		spent = r.priorSpent(key, t)
+	}
+
+	result, err := fareService.Calculate(FareRequest{
+		CardID:         t.CardID,
+		RouteID:        t.RouteID,
+		StartStationID: t.StartStation,
+		EndStationID:   t.EndStation,
+		BoardTime:      t.BoardTime,
+		PenaltyFare:    t.PenaltyFare,
+		Direction:      t.Direction,
+		Passengers:     passengersFromComposition(t.GroupComposition),
+		MonthlySpent:   &spent,
+	})
+	if err != nil {
+		monthly[key] = spent
+		return simulatedFare{err: err}
+	}
+	if !result.PenaltyFare {
+		spent += result.ActualFare
+	}
+	monthly[key] = spent
+	return simulatedFare{fare: result.ActualFare, cardType: result.CardType}
+}
+
+// priorSpent 卡片当月在开始日期前的实际消费
+func (r *simulationReplay) priorSpent(key string, t models.Transaction) models.Money {
+	if spent, ok := r.prior[key]; ok {
+		return spent
+	}
+	var spent models.Money
+	monthStart := time.Date(t.BoardTime.Year(), t.BoardTime.Month(), 1, 0, 0, 0, 0, t.BoardTime.Location())
+	r.db.Model(&models.Transaction{}).
+		Select("COALESCE(SUM(actual_fare), 0)").
+		Where("card_id = ? AND status = 'completed' AND penalty_fare = false AND board_time >= ? AND board_time < ?", t.CardID, monthStart, r.start).
+		Scan(&spent)
+	r.prior[key] = spent
+	return spent
+}
+
+func addSimulationGroup(groups map[string]*FareSimulationGroup, key string, trip FareSimulationTrip) {
+	group, ok := groups[key]
+	if !ok {
+		group = &FareSimulationGroup{Key: key}
+		groups[key] = group
+	}
+	group.Trips++
+	group.Baseline += trip.Baseline
+	group.Proposed += trip.Proposed
+	group.Delta = group.Proposed - group.Baseline
+}
+
+func sortedSimulationGroups(groups map[string]*FareSimulationGroup) []FareSimulationGroup {
+	result := make([]FareSimulationGroup, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Delta != result[j].Delta {
+			return result[i].Delta < result[j].Delta
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// simulationBucket 票价变化所在的区间下标
+func simulationBucket(delta models.Money) int {
+	for i, bucket := range fareSimulationBuckets {
+		if delta <= bucket.max {
+			return i
+		}
+	}
+	return len(fareSimulationBuckets) - 1
+}
+
+// 模拟报告CSV导出视图
+const (
+	SimulationCSVTrips     = "trips"        // 逐程明细
+	SimulationCSVRoutes    = "routes"       // 按线路汇总
+	SimulationCSVCardTypes = "card_types"   // 按卡类型汇总
+	SimulationCSVBuckets   = "distribution" // 单程票价变化分布
+)
+
+// SimulationTripWriter 逐程明细CSV写出器（随模拟逐程写出，不在内存中保留逐程明细）
+type SimulationTripWriter struct {
+	writer *csv.Writer
+}
+
+// NewSimulationTripWriter 创建逐程明细CSV写出器并写入表头
+func NewSimulationTripWriter(w io.Writer) *SimulationTripWriter {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"record_id", "card_id", "card_type", "route_id", "board_time", "recorded", "baseline", "proposed", "delta", "error"})
+	return &SimulationTripWriter{writer: writer}
+}
+
+// Write 写出单程模拟结果（可直接作为Simulate的onTrip回调）
+func (w *SimulationTripWriter) Write(trip FareSimulationTrip) error {
+	delta := ""
+	if trip.Error == "" {
+		delta = (trip.Proposed - trip.Baseline).String()
+	}
+	return w.writer.Write([]string{
+		trip.RecordID, trip.CardID, trip.CardType,
+		strconv.FormatUint(uint64(trip.RouteID), 10),
+		trip.BoardTime.Format(time.RFC3339),
+		trip.Recorded.String(), trip.Baseline.String(), trip.Proposed.String(), delta, trip.Error,
+	})
+}
+
+// Flush 写出缓冲的记录
+func (w *SimulationTripWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// WriteCSV 按视图导出模拟报告的汇总（逐程明细由SimulationTripWriter在模拟时写出）
+func (r *FareSimulationReport) WriteCSV(w io.Writer, view string) error {
+	writer := csv.NewWriter(w)
+	switch view {
+	case SimulationCSVRoutes, SimulationCSVCardTypes:
+		groups, keyName := r.ByRoute, "route_id"
+		if view == SimulationCSVCardTypes {
+			groups, keyName = r.ByCardType, "card_type"
+		}
+		writer.Write([]string{keyName, "trips", "baseline", "proposed", "delta"})
+		for _, group := range groups {
+			writer.Write([]string{group.Key, strconv.Itoa(group.Trips), group.Baseline.String(), group.Proposed.String(), group.Delta.String()})
+		}
+	case SimulationCSVBuckets:
+		writer.Write([]string{"range", "trips"})
+		for _, bucket := range r.Distribution {
+			writer.Write([]string{bucket.Range, strconv.Itoa(bucket.Trips)})
+		}
+	default:
+		return fmt.Errorf("不支持的导出视图: %s（应为routes、card_types或distribution，逐程明细使用trips视图在模拟时导出）", view)
+	}
+	writer.Flush()
+	return writer.Error()
+}
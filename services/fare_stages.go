@@ -0,0 +1,252 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"TapTransit-backend/utils"
+	"fmt"
+)
+
+// 计费阶段名称
+const (
+	StageBaseFare  = "base_fare"
+	StagePenalty   = "penalty"
+	StageDiscounts = "discounts"
+	StageCap       = "cap"
+	StageRounding  = "rounding"
+)
+
+// defaultFarePipeline 默认计费流水线
+// 计算顺序：基础票价 → 罚款计费 → 行程（换乘判断） → 票卡产品（月票等） → 优惠（优惠活动、特殊票种、换乘、月度折扣，按叠加策略组合） → 封顶 → 舍入 → 同行乘客
+func (s *FareService) defaultFarePipeline() *FarePipeline {
+	return NewFarePipeline(
+		&baseFareStage{fareService: s},
+		&penaltyStage{},
+		&journeyStage{fareService: s},
+		&passStage{fareService: s},
+		newDiscountStage(s,
+			&campaignRule{fareService: s},
+			&concessionRule{fareService: s},
+			&transferRule{fareService: s},
+			&monthlyTierRule{fareService: s},
+		),
+		&capStage{},
+		&roundingStage{fareService: s},
+		&passengerStage{fareService: s},
+	)
+}
+
+// baseFareStage 基础票价阶段（按线路计价模式计算）
+type baseFareStage struct {
+	fareService *FareService
+}
+
+func (st *baseFareStage) Name() string { return StageBaseFare }
+
+func (st *baseFareStage) Apply(fc *FareContext) error {
+	baseFare, source, err := st.fareService.calculateBaseFare(&fc.Route, fc.Request.StartStationID, fc.Request.EndStationID, fc.Request.Direction)
+	if err != nil {
+		return err
+	}
+	fc.Result.BaseFare = baseFare
+	fc.Result.ActualFare = baseFare
+	fc.Result.Direction = source.Direction
+	if fc.Result.Direction == "" {
+		fc.Result.Direction = fc.Request.Direction
+	}
+	fc.AddTrace(models.FareTraceStep{
+		Stage:       StageBaseFare,
+		Rule:        source.Rule,
+		Description: fmt.Sprintf("线路计价模式%s，按%s计价", fc.Route.FareType, source.Method),
+		Before:      0,
+		After:       baseFare,
+		Details:     source.Details,
+	})
+	return nil
+}
+
+// penaltyStage 罚款计费阶段（缺少下车刷卡时按max_fare计费，不享受任何优惠）
+type penaltyStage struct{}
+
+func (st *penaltyStage) Name() string { return StagePenalty }
+
+func (st *penaltyStage) Apply(fc *FareContext) error {
+	if !fc.Request.PenaltyFare {
+		return nil
+	}
+	fc.Result.PenaltyFare = true
+	before := fc.Result.ActualFare
+	if fc.Route.MaxFare > 0 {
+		fc.Result.BaseFare = fc.Route.MaxFare
+		fc.Result.ActualFare = fc.Route.MaxFare
+	}
+	fc.AddTrace(models.FareTraceStep{
+		Stage:       StagePenalty,
+		Rule:        "route.max_fare",
+		Description: "缺少下车刷卡，按线路最高票价计费且不享受优惠",
+		Before:      before,
+		After:       fc.Result.ActualFare,
+	})
+	return nil
+}
+
+// DiscountRule 优惠规则（由优惠阶段按优惠叠加策略组合应用）
+type DiscountRule interface {
+	// Kind 优惠类别（对应叠加策略中的apply_order/exclusive_groups）
+	Kind() string
+	// Evaluate 按当前应付金额计算优惠（不适用时返回零值）
+	Evaluate(fc *FareContext, currentFare models.Money) Discount
+}
+
+// discountStage 优惠阶段（按线路的优惠叠加策略组合各项优惠规则）
+type discountStage struct {
+	fareService *FareService
+	rules       map[string]DiscountRule
+}
+
+func newDiscountStage(fareService *FareService, rules ...DiscountRule) *discountStage {
+	stage := &discountStage{fareService: fareService, rules: make(map[string]DiscountRule)}
+	for _, rule := range rules {
+		stage.AddRule(rule)
+	}
+	return stage
+}
+
+// AddRule 注册优惠规则（同类别规则会被替换）
+func (st *discountStage) AddRule(rule DiscountRule) {
+	st.rules[rule.Kind()] = rule
+}
+
+func (st *discountStage) Name() string { return StageDiscounts }
+
+func (st *discountStage) Apply(fc *FareContext) error {
+	if fc.Result.PenaltyFare {
+		return nil
+	}
+	applyStackedDiscounts(st.fareService.getStackingPolicy(&fc.Route), st.rulesFor(fc), fc)
+	return nil
+}
+
+// rulesFor 本次计费参与叠加的优惠规则（指定不参与优惠活动时去掉活动规则）
+func (st *discountStage) rulesFor(fc *FareContext) map[string]DiscountRule {
+	if !fc.Request.SkipCampaigns {
+		return st.rules
+	}
+	rules := make(map[string]DiscountRule, len(st.rules))
+	for kind, rule := range st.rules {
+		if kind != DiscountKindCampaign {
+			rules[kind] = rule
+		}
+	}
+	return rules
+}
+
+// concessionRule 特殊票种优惠（学生、长者、爱心卡等）
+type concessionRule struct {
+	fareService *FareService
+}
+
+func (r *concessionRule) Kind() string { return DiscountKindCardType }
+
+func (r *concessionRule) Evaluate(fc *FareContext, currentFare models.Money) Discount {
+	if fc.CardType() == "" {
+		return Discount{}
+	}
+	return r.fareService.checkCardTypeDiscount(fc.CardType(), currentFare, r.fareService.roundingFor(&fc.Route).Mode)
+}
+
+// transferRule 换乘优惠
+type transferRule struct {
+	fareService *FareService
+}
+
+func (r *transferRule) Kind() string { return DiscountKindTransfer }
+
+func (r *transferRule) Evaluate(fc *FareContext, currentFare models.Money) Discount {
+	if fc.Journey == nil || fc.Journey.Transfer == nil || !fc.Journey.DiscountEligible {
+		return Discount{}
+	}
+	return r.fareService.transferDiscount(fc.Journey, currentFare, r.fareService.roundingFor(&fc.Route).Mode)
+}
+
+// monthlyTierRule 月度累计阶梯折扣
+type monthlyTierRule struct {
+	fareService *FareService
+}
+
+func (r *monthlyTierRule) Kind() string { return DiscountKindMonthly }
+
+func (r *monthlyTierRule) Evaluate(fc *FareContext, currentFare models.Money) Discount {
+	spent := fc.Request.MonthlySpent
+	if spent == nil {
+		if fc.Request.CardID == "" {
+			return Discount{}
+		}
+		amount, err := utils.GetCurrentMonthAggregate(r.fareService.db, fc.Request.CardID)
+		if err != nil {
+			return Discount{}
+		}
+		spent = &amount
+	}
+	return checkMonthlyDiscount(*spent, currentFare, r.fareService.roundingFor(&fc.Route).Mode)
+}
+
+// capStage 封顶阶段（实收金额不超过线路max_fare）
+type capStage struct{}
+
+func (st *capStage) Name() string { return StageCap }
+
+func (st *capStage) Apply(fc *FareContext) error {
+	if fc.Route.MaxFare > 0 && fc.Result.ActualFare > fc.Route.MaxFare {
+		before := fc.Result.ActualFare
+		fc.Result.ActualFare = fc.Route.MaxFare
+		fc.AddTrace(models.FareTraceStep{
+			Stage:       StageCap,
+			Rule:        "route.max_fare",
+			Description: fmt.Sprintf("实收金额超过线路最高票价，封顶为%s元", fc.Route.MaxFare),
+			Before:      before,
+			After:       fc.Result.ActualFare,
+		})
+	}
+	return nil
+}
+
+// roundingStage 舍入阶段（按运营方配置的舍入模式和舍入单位处理实收金额，舍入后超过线路max_fare时再次封顶）
+type roundingStage struct {
+	fareService *FareService
+}
+
+func (st *roundingStage) Name() string { return StageRounding }
+
+func (st *roundingStage) Apply(fc *FareContext) error {
+	before := fc.Result.ActualFare
+	if fc.Result.ActualFare < 0 {
+		fc.Result.ActualFare = 0
+	}
+	rounding := st.fareService.roundingFor(&fc.Route)
+	fc.Result.ActualFare = fc.Result.ActualFare.RoundTo(rounding.Unit, rounding.Mode)
+	fc.AddTrace(models.FareTraceStep{
+		Stage:       StageRounding,
+		Description: fmt.Sprintf("按%s模式舍入到%s元", rounding.Mode, rounding.Unit),
+		Before:      before,
+		After:       fc.Result.ActualFare,
+		Details: map[string]interface{}{
+			"operator":      fc.Route.Operator,
+			"rounding_mode": rounding.Mode,
+			"rounding_unit": rounding.Unit,
+		},
+	})
+
+	// 向上舍入可能使已封顶的金额重新超过max_fare，封顶优先
+	if fc.Route.MaxFare > 0 && fc.Result.ActualFare > fc.Route.MaxFare {
+		rounded := fc.Result.ActualFare
+		fc.Result.ActualFare = fc.Route.MaxFare
+		fc.AddTrace(models.FareTraceStep{
+			Stage:       StageCap,
+			Rule:        "route.max_fare",
+			Description: fmt.Sprintf("舍入后超过线路最高票价，再次封顶为%s元", fc.Route.MaxFare),
+			Before:      rounded,
+			After:       fc.Result.ActualFare,
+		})
+	}
+	return nil
+}
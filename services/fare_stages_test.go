@@ -0,0 +1,117 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"reflect"
+	"testing"
+)
+
+func TestCapAndRoundingOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		rounding  roundingPolicy
+		operators map[string]roundingPolicy
+		route     models.Route
+		fare      models.Money
+		want      models.Money
+		wantSteps []string
+	}{
+		{
+			name:      "先封顶再舍入",
+			rounding:  roundingPolicy{Mode: models.RoundDown, Unit: 50},
+			route:     models.Route{MaxFare: 500},
+			fare:      620,
+			want:      500,
+			wantSteps: []string{StageCap, StageRounding},
+		},
+		{
+			name:      "封顶后的金额按舍入单位向下舍入",
+			rounding:  roundingPolicy{Mode: models.RoundDown, Unit: 100},
+			route:     models.Route{MaxFare: 450},
+			fare:      620,
+			want:      400,
+			wantSteps: []string{StageCap, StageRounding},
+		},
+		{
+			name:      "向上舍入超过最高票价时再次封顶",
+			rounding:  roundingPolicy{Mode: models.RoundHalfUp, Unit: 50},
+			route:     models.Route{MaxFare: 480},
+			fare:      476,
+			want:      480,
+			wantSteps: []string{StageRounding, StageCap},
+		},
+		{
+			name:      "封顶后向上舍入仍以最高票价为准",
+			rounding:  roundingPolicy{Mode: models.RoundHalfUp, Unit: 100},
+			route:     models.Route{MaxFare: 480},
+			fare:      600,
+			want:      480,
+			wantSteps: []string{StageCap, StageRounding, StageCap},
+		},
+		{
+			name:      "未设置最高票价只舍入",
+			rounding:  roundingPolicy{Mode: models.RoundHalfEven, Unit: 10},
+			route:     models.Route{},
+			fare:      445,
+			want:      440,
+			wantSteps: []string{StageRounding},
+		},
+		{
+			name:      "负数金额按0处理",
+			rounding:  roundingPolicy{Mode: models.RoundHalfUp, Unit: 10},
+			route:     models.Route{MaxFare: 300},
+			fare:      -30,
+			want:      0,
+			wantSteps: []string{StageRounding},
+		},
+		{
+			name:      "使用运营方的舍入规则",
+			rounding:  roundingPolicy{Mode: models.RoundDown, Unit: 1},
+			operators: map[string]roundingPolicy{"metro": {Mode: models.RoundHalfUp, Unit: 100}},
+			route:     models.Route{Operator: "metro"},
+			fare:      250,
+			want:      300,
+			wantSteps: []string{StageRounding},
+		},
+		{
+			name:      "运营方未单独配置时使用默认规则",
+			rounding:  roundingPolicy{Mode: models.RoundDown, Unit: 100},
+			operators: map[string]roundingPolicy{"metro": {Mode: models.RoundHalfUp, Unit: 100}},
+			route:     models.Route{Operator: "bus"},
+			fare:      250,
+			want:      200,
+			wantSteps: []string{StageRounding},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &FareService{rounding: tt.rounding, operatorRoundings: tt.operators}
+			pipeline := NewFarePipeline(&capStage{}, &roundingStage{fareService: s})
+			fc := &FareContext{Route: tt.route, Result: &FareCalculationResult{ActualFare: tt.fare}}
+			if err := pipeline.Run(fc); err != nil {
+				t.Fatalf("计费失败: %v", err)
+			}
+			if fc.Result.ActualFare != tt.want {
+				t.Errorf("ActualFare = %s，应为 %s", fc.Result.ActualFare, tt.want)
+			}
+			var steps []string
+			for _, step := range fc.Result.Trace {
+				steps = append(steps, step.Stage)
+			}
+			if !reflect.DeepEqual(steps, tt.wantSteps) {
+				t.Errorf("计费明细 = %v，应为 %v", steps, tt.wantSteps)
+			}
+		})
+	}
+}
+
+func TestDefaultPipelineCapsBeforeRounding(t *testing.T) {
+	stages := NewFareService(nil).Pipeline().Stages()
+	position := make(map[string]int, len(stages))
+	for i, name := range stages {
+		position[name] = i
+	}
+	if !(position[StageCap] < position[StageRounding] && position[StageRounding] < position[StagePassengers]) {
+		t.Errorf("计费阶段顺序 = %v，应为封顶 → 舍入 → 同行乘客", stages)
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// FindZoneFares 查询线路适用的分区票价矩阵（线路矩阵优先，其次全网通用矩阵）
+func FindZoneFares(db *gorm.DB, routeID uint) ([]models.ZoneFare, error) {
+	var zoneFares []models.ZoneFare
+	err := db.Where("(route_id = ? OR route_id = 0) AND status = 'active'", routeID).
+		Order("route_id DESC, from_zone ASC, to_zone ASC").
+		Find(&zoneFares).Error
+	return zoneFares, err
+}
+
+// getZoneCountFare 获取线路的按分区数计价规则
+func (s *FareService) getZoneCountFare(routeID uint) (*models.Fare, bool) {
+	var fare models.Fare
+	err := s.db.Where(
+		"route_id = ? AND fare_type = 'zone_count' AND status = 'active' AND start_station = 0 AND end_station = 0",
+		routeID,
+	).First(&fare).Error
+	if err != nil {
+		return nil, false
+	}
+	return &fare, true
+}
+
+// lookupZoneMatrix 查询分区票价矩阵（线路矩阵优先，未配置反方向时按对称处理）
+func (s *FareService) lookupZoneMatrix(routeID uint, fromZone, toZone string) (*models.ZoneFare, bool) {
+	var candidates []models.ZoneFare
+	err := s.db.Where(
+		"(route_id = ? OR route_id = 0) AND status = 'active' AND ((from_zone = ? AND to_zone = ?) OR (from_zone = ? AND to_zone = ?))",
+		routeID, fromZone, toZone, toZone, fromZone,
+	).Order("route_id DESC").Find(&candidates).Error
+	if err != nil || len(candidates) == 0 {
+		return nil, false
+	}
+	// 同一优先级的矩阵中优先使用同方向配置
+	best := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if candidate.RouteID == best.RouteID && candidate.FromZone == fromZone && best.FromZone != fromZone {
+			best = candidate
+		}
+	}
+	return &best, true
+}
+
+// countZonesCrossed 计算行程经过的分区数（包含上车分区，按行驶顺序统计分区变化次数+1）
+func countZonesCrossed(path []models.RouteStation) int {
+	zones := 0
+	lastZone := ""
+	for _, rs := range path {
+		if rs.ZoneID == nil {
+			continue
+		}
+		if zones == 0 || *rs.ZoneID != lastZone {
+			zones++
+			lastZone = *rs.ZoneID
+		}
+	}
+	return zones
+}
+
+// priceZoneTrip 按分区计价：优先使用分区票价矩阵，其次使用按经过分区数计价规则
+func (s *FareService) priceZoneTrip(routeID uint, path *tripPath) (models.Money, fareSource, bool) {
+	fromRS, toRS := path.Board(), path.Alight()
+	if fromRS.ZoneID == nil || toRS.ZoneID == nil {
+		return 0, fareSource{}, false
+	}
+	details := path.details()
+	details["from_zone"] = *fromRS.ZoneID
+	details["to_zone"] = *toRS.ZoneID
+
+	if zoneFare, ok := s.lookupZoneMatrix(routeID, *fromRS.ZoneID, *toRS.ZoneID); ok {
+		return zoneFare.Price, fareSource{Rule: fareRuleRef("zone_fares", zoneFare.ID), Method: "zone_matrix", Details: details, Direction: path.Direction()}, true
+	}
+
+	if fare, ok := s.getZoneCountFare(routeID); ok {
+		zones := countZonesCrossed(path.Stations())
+		included := fare.SegmentCount
+		if included <= 0 {
+			included = 1
+		}
+		price := fare.BasePrice
+		if zones > included && fare.ExtraPrice > 0 {
+			price += models.Money(zones-included) * fare.ExtraPrice
+		}
+		details["zones_crossed"] = zones
+		details["included_zones"] = included
+		details["extra_price"] = fare.ExtraPrice
+		return price, fareSource{Rule: fareRuleRef("fares", fare.ID), Method: "zone_count", Details: details, Direction: path.Direction()}, true
+	}
+	return 0, fareSource{}, false
+}
+
+// calculateZoneFare 分区计价
+// tap_in_out：按上车分区到下车分区计价；single_tap（无下车站点）：按上车分区到行驶方向上最远分区计价
+// 未配置分区票价时退回统一票价
+func (s *FareService) calculateZoneFare(route *models.Route, startStationID uint, endStationID *uint, direction string) (models.Money, fareSource) {
+	if endStationID != nil && *endStationID > 0 {
+		if path, ok := s.resolveTripPath(route, startStationID, *endStationID, direction); ok {
+			if price, source, ok := s.priceZoneTrip(route.ID, path); ok {
+				return price, source
+			}
+		}
+		return s.getUniformFare(route.ID, route.MaxFare)
+	}
+
+	if price, source, ok := s.priceZoneToFurthest(route, startStationID, direction); ok {
+		return price, source
+	}
+	return s.getUniformFare(route.ID, route.MaxFare)
+}
+
+// priceZoneToFurthest 按上车分区到行驶方向上最远分区计价（single_tap模式）
+func (s *FareService) priceZoneToFurthest(route *models.Route, startStationID uint, direction string) (models.Money, fareSource, bool) {
+	path, ok := s.resolveFurthestPath(route, startStationID, direction)
+	if !ok {
+		return 0, fareSource{}, false
+	}
+	// 最远站点未划分分区时，取路径上最后一个有分区的站点
+	stations := path.Stations()
+	for len(stations) > 1 && stations[len(stations)-1].ZoneID == nil {
+		stations = stations[:len(stations)-1]
+		path.to = path.pattern.indexOf(stations[len(stations)-1].StationID)
+	}
+	price, source, ok := s.priceZoneTrip(route.ID, path)
+	if ok {
+		source.Details["single_tap_furthest_station"] = path.Alight().StationID
+	}
+	return price, source, ok
+}
@@ -0,0 +1,528 @@
+package services
+
+import (
+	"TapTransit-backend/config"
+	"TapTransit-backend/models"
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// gtfsServiceID 导出班次使用的服务日历（每天运营）
+const gtfsServiceID = "DAILY"
+
+// gtfsExportTables 导出依赖的主数据表（任何一张表变化时重新生成）
+var gtfsExportTables = []string{"routes", "stations", "route_stations", "fares", "zone_fares", "transfers", "route_groups"}
+
+// GTFSExport 生成的GTFS压缩包
+type GTFSExport struct {
+	Data        []byte         `json:"-"`
+	Fingerprint string         `json:"fingerprint"`        // 生成时的主数据指纹
+	GeneratedAt time.Time      `json:"generated_at"`       // 生成时间
+	Files       map[string]int `json:"files"`              // 文件名 -> 记录数
+	Warnings    []string       `json:"warnings,omitempty"` // 无法用GTFS-Fares v2表达而未导出的规则
+}
+
+// GTFSExporter 由线路、站点、站序与票价配置生成GTFS（含GTFS-Fares v2）压缩包
+// 导出结果缓存在内存中，每次导出时检查主数据指纹，有变化（新增、修改、删除记录）时重新生成
+type GTFSExporter struct {
+	db          *gorm.DB
+	fareService *FareService
+
+	mu      sync.Mutex
+	current *GTFSExport
+}
+
+func NewGTFSExporter(db *gorm.DB, fareService *FareService) *GTFSExporter {
+	return &GTFSExporter{db: db, fareService: fareService}
+}
+
+// Export 返回最新的GTFS压缩包（主数据有变化时先重新生成）
+func (e *GTFSExporter) Export() (*GTFSExport, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	fingerprint, err := e.masterDataFingerprint()
+	if err != nil {
+		return nil, err
+	}
+	if e.current != nil && e.current.Fingerprint == fingerprint {
+		return e.current, nil
+	}
+	export, err := e.generate(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	e.current = export
+	return export, nil
+}
+
+// masterDataFingerprint 主数据指纹（各表记录数、最后修改时间、最后删除时间）
+func (e *GTFSExporter) masterDataFingerprint() (string, error) {
+	var parts []string
+	for _, table := range gtfsExportTables {
+		var count int64
+		var updatedAt, deletedAt sql.NullTime
+		row := e.db.Raw("SELECT COUNT(*), MAX(updated_at), MAX(deleted_at) FROM " + table).Row()
+		if err := row.Scan(&count, &updatedAt, &deletedAt); err != nil {
+			return "", fmt.Errorf("查询%s失败: %w", table, err)
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d:%d:%d", table, count, updatedAt.Time.UnixNano(), deletedAt.Time.UnixNano()))
+	}
+	var count int64
+	var createdAt sql.NullTime
+	if err := e.db.Raw("SELECT COUNT(*), MAX(created_at) FROM route_group_members").Row().Scan(&count, &createdAt); err != nil {
+		return "", fmt.Errorf("查询route_group_members失败: %w", err)
+	}
+	parts = append(parts, fmt.Sprintf("route_group_members:%d:%d", count, createdAt.Time.UnixNano()))
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// gtfsFile 一个GTFS文件
+type gtfsFile struct {
+	name   string
+	header []string
+	rows   [][]string
+}
+
+func (f *gtfsFile) add(values ...string) {
+	f.rows = append(f.rows, values)
+}
+
+// gtfsExportBuilder 生成过程中的中间数据
+type gtfsExportBuilder struct {
+	products map[string]bool // 已添加的票价产品
+	areas    map[string]bool // 已添加的区域
+
+	fareProducts  *gtfsFile
+	areaFile      *gtfsFile
+	stopAreas     *gtfsFile
+	fareLegRules  *gtfsFile
+	transferRules *gtfsFile
+	warnings      []string
+}
+
+// generate 生成GTFS压缩包
+func (e *GTFSExporter) generate(fingerprint string) (*GTFSExport, error) {
+	cfg := gtfsConfig()
+	now := time.Now()
+
+	var routes []models.Route
+	if err := e.db.Where("status = 'active'").Order("route_id ASC").Find(&routes).Error; err != nil {
+		return nil, fmt.Errorf("查询线路失败: %w", err)
+	}
+	var stations []models.Station
+	if err := e.db.Where("status = 'active'").Order("station_id ASC").Find(&stations).Error; err != nil {
+		return nil, fmt.Errorf("查询站点失败: %w", err)
+	}
+
+	agency := &gtfsFile{name: "agency.txt", header: []string{"agency_id", "agency_name", "agency_url", "agency_timezone", "agency_lang"}}
+	agency.add(cfg.AgencyID, cfg.AgencyName, cfg.AgencyURL, cfg.AgencyTimezone, cfg.AgencyLang)
+
+	calendar := &gtfsFile{name: "calendar.txt", header: []string{"service_id", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday", "start_date", "end_date"}}
+	calendar.add(gtfsServiceID, "1", "1", "1", "1", "1", "1", "1", now.Format("20060102"), now.AddDate(1, 0, 0).Format("20060102"))
+
+	stops := &gtfsFile{name: "stops.txt", header: []string{"stop_id", "stop_name", "stop_desc", "stop_lat", "stop_lon"}}
+	addGTFSStops(stations, stops)
+
+	routeFile := &gtfsFile{name: "routes.txt", header: []string{"route_id", "agency_id", "route_short_name", "route_long_name", "route_type", "network_id"}}
+	trips := &gtfsFile{name: "trips.txt", header: []string{"route_id", "service_id", "trip_id", "direction_id"}}
+	stopTimes := &gtfsFile{name: "stop_times.txt", header: []string{"trip_id", "arrival_time", "departure_time", "stop_id", "stop_sequence", "shape_dist_traveled", "timepoint"}}
+
+	builder := &gtfsExportBuilder{
+		products:      make(map[string]bool),
+		areas:         make(map[string]bool),
+		fareProducts:  &gtfsFile{name: "fare_products.txt", header: []string{"fare_product_id", "fare_product_name", "amount", "currency"}},
+		areaFile:      &gtfsFile{name: "areas.txt", header: []string{"area_id", "area_name"}},
+		stopAreas:     &gtfsFile{name: "stop_areas.txt", header: []string{"area_id", "stop_id"}},
+		fareLegRules:  &gtfsFile{name: "fare_leg_rules.txt", header: []string{"leg_group_id", "network_id", "from_area_id", "to_area_id", "fare_product_id"}},
+		transferRules: &gtfsFile{name: "fare_transfer_rules.txt", header: []string{"from_leg_group_id", "to_leg_group_id", "transfer_count", "duration_limit", "duration_limit_type", "fare_transfer_type", "fare_product_id"}},
+	}
+
+	routeCodes := make(map[uint]string, len(routes))
+	for idx := range routes {
+		route := &routes[idx]
+		routeCodes[route.ID] = route.RouteID
+
+		var routeStations []models.RouteStation
+		if err := e.db.Preload("Station").Where("route_id = ?", route.ID).Order("direction ASC, sequence ASC").Find(&routeStations).Error; err != nil {
+			return nil, fmt.Errorf("查询线路%s站序失败: %w", route.RouteID, err)
+		}
+		addGTFSRoute(cfg.AgencyID, route, routeStations, routeFile, trips, stopTimes)
+		builder.addRouteFares(e.fareService, route, routeStations, cfg.Currency)
+	}
+	if err := builder.addTransferRules(e.db, routeCodes, cfg.Currency); err != nil {
+		return nil, err
+	}
+
+	data, counts, err := writeGTFSArchive([]*gtfsFile{agency, stops, routeFile, calendar, trips, stopTimes,
+		builder.fareProducts, builder.areaFile, builder.stopAreas, builder.fareLegRules, builder.transferRules})
+	if err != nil {
+		return nil, err
+	}
+
+	return &GTFSExport{
+		Data:        data,
+		Fingerprint: fingerprint,
+		GeneratedAt: now,
+		Files:       counts,
+		Warnings:    builder.warnings,
+	}, nil
+}
+
+// writeGTFSArchive 将GTFS文件写入压缩包，返回压缩包内容与各文件记录数
+func writeGTFSArchive(files []*gtfsFile) ([]byte, map[string]int, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	counts := make(map[string]int, len(files))
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("生成%s失败: %w", file.name, err)
+		}
+		writer := csv.NewWriter(w)
+		writer.Write(file.header)
+		writer.WriteAll(file.rows)
+		if err := writer.Error(); err != nil {
+			return nil, nil, fmt.Errorf("生成%s失败: %w", file.name, err)
+		}
+		counts[file.name] = len(file.rows)
+	}
+	if err := archive.Close(); err != nil {
+		return nil, nil, fmt.Errorf("生成GTFS压缩包失败: %w", err)
+	}
+	return buf.Bytes(), counts, nil
+}
+
+// addGTFSStops 导出站点（站点编号作为stop_id）
+func addGTFSStops(stations []models.Station, stops *gtfsFile) {
+	for _, station := range stations {
+		stops.add(station.StationID, station.Name, station.Address, formatCoordinate(station.Latitude), formatCoordinate(station.Longitude))
+	}
+}
+
+// addGTFSRoute 导出线路（线路编号作为route_id与network_id）及其各方向的代表班次
+func addGTFSRoute(agencyID string, route *models.Route, routeStations []models.RouteStation, routeFile, trips, stopTimes *gtfsFile) {
+	routeFile.add(route.RouteID, agencyID, route.RouteID, route.Name, "3", route.RouteID)
+	addGTFSTrips(route, routeStations, trips, stopTimes)
+}
+
+// addGTFSTrips 每条线路每个方向导出一个代表班次（系统不维护时刻表，到离站时间按首站06:00、每站2分钟估算，timepoint为0）
+func addGTFSTrips(route *models.Route, routeStations []models.RouteStation, trips, stopTimes *gtfsFile) {
+	byDirection := make(map[string][]models.RouteStation)
+	var directions []string
+	for _, rs := range routeStations {
+		if _, ok := byDirection[rs.Direction]; !ok {
+			directions = append(directions, rs.Direction)
+		}
+		byDirection[rs.Direction] = append(byDirection[rs.Direction], rs)
+	}
+	for _, direction := range directions {
+		directionID := gtfsDirectionUp
+		if direction == "down" {
+			directionID = gtfsDirectionDown
+		}
+		tripID := route.RouteID + "_" + direction
+		trips.add(route.RouteID, gtfsServiceID, tripID, directionID)
+		for idx, rs := range byDirection[direction] {
+			minutes := 6*60 + idx*2
+			stopTime := fmt.Sprintf("%02d:%02d:00", minutes/60, minutes%60)
+			distance := ""
+			if rs.DistanceKm != nil {
+				distance = strconv.FormatFloat(*rs.DistanceKm, 'f', -1, 64)
+			}
+			stopTimes.add(tripID, stopTime, stopTime, rs.Station.StationID, strconv.Itoa(idx+1), distance, "0")
+		}
+	}
+}
+
+// gtfsStationPair 上下车站点（single_tap线路下车站点为0）
+type gtfsStationPair struct {
+	from uint
+	to   uint
+}
+
+// gtfsZonePair 上下车分区（single_tap线路下车分区为空）
+type gtfsZonePair struct {
+	from string
+	to   string
+}
+
+// addRouteFares 导出线路的票价规则（leg_group_id与network_id均为线路编号）
+// 按计费服务计算各上下车站点组合的基础票价（普通卡、无优惠）：全线同价时导出一条不限区域的规则；
+// 各站均划分分区且同一分区组合票价相同时按分区（zone_<分区ID>）导出；否则按站点（stop_<站点编号>）导出
+func (b *gtfsExportBuilder) addRouteFares(fareService *FareService, route *models.Route, routeStations []models.RouteStation, currency string) {
+	stations := make(map[uint]models.Station)
+	zones := make(map[uint]string)
+	for _, rs := range routeStations {
+		stations[rs.StationID] = rs.Station
+		if rs.ZoneID != nil {
+			zones[rs.StationID] = *rs.ZoneID
+		}
+	}
+
+	prices := make(map[gtfsStationPair]models.Money)
+	var pairs []gtfsStationPair
+	addPrice := func(pair gtfsStationPair, direction string) {
+		if _, ok := prices[pair]; ok {
+			return
+		}
+		var end *uint
+		if pair.to != 0 {
+			end = &pair.to
+		}
+		price, _, err := fareService.calculateBaseFare(route, pair.from, end, direction)
+		if err != nil {
+			b.warnings = append(b.warnings, fmt.Sprintf("线路%s站点%d-%d计价失败: %v", route.RouteID, pair.from, pair.to, err))
+			return
+		}
+		prices[pair] = price
+		pairs = append(pairs, pair)
+	}
+	for _, pattern := range fareService.loadRoutePatterns(route) {
+		n := len(pattern.stations)
+		for i := 0; i < n; i++ {
+			from := pattern.stations[i].StationID
+			if route.TapMode != "tap_in_out" {
+				if i < n-1 || pattern.loop {
+					addPrice(gtfsStationPair{from: from}, pattern.direction)
+				}
+				continue
+			}
+			for j := 0; j < n; j++ {
+				if j > i || (pattern.loop && j != i) {
+					addPrice(gtfsStationPair{from: from, to: pattern.stations[j].StationID}, pattern.direction)
+				}
+			}
+		}
+	}
+	if len(pairs) == 0 {
+		// 线路未配置站序：按统一票价导出
+		price, _, err := fareService.calculateBaseFare(route, 0, nil, "")
+		if err != nil {
+			b.warnings = append(b.warnings, fmt.Sprintf("线路%s计价失败: %v", route.RouteID, err))
+			return
+		}
+		b.fareLegRules.add(route.RouteID, route.RouteID, "", "", b.fareProduct(price, currency))
+		return
+	}
+
+	uniform := true
+	for _, pair := range pairs {
+		if prices[pair] != prices[pairs[0]] {
+			uniform = false
+			break
+		}
+	}
+	if uniform {
+		b.fareLegRules.add(route.RouteID, route.RouteID, "", "", b.fareProduct(prices[pairs[0]], currency))
+		return
+	}
+
+	zonePrices := make(map[gtfsZonePair]models.Money)
+	var zonePairs []gtfsZonePair
+	byZone := true
+	for _, pair := range pairs {
+		fromZone, fromOK := zones[pair.from]
+		toZone, toOK := "", true
+		if pair.to != 0 {
+			toZone, toOK = zones[pair.to]
+		}
+		if !fromOK || !toOK {
+			byZone = false
+			break
+		}
+		zonePair := gtfsZonePair{from: fromZone, to: toZone}
+		if price, ok := zonePrices[zonePair]; ok {
+			if price != prices[pair] {
+				byZone = false
+				break
+			}
+			continue
+		}
+		zonePrices[zonePair] = prices[pair]
+		zonePairs = append(zonePairs, zonePair)
+	}
+
+	if byZone {
+		for _, rs := range routeStations {
+			if rs.ZoneID != nil {
+				b.addStopArea("zone_"+*rs.ZoneID, "分区"+*rs.ZoneID, rs.Station.StationID)
+			}
+		}
+		for _, pair := range zonePairs {
+			to := ""
+			if pair.to != "" {
+				to = "zone_" + pair.to
+			}
+			b.fareLegRules.add(route.RouteID, route.RouteID, "zone_"+pair.from, to, b.fareProduct(zonePrices[pair], currency))
+		}
+		return
+	}
+	stationArea := func(id uint) string {
+		station := stations[id]
+		b.addStopArea("stop_"+station.StationID, station.Name, station.StationID)
+		return "stop_" + station.StationID
+	}
+	for _, pair := range pairs {
+		from, to := stationArea(pair.from), ""
+		if pair.to != 0 {
+			to = stationArea(pair.to)
+		}
+		b.fareLegRules.add(route.RouteID, route.RouteID, from, to, b.fareProduct(prices[pair], currency))
+	}
+}
+
+// addStopArea 添加区域与站点的归属（同一区域只添加一次，同一站点在区域中只出现一次）
+func (b *gtfsExportBuilder) addStopArea(areaID, areaName, stopID string) {
+	if !b.areas[areaID] {
+		b.areas[areaID] = true
+		b.areaFile.add(areaID, areaName)
+	}
+	key := areaID + "\x00" + stopID
+	if !b.areas[key] {
+		b.areas[key] = true
+		b.stopAreas.add(areaID, stopID)
+	}
+}
+
+// fareProduct 按金额返回票价产品ID（同一金额共用一个产品）
+func (b *gtfsExportBuilder) fareProduct(amount models.Money, currency string) string {
+	id := fmt.Sprintf("fare_%d", amount.Cents())
+	name := amount.String() + "元"
+	if amount < 0 {
+		id = fmt.Sprintf("transfer_discount_%d", -amount.Cents())
+		name = "换乘优惠" + (-amount).String() + "元"
+	}
+	if !b.products[id] {
+		b.products[id] = true
+		b.fareProducts.add(id, name, amount.String(), currency)
+	}
+	return id
+}
+
+// addTransferRules 导出换乘规则：固定金额优惠导出为负金额的换乘产品（A+AB+B），全额优惠导出为免费换乘（A+AB）
+// 线路组展开为组内各线路；限定换乘站点的规则和部分比例优惠无法表达，记录在warnings中
+func (b *gtfsExportBuilder) addTransferRules(db *gorm.DB, routeCodes map[uint]string, currency string) error {
+	var transfers []models.Transfer
+	if err := db.Where("status = 'active'").Order("id ASC").Find(&transfers).Error; err != nil {
+		return fmt.Errorf("查询换乘规则失败: %w", err)
+	}
+	for _, transfer := range transfers {
+		ref := fareRuleRef("transfers", transfer.ID)
+		if transfer.FromStationID != 0 || transfer.ToStationID != 0 {
+			b.warnings = append(b.warnings, ref+"限定了换乘站点，未导出")
+			continue
+		}
+		transferType, product := "", ""
+		switch {
+		case transfer.DiscountAmount > 0:
+			transferType, product = "1", b.fareProduct(-transfer.DiscountAmount, currency)
+		case transfer.DiscountRate >= 1:
+			transferType, product = "0", b.fareProduct(0, currency)
+		case transfer.DiscountRate > 0:
+			b.warnings = append(b.warnings, ref+"为按比例优惠，未导出")
+			continue
+		default:
+			continue
+		}
+
+		fromGroups, err := transferLegGroups(db, transfer.FromRouteID, transfer.FromRouteGroupID, routeCodes)
+		if err != nil {
+			return err
+		}
+		toGroups, err := transferLegGroups(db, transfer.ToRouteID, transfer.ToRouteGroupID, routeCodes)
+		if err != nil {
+			return err
+		}
+		timeWindow := transfer.TimeWindow
+		if timeWindow == 0 {
+			timeWindow = 60
+		}
+		for _, from := range fromGroups {
+			for _, to := range toGroups {
+				// 起止为同一组时必须指定换乘次数（-1表示不限）
+				transferCount := ""
+				if from == to {
+					transferCount = "-1"
+					if transfer.MaxDiscountedTransfers > 0 {
+						transferCount = strconv.Itoa(transfer.MaxDiscountedTransfers)
+					}
+				}
+				// duration_limit_type=2：从上一程下车到下一程上车
+				b.transferRules.add(from, to, transferCount, strconv.Itoa(timeWindow*60), "2", transferType, product)
+			}
+		}
+	}
+	return nil
+}
+
+// transferLegGroups 换乘规则一侧对应的leg_group_id（线路编号；不限线路时为空；线路组展开为组内启用的线路）
+func transferLegGroups(db *gorm.DB, routeID, routeGroupID uint, routeCodes map[uint]string) ([]string, error) {
+	if routeID != 0 {
+		if code, ok := routeCodes[routeID]; ok {
+			return []string{code}, nil
+		}
+		return nil, nil
+	}
+	if routeGroupID == 0 {
+		return []string{""}, nil
+	}
+	var routeIDs []uint
+	if err := db.Model(&models.RouteGroupMember{}).Where("route_group_id = ?", routeGroupID).Pluck("route_id", &routeIDs).Error; err != nil {
+		return nil, fmt.Errorf("查询线路组成员失败: %w", err)
+	}
+	var groups []string
+	for _, id := range routeIDs {
+		if code, ok := routeCodes[id]; ok {
+			groups = append(groups, code)
+		}
+	}
+	sort.Strings(groups)
+	return groups, nil
+}
+
+// gtfsConfig GTFS导出配置（未配置的项使用默认值）
+func gtfsConfig() config.GTFSConfig {
+	var cfg config.GTFSConfig
+	if config.AppConfig != nil {
+		cfg = config.AppConfig.GTFS
+	}
+	if cfg.AgencyID == "" {
+		cfg.AgencyID = "TapTransit"
+	}
+	if cfg.AgencyName == "" {
+		cfg.AgencyName = cfg.AgencyID
+	}
+	if cfg.AgencyURL == "" {
+		cfg.AgencyURL = "https://www.example.com"
+	}
+	if cfg.AgencyTimezone == "" {
+		cfg.AgencyTimezone = "Asia/Shanghai"
+	}
+	if cfg.AgencyLang == "" {
+		cfg.AgencyLang = "zh"
+	}
+	if cfg.Currency == "" {
+		cfg.Currency = "CNY"
+	}
+	return cfg
+}
+
+// formatCoordinate 格式化经纬度
+func formatCoordinate(value float64) string {
+	return strconv.FormatFloat(roundCoordinate(value), 'f', -1, 64)
+}
@@ -0,0 +1,661 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// GTFS direction_id与线路站序方向的对应关系
+const (
+	gtfsDirectionUp   = "0" // 对应站序方向up
+	gtfsDirectionDown = "1" // 对应站序方向down
+)
+
+// GTFS导入的数据变化类型
+const (
+	gtfsActionCreate = "create"
+	gtfsActionUpdate = "update"
+	gtfsActionDelete = "delete"
+)
+
+// gtfsDirection GTFS direction_id对应的站序方向（为空按0处理）
+func gtfsDirection(directionID string) string {
+	if directionID == gtfsDirectionDown {
+		return "down"
+	}
+	return "up"
+}
+
+// gtfsFeed GTFS静态数据（只读取导入需要的字段）
+type gtfsFeed struct {
+	routes    []gtfsRoute
+	stops     []gtfsStop
+	trips     []gtfsTrip
+	stopTimes map[string][]gtfsStopTime   // trip_id -> 按stop_sequence排序
+	shapes    map[string][]gtfsShapePoint // shape_id -> 按shape_pt_sequence排序
+}
+
+type gtfsRoute struct {
+	routeID   string
+	shortName string
+	longName  string
+}
+
+type gtfsStop struct {
+	stopID string
+	name   string
+	desc   string
+	zoneID string
+	lat    float64
+	lon    float64
+}
+
+type gtfsTrip struct {
+	tripID      string
+	routeID     string
+	directionID string
+	shapeID     string
+}
+
+type gtfsStopTime struct {
+	stopID   string
+	sequence int
+}
+
+type gtfsShapePoint struct {
+	lat      float64
+	lon      float64
+	sequence int
+}
+
+// GTFSImportReport GTFS导入报告（dry_run时为与现有数据的差异，不写入任何数据）
+type GTFSImportReport struct {
+	DryRun   bool              `json:"dry_run"`
+	Summary  GTFSImportSummary `json:"summary"`
+	Changes  []GTFSChange      `json:"changes"`            // 新增、修改、移除的数据（不含未变化的数据）
+	Warnings []string          `json:"warnings,omitempty"` // 跳过的数据
+}
+
+// GTFSImportSummary 各类数据的新增、修改、未变化数量
+type GTFSImportSummary struct {
+	RoutesCreated      int `json:"routes_created"`
+	RoutesUpdated      int `json:"routes_updated"`
+	RoutesUnchanged    int `json:"routes_unchanged"`
+	StationsCreated    int `json:"stations_created"`
+	StationsUpdated    int `json:"stations_updated"`
+	StationsUnchanged  int `json:"stations_unchanged"`
+	SequencesCreated   int `json:"sequences_created"`
+	SequencesUpdated   int `json:"sequences_updated"`
+	SequencesRemoved   int `json:"sequences_removed"`
+	SequencesUnchanged int `json:"sequences_unchanged"`
+}
+
+// GTFSChange 一项数据变化
+type GTFSChange struct {
+	Entity string            `json:"entity"`           // route, station, route_stations
+	Key    string            `json:"key"`              // 线路编号、站点编号或“线路编号/方向”
+	Action string            `json:"action"`           // create, update, delete
+	Fields []GTFSFieldChange `json:"fields,omitempty"` // 修改的字段
+}
+
+// GTFSFieldChange 字段变化
+type GTFSFieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// GTFSImporter 从GTFS静态数据导入线路、站点与线路站序
+// GTFS的route_id、stop_id分别作为线路编号和站点编号；每条线路每个方向取站点最多的班次作为站序
+type GTFSImporter struct {
+	db *gorm.DB
+}
+
+func NewGTFSImporter(db *gorm.DB) *GTFSImporter {
+	return &GTFSImporter{db: db}
+}
+
+// Import 导入GTFS压缩包；dryRun为true时只返回差异
+// 导入在一个事务中执行（与正式导入使用相同的校验），dry_run或任何一步失败时整体回滚
+func (i *GTFSImporter) Import(data []byte, dryRun bool) (*GTFSImportReport, error) {
+	feed, err := parseGTFSFeed(data)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := i.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.Rollback()
+
+	report := &GTFSImportReport{DryRun: dryRun, Changes: []GTFSChange{}}
+	stations, err := importGTFSStops(tx, feed, report)
+	if err != nil {
+		return nil, err
+	}
+	patterns := buildGTFSPatterns(feed, report)
+	for _, route := range feed.routes {
+		if err := importGTFSRoute(tx, route, patterns[route.routeID], stations, report); err != nil {
+			return nil, fmt.Errorf("线路%s: %w", route.routeID, err)
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("提交导入失败: %w", err)
+	}
+	return report, nil
+}
+
+// gtfsPattern 线路某一方向的站序（来自代表班次）
+type gtfsPattern struct {
+	direction string
+	tripID    string
+	stops     []gtfsStop
+	distances []float64 // 到首站的累计距离（公里）
+}
+
+// buildGTFSPatterns 为每条线路的每个方向选取站点最多的班次（站点数相同时取trip_id最小的）并计算累计距离
+func buildGTFSPatterns(feed *gtfsFeed, report *GTFSImportReport) map[string][]gtfsPattern {
+	stopsByID := make(map[string]gtfsStop, len(feed.stops))
+	for _, stop := range feed.stops {
+		stopsByID[stop.stopID] = stop
+	}
+
+	trips := make([]gtfsTrip, len(feed.trips))
+	copy(trips, feed.trips)
+	sort.Slice(trips, func(a, b int) bool { return trips[a].tripID < trips[b].tripID })
+	representative := make(map[string]gtfsTrip)
+	for _, trip := range trips {
+		key := trip.routeID + "/" + gtfsDirection(trip.directionID)
+		current, ok := representative[key]
+		if !ok || len(feed.stopTimes[trip.tripID]) > len(feed.stopTimes[current.tripID]) {
+			representative[key] = trip
+		}
+	}
+
+	patterns := make(map[string][]gtfsPattern)
+	keys := make([]string, 0, len(representative))
+	for key := range representative {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		trip := representative[key]
+		pattern := gtfsPattern{direction: gtfsDirection(trip.directionID), tripID: trip.tripID}
+		for _, stopTime := range feed.stopTimes[trip.tripID] {
+			stop, ok := stopsByID[stopTime.stopID]
+			if !ok {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("班次%s的站点%s不在stops.txt中，已跳过", trip.tripID, stopTime.stopID))
+				continue
+			}
+			pattern.stops = append(pattern.stops, stop)
+		}
+		if len(pattern.stops) < 2 {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("班次%s的站点少于2个，线路%s方向%s未导入站序", trip.tripID, trip.routeID, pattern.direction))
+			continue
+		}
+		pattern.distances = gtfsCumulativeDistances(pattern.stops, feed.shapes[trip.shapeID])
+		patterns[trip.routeID] = append(patterns[trip.routeID], pattern)
+	}
+	return patterns
+}
+
+// importGTFSStops 新增或更新站点，返回站点编号到站点的映射
+func importGTFSStops(tx *gorm.DB, feed *gtfsFeed, report *GTFSImportReport) (map[string]models.Station, error) {
+	stations := make(map[string]models.Station, len(feed.stops))
+	for _, stop := range feed.stops {
+		var station models.Station
+		err := tx.Where("station_id = ?", stop.stopID).First(&station).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("查询站点%s失败: %w", stop.stopID, err)
+		}
+		if err == gorm.ErrRecordNotFound {
+			station = models.Station{
+				StationID: stop.stopID,
+				Name:      stop.name,
+				Latitude:  stop.lat,
+				Longitude: stop.lon,
+				Address:   stop.desc,
+				Status:    "active",
+			}
+			if err := tx.Create(&station).Error; err != nil {
+				return nil, fmt.Errorf("新增站点%s失败: %w", stop.stopID, err)
+			}
+			report.Summary.StationsCreated++
+			report.Changes = append(report.Changes, GTFSChange{Entity: "station", Key: stop.stopID, Action: gtfsActionCreate})
+			stations[stop.stopID] = station
+			continue
+		}
+
+		var fields []GTFSFieldChange
+		fields = appendFieldChange(fields, "name", station.Name, stop.name)
+		fields = appendFieldChange(fields, "latitude", roundCoordinate(station.Latitude), roundCoordinate(stop.lat))
+		fields = appendFieldChange(fields, "longitude", roundCoordinate(station.Longitude), roundCoordinate(stop.lon))
+		if stop.desc != "" {
+			fields = appendFieldChange(fields, "address", station.Address, stop.desc)
+		}
+		fields = appendFieldChange(fields, "status", station.Status, "active")
+		if len(fields) == 0 {
+			report.Summary.StationsUnchanged++
+			stations[stop.stopID] = station
+			continue
+		}
+		station.Name = stop.name
+		station.Latitude = stop.lat
+		station.Longitude = stop.lon
+		if stop.desc != "" {
+			station.Address = stop.desc
+		}
+		station.Status = "active"
+		if err := tx.Save(&station).Error; err != nil {
+			return nil, fmt.Errorf("更新站点%s失败: %w", stop.stopID, err)
+		}
+		report.Summary.StationsUpdated++
+		report.Changes = append(report.Changes, GTFSChange{Entity: "station", Key: stop.stopID, Action: gtfsActionUpdate, Fields: fields})
+		stations[stop.stopID] = station
+	}
+	return stations, nil
+}
+
+// importGTFSRoute 新增或更新线路，并替换有变化的方向站序（新增线路按统一票价、单次刷卡创建）
+func importGTFSRoute(tx *gorm.DB, gr gtfsRoute, patterns []gtfsPattern, stations map[string]models.Station, report *GTFSImportReport) error {
+	name := gr.longName
+	if name == "" {
+		name = gr.shortName
+	}
+	if name == "" {
+		name = gr.routeID
+	}
+	directionMode := DirectionModeSingle
+	if len(patterns) > 1 {
+		directionMode = DirectionModeBoth
+	}
+	for _, pattern := range patterns {
+		if pattern.stops[0].stopID == pattern.stops[len(pattern.stops)-1].stopID {
+			directionMode = DirectionModeLoop
+		}
+	}
+
+	var route models.Route
+	err := tx.Where("route_id = ?", gr.routeID).First(&route).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return fmt.Errorf("查询线路失败: %w", err)
+	}
+	if err == gorm.ErrRecordNotFound {
+		route = models.Route{
+			RouteID:        gr.routeID,
+			Name:           name,
+			Status:         "active",
+			FareType:       "uniform",
+			TapMode:        "single_tap",
+			DirectionMode:  directionMode,
+			LoopFarePolicy: LoopFareShortest,
+		}
+		if err := tx.Create(&route).Error; err != nil {
+			return fmt.Errorf("新增线路失败: %w", err)
+		}
+		report.Summary.RoutesCreated++
+		report.Changes = append(report.Changes, GTFSChange{Entity: "route", Key: gr.routeID, Action: gtfsActionCreate})
+	} else {
+		var fields []GTFSFieldChange
+		fields = appendFieldChange(fields, "name", route.Name, name)
+		if len(patterns) > 0 {
+			fields = appendFieldChange(fields, "direction_mode", route.DirectionMode, directionMode)
+		}
+		fields = appendFieldChange(fields, "status", route.Status, "active")
+		if len(fields) == 0 {
+			report.Summary.RoutesUnchanged++
+		} else {
+			route.Name = name
+			if len(patterns) > 0 {
+				route.DirectionMode = directionMode
+			}
+			route.Status = "active"
+			if err := tx.Save(&route).Error; err != nil {
+				return fmt.Errorf("更新线路失败: %w", err)
+			}
+			report.Summary.RoutesUpdated++
+			report.Changes = append(report.Changes, GTFSChange{Entity: "route", Key: gr.routeID, Action: gtfsActionUpdate, Fields: fields})
+		}
+	}
+	if len(patterns) == 0 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("线路%s没有可用的班次，未导入站序", gr.routeID))
+		return nil
+	}
+
+	var existing []models.RouteStation
+	if err := tx.Preload("Station").Where("route_id = ?", route.ID).Order("direction ASC, sequence ASC").Find(&existing).Error; err != nil {
+		return fmt.Errorf("查询线路站序失败: %w", err)
+	}
+	existingByDirection := make(map[string][]models.RouteStation)
+	for _, rs := range existing {
+		existingByDirection[rs.Direction] = append(existingByDirection[rs.Direction], rs)
+	}
+
+	networkService := NewNetworkService(tx)
+	imported := make(map[string]bool)
+	for _, pattern := range patterns {
+		imported[pattern.direction] = true
+		key := gr.routeID + "/" + pattern.direction
+		req := RouteStationsRequest{Direction: pattern.direction}
+		for idx, stop := range pattern.stops {
+			entry := RouteStationEntry{StationID: stations[stop.stopID].ID, Sequence: idx + 1}
+			if stop.zoneID != "" {
+				zoneID := stop.zoneID
+				entry.ZoneID = &zoneID
+			}
+			distance := pattern.distances[idx]
+			entry.DistanceKm = &distance
+			req.Stations = append(req.Stations, entry)
+		}
+
+		current := existingByDirection[pattern.direction]
+		fields := diffRouteStations(current, pattern)
+		if len(current) > 0 && len(fields) == 0 {
+			report.Summary.SequencesUnchanged++
+			continue
+		}
+		if _, err := networkService.SetRouteStations(route.ID, req); err != nil {
+			return fmt.Errorf("方向%s（班次%s）: %w", pattern.direction, pattern.tripID, err)
+		}
+		if len(current) == 0 {
+			report.Summary.SequencesCreated++
+			report.Changes = append(report.Changes, GTFSChange{Entity: "route_stations", Key: key, Action: gtfsActionCreate, Fields: fields})
+		} else {
+			report.Summary.SequencesUpdated++
+			report.Changes = append(report.Changes, GTFSChange{Entity: "route_stations", Key: key, Action: gtfsActionUpdate, Fields: fields})
+		}
+	}
+
+	// 移除GTFS中已不存在的方向
+	directions := make([]string, 0, len(existingByDirection))
+	for direction := range existingByDirection {
+		directions = append(directions, direction)
+	}
+	sort.Strings(directions)
+	for _, direction := range directions {
+		if imported[direction] {
+			continue
+		}
+		if err := tx.Where("route_id = ? AND direction = ?", route.ID, direction).Delete(&models.RouteStation{}).Error; err != nil {
+			return fmt.Errorf("移除方向%s的站序失败: %w", direction, err)
+		}
+		report.Summary.SequencesRemoved++
+		report.Changes = append(report.Changes, GTFSChange{Entity: "route_stations", Key: gr.routeID + "/" + direction, Action: gtfsActionDelete})
+	}
+	return nil
+}
+
+// diffRouteStations 比较现有站序与GTFS站序（站点编号、分区、累计距离）
+func diffRouteStations(current []models.RouteStation, pattern gtfsPattern) []GTFSFieldChange {
+	var oldStations, oldZones, newStations, newZones []string
+	var oldDistances, newDistances []float64
+	for _, rs := range current {
+		oldStations = append(oldStations, rs.Station.StationID)
+		zone := ""
+		if rs.ZoneID != nil {
+			zone = *rs.ZoneID
+		}
+		oldZones = append(oldZones, zone)
+		distance := -1.0
+		if rs.DistanceKm != nil {
+			distance = *rs.DistanceKm
+		}
+		oldDistances = append(oldDistances, distance)
+	}
+	for idx, stop := range pattern.stops {
+		newStations = append(newStations, stop.stopID)
+		newZones = append(newZones, stop.zoneID)
+		newDistances = append(newDistances, pattern.distances[idx])
+	}
+
+	var fields []GTFSFieldChange
+	fields = appendFieldChange(fields, "stations", strings.Join(oldStations, ","), strings.Join(newStations, ","))
+	fields = appendFieldChange(fields, "zone_id", strings.Join(oldZones, ","), strings.Join(newZones, ","))
+	if !sameDistances(oldDistances, newDistances) {
+		fields = append(fields, GTFSFieldChange{Field: "distance_km", Old: oldDistances, New: newDistances})
+	}
+	return fields
+}
+
+// sameDistances 累计距离是否相同（按米比较）
+func sameDistances(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if kmToMeters(a[idx]) != kmToMeters(b[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+// appendFieldChange 值不同时记录字段变化
+func appendFieldChange(fields []GTFSFieldChange, field string, old, new interface{}) []GTFSFieldChange {
+	if old == new {
+		return fields
+	}
+	return append(fields, GTFSFieldChange{Field: field, Old: old, New: new})
+}
+
+// roundCoordinate 经纬度保留8位小数（与数据库精度一致）
+func roundCoordinate(value float64) float64 {
+	return math.Round(value*1e8) / 1e8
+}
+
+// gtfsCumulativeDistances 计算各站到首站的累计距离（公里，保留3位小数）
+// 有线路走向（shapes.txt）时沿走向计算，否则按相邻站点直线距离累加
+func gtfsCumulativeDistances(stops []gtfsStop, shape []gtfsShapePoint) []float64 {
+	distances := make([]float64, len(stops))
+	if len(shape) >= 2 {
+		shapeDistances := make([]float64, len(shape))
+		for idx := 1; idx < len(shape); idx++ {
+			shapeDistances[idx] = shapeDistances[idx-1] + haversineKm(shape[idx-1].lat, shape[idx-1].lon, shape[idx].lat, shape[idx].lon)
+		}
+		// 站点按顺序投影到走向上，只向前查找以保证累计距离单调不减
+		segment := 0
+		for idx, stop := range stops {
+			bestSegment, bestOffset, bestDistance := segment, 0.0, math.MaxFloat64
+			for s := segment; s < len(shape)-1; s++ {
+				offset, distance := projectOnSegment(stop, shape[s], shape[s+1])
+				if distance < bestDistance {
+					bestSegment, bestOffset, bestDistance = s, offset, distance
+				}
+			}
+			along := shapeDistances[bestSegment] + bestOffset*(shapeDistances[bestSegment+1]-shapeDistances[bestSegment])
+			if idx > 0 && along < distances[idx-1] {
+				along = distances[idx-1]
+			}
+			distances[idx] = along
+			segment = bestSegment
+		}
+	} else {
+		for idx := 1; idx < len(stops); idx++ {
+			distances[idx] = distances[idx-1] + haversineKm(stops[idx-1].lat, stops[idx-1].lon, stops[idx].lat, stops[idx].lon)
+		}
+	}
+	for idx := range distances {
+		distances[idx] = math.Round(distances[idx]*1000) / 1000
+	}
+	return distances
+}
+
+// projectOnSegment 站点在走向线段上的投影位置（0-1）及到线段的距离（公里，局部平面近似）
+func projectOnSegment(stop gtfsStop, a, b gtfsShapePoint) (float64, float64) {
+	scale := math.Cos(a.lat * math.Pi / 180)
+	ax, ay := a.lon*scale, a.lat
+	bx, by := b.lon*scale, b.lat
+	px, py := stop.lon*scale, stop.lat
+	dx, dy := bx-ax, by-ay
+	offset := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		offset = math.Max(0, math.Min(1, ((px-ax)*dx+(py-ay)*dy)/length))
+	}
+	lat := a.lat + offset*(b.lat-a.lat)
+	lon := a.lon + offset*(b.lon-a.lon)
+	return offset, haversineKm(stop.lat, stop.lon, lat, lon)
+}
+
+// haversineKm 两点间的球面距离（公里）
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371.0
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLon := (lon2 - lon1) * toRad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// parseGTFSFeed 解析GTFS压缩包（routes.txt、stops.txt、trips.txt、stop_times.txt必需，shapes.txt可选）
+func parseGTFSFeed(data []byte) (*gtfsFeed, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("GTFS压缩包格式错误: %w", err)
+	}
+	files := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		// 兼容压缩包内带一层目录的情况
+		name := file.Name[strings.LastIndex(file.Name, "/")+1:]
+		files[name] = file
+	}
+	feed := &gtfsFeed{stopTimes: make(map[string][]gtfsStopTime), shapes: make(map[string][]gtfsShapePoint)}
+
+	routes, err := readGTFSTable(files, "routes.txt", true)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range routes {
+		if row["route_id"] == "" {
+			return nil, fmt.Errorf("routes.txt存在route_id为空的记录")
+		}
+		feed.routes = append(feed.routes, gtfsRoute{routeID: row["route_id"], shortName: row["route_short_name"], longName: row["route_long_name"]})
+	}
+
+	stops, err := readGTFSTable(files, "stops.txt", true)
+	if err != nil {
+		return nil, err
+	}
+	for line, row := range stops {
+		// 只导入站点/站台（location_type为空或0），不导入车站、出入口等
+		if row["location_type"] != "" && row["location_type"] != "0" {
+			continue
+		}
+		lat, latErr := strconv.ParseFloat(row["stop_lat"], 64)
+		lon, lonErr := strconv.ParseFloat(row["stop_lon"], 64)
+		if row["stop_id"] == "" || latErr != nil || lonErr != nil {
+			return nil, fmt.Errorf("stops.txt第%d行的stop_id或经纬度格式错误", line+2)
+		}
+		feed.stops = append(feed.stops, gtfsStop{
+			stopID: row["stop_id"],
+			name:   row["stop_name"],
+			desc:   row["stop_desc"],
+			zoneID: row["zone_id"],
+			lat:    lat,
+			lon:    lon,
+		})
+	}
+
+	trips, err := readGTFSTable(files, "trips.txt", true)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range trips {
+		feed.trips = append(feed.trips, gtfsTrip{tripID: row["trip_id"], routeID: row["route_id"], directionID: row["direction_id"], shapeID: row["shape_id"]})
+	}
+
+	stopTimes, err := readGTFSTable(files, "stop_times.txt", true)
+	if err != nil {
+		return nil, err
+	}
+	for line, row := range stopTimes {
+		sequence, err := strconv.Atoi(row["stop_sequence"])
+		if err != nil {
+			return nil, fmt.Errorf("stop_times.txt第%d行的stop_sequence格式错误", line+2)
+		}
+		feed.stopTimes[row["trip_id"]] = append(feed.stopTimes[row["trip_id"]], gtfsStopTime{stopID: row["stop_id"], sequence: sequence})
+	}
+	for _, list := range feed.stopTimes {
+		sort.SliceStable(list, func(a, b int) bool { return list[a].sequence < list[b].sequence })
+	}
+
+	shapes, err := readGTFSTable(files, "shapes.txt", false)
+	if err != nil {
+		return nil, err
+	}
+	for line, row := range shapes {
+		lat, latErr := strconv.ParseFloat(row["shape_pt_lat"], 64)
+		lon, lonErr := strconv.ParseFloat(row["shape_pt_lon"], 64)
+		sequence, seqErr := strconv.Atoi(row["shape_pt_sequence"])
+		if latErr != nil || lonErr != nil || seqErr != nil {
+			return nil, fmt.Errorf("shapes.txt第%d行格式错误", line+2)
+		}
+		feed.shapes[row["shape_id"]] = append(feed.shapes[row["shape_id"]], gtfsShapePoint{lat: lat, lon: lon, sequence: sequence})
+	}
+	for _, list := range feed.shapes {
+		sort.SliceStable(list, func(a, b int) bool { return list[a].sequence < list[b].sequence })
+	}
+	return feed, nil
+}
+
+// readGTFSTable 读取GTFS文件为按表头字段名索引的记录
+func readGTFSTable(files map[string]*zip.File, name string, required bool) ([]map[string]string, error) {
+	file, ok := files[name]
+	if !ok {
+		if required {
+			return nil, fmt.Errorf("GTFS压缩包缺少%s", name)
+		}
+		return nil, nil
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("读取%s失败: %w", name, err)
+	}
+	defer rc.Close()
+
+	reader := csv.NewReader(rc)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取%s失败: %w", name, err)
+	}
+	for idx := range header {
+		header[idx] = strings.TrimSpace(strings.TrimPrefix(header[idx], "\ufeff"))
+	}
+
+	var rows []map[string]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取%s失败: %w", name, err)
+		}
+		row := make(map[string]string, len(header))
+		for idx, field := range header {
+			if idx < len(record) {
+				row[field] = strings.TrimSpace(record[idx])
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"testing"
+)
+
+// testGTFSRouteStations 按方向生成线路站序（站点按StationID从stations中取，ID为下标+1）
+func testGTFSRouteStations(stations []models.Station, direction string, codes ...string) []models.RouteStation {
+	byCode := make(map[string]models.Station, len(stations))
+	for _, station := range stations {
+		byCode[station.StationID] = station
+	}
+	routeStations := make([]models.RouteStation, 0, len(codes))
+	for idx, code := range codes {
+		station := byCode[code]
+		routeStations = append(routeStations, models.RouteStation{StationID: station.ID, Sequence: idx + 1, Direction: direction, Station: station})
+	}
+	return routeStations
+}
+
+func TestGTFSExportImportRoundTrip(t *testing.T) {
+	stations := []models.Station{
+		{ID: 1, StationID: "S001", Name: "火车站", Latitude: 31.2304, Longitude: 121.4737, Address: "站前路1号"},
+		{ID: 2, StationID: "S002", Name: "人民广场", Latitude: 31.2354, Longitude: 121.4787},
+		{ID: 3, StationID: "S003", Name: "体育中心", Latitude: 31.2404, Longitude: 121.4837},
+		{ID: 4, StationID: "S004", Name: "大学城, 南门", Latitude: 31.24541234, Longitude: 121.48871234},
+	}
+
+	tests := []struct {
+		name          string
+		route         models.Route
+		routeStations []models.RouteStation
+		wantLoop      bool
+	}{
+		{
+			name:  "双向线路",
+			route: models.Route{ID: 1, RouteID: "R1", Name: "1路"},
+			routeStations: append(
+				testGTFSRouteStations(stations, "down", "S004", "S003", "S002", "S001"),
+				testGTFSRouteStations(stations, "up", "S001", "S002", "S003", "S004")...,
+			),
+		},
+		{
+			name:          "单向线路",
+			route:         models.Route{ID: 2, RouteID: "R2", Name: "2路"},
+			routeStations: testGTFSRouteStations(stations, "up", "S002", "S004"),
+		},
+		{
+			name:          "闭合环线",
+			route:         models.Route{ID: 3, RouteID: "R3", Name: "环线"},
+			routeStations: testGTFSRouteStations(stations, "up", "S001", "S002", "S003", "S001"),
+			wantLoop:      true,
+		},
+	}
+
+	stops := &gtfsFile{name: "stops.txt", header: []string{"stop_id", "stop_name", "stop_desc", "stop_lat", "stop_lon"}}
+	routeFile := &gtfsFile{name: "routes.txt", header: []string{"route_id", "agency_id", "route_short_name", "route_long_name", "route_type", "network_id"}}
+	trips := &gtfsFile{name: "trips.txt", header: []string{"route_id", "service_id", "trip_id", "direction_id"}}
+	stopTimes := &gtfsFile{name: "stop_times.txt", header: []string{"trip_id", "arrival_time", "departure_time", "stop_id", "stop_sequence", "shape_dist_traveled", "timepoint"}}
+	addGTFSStops(stations, stops)
+	for idx := range tests {
+		addGTFSRoute("TapTransit", &tests[idx].route, tests[idx].routeStations, routeFile, trips, stopTimes)
+	}
+	data, counts, err := writeGTFSArchive([]*gtfsFile{stops, routeFile, trips, stopTimes})
+	if err != nil {
+		t.Fatalf("生成GTFS压缩包失败: %v", err)
+	}
+	if counts["trips.txt"] != 4 || counts["stop_times.txt"] != 14 {
+		t.Errorf("记录数 = %v", counts)
+	}
+
+	feed, err := parseGTFSFeed(data)
+	if err != nil {
+		t.Fatalf("解析导出的GTFS失败: %v", err)
+	}
+
+	if len(feed.stops) != len(stations) {
+		t.Fatalf("站点数 = %d，应为 %d", len(feed.stops), len(stations))
+	}
+	for idx, stop := range feed.stops {
+		station := stations[idx]
+		if stop.stopID != station.StationID || stop.name != station.Name || stop.desc != station.Address ||
+			stop.lat != roundCoordinate(station.Latitude) || stop.lon != roundCoordinate(station.Longitude) {
+			t.Errorf("站点%s导入后为 %+v", station.StationID, stop)
+		}
+	}
+
+	report := &GTFSImportReport{}
+	patterns := buildGTFSPatterns(feed, report)
+	if len(report.Warnings) > 0 {
+		t.Errorf("导入警告: %v", report.Warnings)
+	}
+	if len(feed.routes) != len(tests) {
+		t.Fatalf("线路数 = %d，应为 %d", len(feed.routes), len(tests))
+	}
+	for idx, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gr := feed.routes[idx]
+			if gr.routeID != tt.route.RouteID || gr.longName != tt.route.Name {
+				t.Errorf("线路导入后为 %+v", gr)
+			}
+
+			byDirection := make(map[string][]models.RouteStation)
+			for _, rs := range tt.routeStations {
+				byDirection[rs.Direction] = append(byDirection[rs.Direction], rs)
+			}
+			routePatterns := patterns[tt.route.RouteID]
+			if len(routePatterns) != len(byDirection) {
+				t.Fatalf("方向数 = %d，应为 %d", len(routePatterns), len(byDirection))
+			}
+			for _, pattern := range routePatterns {
+				current, ok := byDirection[pattern.direction]
+				if !ok {
+					t.Errorf("导入了线路不存在的方向%s", pattern.direction)
+					continue
+				}
+				if fields := diffRouteStations(current, pattern); fieldChanged(fields, "stations") || fieldChanged(fields, "zone_id") {
+					t.Errorf("方向%s站序导入后有变化: %+v", pattern.direction, fields)
+				}
+				loop := pattern.stops[0].stopID == pattern.stops[len(pattern.stops)-1].stopID
+				if loop != tt.wantLoop {
+					t.Errorf("方向%s识别为环线 = %v，应为 %v", pattern.direction, loop, tt.wantLoop)
+				}
+				for i := 1; i < len(pattern.distances); i++ {
+					if pattern.distances[i] < pattern.distances[i-1] {
+						t.Errorf("方向%s累计距离递减: %v", pattern.direction, pattern.distances)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGTFSDirection(t *testing.T) {
+	tests := map[string]string{"": "up", gtfsDirectionUp: "up", gtfsDirectionDown: "down", "2": "up"}
+	for directionID, want := range tests {
+		if got := gtfsDirection(directionID); got != want {
+			t.Errorf("gtfsDirection(%q) = %s，应为 %s", directionID, got, want)
+		}
+	}
+}
+
+// fieldChanged 字段变化中是否包含指定字段
+func fieldChanged(fields []GTFSFieldChange, field string) bool {
+	for _, change := range fields {
+		if change.Field == field {
+			return true
+		}
+	}
+	return false
+}
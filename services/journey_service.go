@@ -0,0 +1,58 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// RefreshJourney 根据行程内已完成的交易记录重新汇总行程（不存在时创建）
+// 每次都按全部乘次重新计算，重复上传或重新计费后结果保持一致
+func RefreshJourney(db *gorm.DB, journeyID string) error {
+	if journeyID == "" {
+		return nil
+	}
+
+	var legs []models.Transaction
+	if err := db.Where("journey_id = ? AND status = 'completed'", journeyID).
+		Order("board_time ASC").Find(&legs).Error; err != nil {
+		return fmt.Errorf("查询行程乘次失败: %w", err)
+	}
+	if len(legs) == 0 {
+		return nil
+	}
+
+	var journey models.Journey
+	if err := db.Where("journey_id = ?", journeyID).FirstOrInit(&journey).Error; err != nil {
+		return fmt.Errorf("查询行程失败: %w", err)
+	}
+
+	first, last := legs[0], legs[len(legs)-1]
+	journey.JourneyID = journeyID
+	journey.CardID = first.CardID
+	journey.StartTime = first.BoardTime
+	journey.StartRouteID = first.RouteID
+	journey.StartStation = first.StartStation
+	journey.StartStationName = first.StartStationName
+	journey.EndRouteID = last.RouteID
+	journey.EndStation = last.EndStation
+	journey.EndStationName = last.EndStationName
+	journey.EndTime = last.BoardTime
+	if last.AlightTime != nil {
+		journey.EndTime = *last.AlightTime
+	}
+	journey.LegCount = len(legs)
+	journey.TransferCount = len(legs) - 1
+	journey.TotalFare, journey.TotalDiscount, journey.TotalActualFare = 0, 0, 0
+	for _, leg := range legs {
+		journey.TotalFare += leg.Fare
+		journey.TotalDiscount += leg.DiscountAmount
+		journey.TotalActualFare += leg.ActualFare
+	}
+
+	if err := db.Save(&journey).Error; err != nil {
+		return fmt.Errorf("保存行程失败: %w", err)
+	}
+	return nil
+}
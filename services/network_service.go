@@ -0,0 +1,368 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// NetworkService 线路、站点与线路站序的维护
+type NetworkService struct {
+	db *gorm.DB
+}
+
+func NewNetworkService(db *gorm.DB) *NetworkService {
+	return &NetworkService{db: db}
+}
+
+// RouteRequest 新增/修改线路请求（最高票价max_fare通过票价配置变更申请修改）
+type RouteRequest struct {
+	RouteID        string `json:"route_id" binding:"required"` // 线路编号
+	Name           string `json:"name" binding:"required"`     // 线路名称
+	FareType       string `json:"fare_type"`                   // 计价模式：uniform, segment, distance, zone（默认uniform）
+	TapMode        string `json:"tap_mode"`                    // 刷卡模式：single_tap, tap_in_out（默认single_tap）
+	DirectionMode  string `json:"direction_mode"`              // 方向模式：single, both, loop（默认both）
+	LoopFarePolicy string `json:"loop_fare_policy"`            // 环线计价策略：shortest, travel（默认shortest）
+	MaxRideMinutes int    `json:"max_ride_minutes"`            // 最长乘车时间（分钟，0表示使用系统默认）
+	Operator       string `json:"operator"`                    // 运营方编码（按运营方配置舍入规则）
+}
+
+// StationRequest 新增/修改站点请求
+type StationRequest struct {
+	StationID  string  `json:"station_id" binding:"required"` // 站点编号
+	Name       string  `json:"name" binding:"required"`       // 站点名称
+	Latitude   float64 `json:"latitude"`                      // 纬度
+	Longitude  float64 `json:"longitude"`                     // 经度
+	Address    string  `json:"address"`                       // 地址
+	IsTransfer bool    `json:"is_transfer"`                   // 是否为换乘站
+}
+
+// RouteStationsRequest 设置线路某一方向的站序（整体替换该方向的站点）
+type RouteStationsRequest struct {
+	Direction string              `json:"direction"`                   // 方向：up, down（环线为行驶方向）
+	Stations  []RouteStationEntry `json:"stations" binding:"required"` // 站点列表（未提供sequence时按列表顺序编号）
+}
+
+// RouteStationEntry 站序中的一个站点
+type RouteStationEntry struct {
+	StationID  uint     `json:"station_id" binding:"required"` // 站点ID（stations.id）
+	Sequence   int      `json:"sequence"`                      // 站序（从1开始连续编号）
+	ZoneID     *string  `json:"zone_id"`                       // 分区ID
+	DistanceKm *float64 `json:"distance_km"`                   // 到线路起点的累计距离（公里）
+}
+
+// ListRoutes 查询线路（status为all时包含停用线路）
+func (s *NetworkService) ListRoutes(status string) ([]models.Route, error) {
+	var routes []models.Route
+	query := s.db.Order("id ASC")
+	switch status {
+	case "all":
+	case "":
+		query = query.Where("status = 'active'")
+	default:
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&routes).Error
+	return routes, err
+}
+
+// CreateRoute 新增线路
+func (s *NetworkService) CreateRoute(req RouteRequest) (*models.Route, error) {
+	route := models.Route{Status: "active"}
+	if err := s.fillRoute(&route, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&route).Error; err != nil {
+		return nil, fmt.Errorf("保存线路失败: %w", err)
+	}
+	return &route, nil
+}
+
+// UpdateRoute 修改线路
+func (s *NetworkService) UpdateRoute(id uint, req RouteRequest) (*models.Route, error) {
+	var route models.Route
+	if err := s.db.First(&route, id).Error; err != nil {
+		return nil, fmt.Errorf("线路不存在")
+	}
+	if err := s.fillRoute(&route, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(&route).Error; err != nil {
+		return nil, fmt.Errorf("保存线路失败: %w", err)
+	}
+	return &route, nil
+}
+
+// SetRouteStatus 启用或停用线路
+func (s *NetworkService) SetRouteStatus(id uint, status string) (*models.Route, error) {
+	var route models.Route
+	if err := s.db.First(&route, id).Error; err != nil {
+		return nil, fmt.Errorf("线路不存在")
+	}
+	route.Status = status
+	if err := s.db.Save(&route).Error; err != nil {
+		return nil, fmt.Errorf("保存线路失败: %w", err)
+	}
+	return &route, nil
+}
+
+// fillRoute 校验并填写线路字段
+func (s *NetworkService) fillRoute(route *models.Route, req RouteRequest) error {
+	req.RouteID = strings.TrimSpace(req.RouteID)
+	if req.RouteID == "" || strings.TrimSpace(req.Name) == "" {
+		return fmt.Errorf("线路编号和线路名称不能为空")
+	}
+	var count int64
+	s.db.Model(&models.Route{}).Where("route_id = ? AND id <> ?", req.RouteID, route.ID).Count(&count)
+	if count > 0 {
+		return fmt.Errorf("线路编号已存在: %s", req.RouteID)
+	}
+
+	fareType := defaultString(req.FareType, "uniform")
+	if !containsString([]string{"uniform", "segment", "distance", "zone"}, fareType) {
+		return fmt.Errorf("计价模式错误: %s（应为uniform、segment、distance或zone）", fareType)
+	}
+	tapMode := defaultString(req.TapMode, "single_tap")
+	if !containsString([]string{"single_tap", "tap_in_out"}, tapMode) {
+		return fmt.Errorf("刷卡模式错误: %s（应为single_tap或tap_in_out）", tapMode)
+	}
+	directionMode := defaultString(req.DirectionMode, DirectionModeBoth)
+	if !containsString([]string{DirectionModeSingle, DirectionModeBoth, DirectionModeLoop}, directionMode) {
+		return fmt.Errorf("方向模式错误: %s（应为single、both或loop）", directionMode)
+	}
+	loopFarePolicy := defaultString(req.LoopFarePolicy, LoopFareShortest)
+	if !containsString([]string{LoopFareShortest, LoopFareTravel}, loopFarePolicy) {
+		return fmt.Errorf("环线计价策略错误: %s（应为shortest或travel）", loopFarePolicy)
+	}
+	if req.MaxRideMinutes < 0 {
+		return fmt.Errorf("最长乘车时间不能为负数")
+	}
+
+	route.RouteID = req.RouteID
+	route.Name = strings.TrimSpace(req.Name)
+	route.FareType = fareType
+	route.TapMode = tapMode
+	route.DirectionMode = directionMode
+	route.LoopFarePolicy = loopFarePolicy
+	route.MaxRideMinutes = req.MaxRideMinutes
+	route.Operator = strings.TrimSpace(req.Operator)
+	return nil
+}
+
+// ListStations 查询站点（status为all时包含停用站点，keyword按编号或名称模糊匹配）
+func (s *NetworkService) ListStations(status, keyword string) ([]models.Station, error) {
+	var stations []models.Station
+	query := s.db.Order("id ASC")
+	switch status {
+	case "all":
+	case "":
+		query = query.Where("status = 'active'")
+	default:
+		query = query.Where("status = ?", status)
+	}
+	if keyword != "" {
+		like := "%" + keyword + "%"
+		query = query.Where("station_id ILIKE ? OR name ILIKE ?", like, like)
+	}
+	err := query.Find(&stations).Error
+	return stations, err
+}
+
+// CreateStation 新增站点
+func (s *NetworkService) CreateStation(req StationRequest) (*models.Station, error) {
+	station := models.Station{Status: "active"}
+	if err := s.fillStation(&station, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&station).Error; err != nil {
+		return nil, fmt.Errorf("保存站点失败: %w", err)
+	}
+	return &station, nil
+}
+
+// UpdateStation 修改站点
+func (s *NetworkService) UpdateStation(id uint, req StationRequest) (*models.Station, error) {
+	var station models.Station
+	if err := s.db.First(&station, id).Error; err != nil {
+		return nil, fmt.Errorf("站点不存在")
+	}
+	if err := s.fillStation(&station, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(&station).Error; err != nil {
+		return nil, fmt.Errorf("保存站点失败: %w", err)
+	}
+	return &station, nil
+}
+
+// SetStationStatus 启用或停用站点（停用前需先从启用线路的站序中移除）
+func (s *NetworkService) SetStationStatus(id uint, status string) (*models.Station, error) {
+	var station models.Station
+	if err := s.db.First(&station, id).Error; err != nil {
+		return nil, fmt.Errorf("站点不存在")
+	}
+	if status != "active" {
+		var routeCodes []string
+		s.db.Model(&models.RouteStation{}).
+			Joins("JOIN routes ON routes.id = route_stations.route_id AND routes.deleted_at IS NULL").
+			Where("route_stations.station_id = ? AND routes.status = 'active'", id).
+			Distinct().Pluck("routes.route_id", &routeCodes)
+		if len(routeCodes) > 0 {
+			return nil, fmt.Errorf("站点仍在启用线路%s的站序中，请先调整站序", strings.Join(routeCodes, "、"))
+		}
+	}
+	station.Status = status
+	if err := s.db.Save(&station).Error; err != nil {
+		return nil, fmt.Errorf("保存站点失败: %w", err)
+	}
+	return &station, nil
+}
+
+// fillStation 校验并填写站点字段
+func (s *NetworkService) fillStation(station *models.Station, req StationRequest) error {
+	req.StationID = strings.TrimSpace(req.StationID)
+	if req.StationID == "" || strings.TrimSpace(req.Name) == "" {
+		return fmt.Errorf("站点编号和站点名称不能为空")
+	}
+	var count int64
+	s.db.Model(&models.Station{}).Where("station_id = ? AND id <> ?", req.StationID, station.ID).Count(&count)
+	if count > 0 {
+		return fmt.Errorf("站点编号已存在: %s", req.StationID)
+	}
+	if req.Latitude < -90 || req.Latitude > 90 || req.Longitude < -180 || req.Longitude > 180 {
+		return fmt.Errorf("经纬度超出范围")
+	}
+
+	station.StationID = req.StationID
+	station.Name = strings.TrimSpace(req.Name)
+	station.Latitude = req.Latitude
+	station.Longitude = req.Longitude
+	station.Address = req.Address
+	station.IsTransfer = req.IsTransfer
+	return nil
+}
+
+// ListRouteStations 查询线路站序（direction为空时返回全部方向）
+func (s *NetworkService) ListRouteStations(routeID uint, direction string) ([]models.RouteStation, error) {
+	var routeStations []models.RouteStation
+	query := s.db.Preload("Station").Where("route_id = ?", routeID)
+	if direction != "" {
+		query = query.Where("direction = ?", direction)
+	}
+	err := query.Order("direction ASC, sequence ASC").Find(&routeStations).Error
+	return routeStations, err
+}
+
+// SetRouteStations 整体替换线路某一方向的站序（新增、移除、调整顺序在一次调用中完成）
+// 校验：站序从1开始连续、站点存在且启用、同一方向站点不重复（闭合环线末站可与首站相同）、累计距离单调不减
+func (s *NetworkService) SetRouteStations(routeID uint, req RouteStationsRequest) ([]models.RouteStation, error) {
+	var route models.Route
+	if err := s.db.First(&route, routeID).Error; err != nil {
+		return nil, fmt.Errorf("线路不存在")
+	}
+	if len(req.Stations) == 0 {
+		return nil, fmt.Errorf("站点列表为空")
+	}
+
+	entries := make([]RouteStationEntry, len(req.Stations))
+	copy(entries, req.Stations)
+	if err := normalizeSequences(entries); err != nil {
+		return nil, err
+	}
+
+	stationIDs := make([]uint, 0, len(entries))
+	for _, entry := range entries {
+		stationIDs = append(stationIDs, entry.StationID)
+	}
+	var stations []models.Station
+	s.db.Where("id IN ?", stationIDs).Find(&stations)
+	stationsByID := make(map[uint]models.Station, len(stations))
+	for _, station := range stations {
+		stationsByID[station.ID] = station
+	}
+
+	seen := make(map[uint]int)
+	routeStations := make([]models.RouteStation, 0, len(entries))
+	for i, entry := range entries {
+		station, ok := stationsByID[entry.StationID]
+		if !ok {
+			return nil, fmt.Errorf("第%d站的站点%d不存在", entry.Sequence, entry.StationID)
+		}
+		if station.Status != "" && station.Status != "active" {
+			return nil, fmt.Errorf("第%d站的站点%s已停用", entry.Sequence, station.StationID)
+		}
+		if previous, ok := seen[entry.StationID]; ok {
+			closedLoop := route.DirectionMode == DirectionModeLoop && previous == 1 && i == len(entries)-1
+			if !closedLoop {
+				return nil, fmt.Errorf("站点%s在第%d站和第%d站重复出现", station.StationID, previous, entry.Sequence)
+			}
+		}
+		if route.FareType == "distance" && entry.DistanceKm == nil {
+			return nil, fmt.Errorf("按距离计价的线路第%d站必须提供累计距离", entry.Sequence)
+		}
+		seen[entry.StationID] = entry.Sequence
+		routeStations = append(routeStations, models.RouteStation{
+			RouteID:    routeID,
+			StationID:  entry.StationID,
+			Sequence:   entry.Sequence,
+			Direction:  req.Direction,
+			ZoneID:     entry.ZoneID,
+			DistanceKm: entry.DistanceKm,
+		})
+	}
+	if err := validateDistanceSequence(routeStations); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("route_id = ? AND direction = ?", routeID, req.Direction).Delete(&models.RouteStation{}).Error; err != nil {
+			return fmt.Errorf("移除原站序失败: %w", err)
+		}
+		if err := tx.Create(&routeStations).Error; err != nil {
+			return fmt.Errorf("保存站序失败: %w", err)
+		}
+		return ValidateRouteDistances(tx, routeID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.ListRouteStations(routeID, req.Direction)
+}
+
+// normalizeSequences 整理站序：全部未提供时按列表顺序编号，否则必须为从1开始的连续编号（按站序排序）
+func normalizeSequences(entries []RouteStationEntry) error {
+	provided := 0
+	for _, entry := range entries {
+		if entry.Sequence != 0 {
+			provided++
+		}
+	}
+	if provided == 0 {
+		for i := range entries {
+			entries[i].Sequence = i + 1
+		}
+		return nil
+	}
+	if provided != len(entries) {
+		return fmt.Errorf("站序需全部提供或全部省略")
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Sequence < entries[j].Sequence })
+	for i, entry := range entries {
+		if entry.Sequence != i+1 {
+			return fmt.Errorf("站序必须从1开始连续编号，第%d个站点的站序为%d", i+1, entry.Sequence)
+		}
+	}
+	return nil
+}
+
+// defaultString 值为空时返回默认值
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -66,14 +66,16 @@ func (s *PenaltyService) processPenaltyFare(transaction *models.Transaction) err
 	}
 
 	// 使用罚款计费逻辑计算费用
-	fareResult, err := s.fareService.CalculateFareV2(
-		transaction.CardID,
-		transaction.RouteID,
-		transaction.StartStation,
-		nil, // 没有下车站点
-		transaction.BoardTime,
-		true, // 是罚款计费
-	)
+	fareResult, err := s.fareService.Calculate(FareRequest{
+		CardID:         transaction.CardID,
+		RouteID:        transaction.RouteID,
+		StartStationID: transaction.StartStation,
+		EndStationID:   nil, // 没有下车站点
+		BoardTime:      transaction.BoardTime,
+		PenaltyFare:    true, // 是罚款计费
+		Direction:      transaction.Direction,
+		Passengers:     passengersFromComposition(transaction.GroupComposition),
+	})
 	if err != nil {
 		return fmt.Errorf("计算罚款费用失败: %w", err)
 	}
@@ -84,6 +86,10 @@ func (s *PenaltyService) processPenaltyFare(transaction *models.Transaction) err
 	transaction.DiscountType = fareResult.DiscountType
 	transaction.DiscountAmount = fareResult.DiscountAmount
 	transaction.PenaltyFare = fareResult.PenaltyFare
+	transaction.FareTrace = fareResult.Trace
+	transaction.JourneyID = fareResult.JourneyID
+	transaction.PassengerCount = fareResult.PassengerCount
+	transaction.GroupComposition = fareResult.Passengers
 	transaction.Status = "completed"
 	// EndStation保持为nil，AlightTime保持为nil（表示未下车）
 
@@ -94,6 +100,9 @@ func (s *PenaltyService) processPenaltyFare(transaction *models.Transaction) err
 	if err := s.db.Save(transaction).Error; err != nil {
 		return fmt.Errorf("更新交易记录失败: %w", err)
 	}
+	if err := RefreshJourney(s.db, transaction.JourneyID); err != nil {
+		fmt.Printf("更新行程失败: %v\n", err)
+	}
 
 	return nil
 }
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ProductService struct {
+	db *gorm.DB
+}
+
+func NewProductService(db *gorm.DB) *ProductService {
+	return &ProductService{db: db}
+}
+
+// PurchaseProductRequest 购买票卡产品请求
+type PurchaseProductRequest struct {
+	ProductCode string        `json:"product_code" binding:"required"` // 产品编号
+	ValidFrom   *FlexibleTime `json:"valid_from"`                      // 生效时间（为空时：首次乘车激活的产品待激活，其他产品立即生效）
+}
+
+// ListProducts 查询在售的票卡产品
+func (s *ProductService) ListProducts() ([]models.Product, error) {
+	var products []models.Product
+	err := s.db.Where("status = 'active'").Order("id ASC").Find(&products).Error
+	return products, err
+}
+
+// PurchaseProduct 为卡片购买票卡产品
+func (s *ProductService) PurchaseProduct(cardID string, req PurchaseProductRequest) (*models.CardProduct, error) {
+	var card models.Card
+	if err := s.db.Where("card_id = ?", cardID).First(&card).Error; err != nil {
+		return nil, fmt.Errorf("卡片不存在")
+	}
+	if card.Status != "active" {
+		return nil, fmt.Errorf("卡片状态异常: %s", card.Status)
+	}
+
+	var product models.Product
+	if err := s.db.Where("product_code = ? AND status = 'active'", req.ProductCode).First(&product).Error; err != nil {
+		return nil, fmt.Errorf("产品不存在或已停售: %s", req.ProductCode)
+	}
+	if !listContains(product.AllowedCardTypes, card.CardType) {
+		return nil, fmt.Errorf("卡类型%s不能购买该产品", card.CardType)
+	}
+
+	now := time.Now()
+	cardProduct := models.CardProduct{
+		CardID:       cardID,
+		ProductID:    product.ID,
+		PurchaseTime: now,
+		PricePaid:    product.Price,
+		Status:       CardProductActive,
+	}
+	if req.ValidFrom == nil && product.ActivateOnFirstUse {
+		cardProduct.Status = CardProductPendingActivation
+	} else {
+		start := now
+		if req.ValidFrom != nil && !req.ValidFrom.IsZero() {
+			start = req.ValidFrom.Time
+		}
+		validFrom, validUntil := passValidity(&product, start)
+		cardProduct.ValidFrom = &validFrom
+		cardProduct.ValidUntil = &validUntil
+	}
+
+	if err := s.db.Create(&cardProduct).Error; err != nil {
+		return nil, fmt.Errorf("保存购买记录失败: %w", err)
+	}
+	cardProduct.Product = product
+	return &cardProduct, nil
+}
+
+// ListCardProducts 查询卡片购买的票卡产品
+func (s *ProductService) ListCardProducts(cardID string) ([]models.CardProduct, error) {
+	var cardProducts []models.CardProduct
+	err := s.db.Preload("Product").Where("card_id = ?", cardID).
+		Order("purchase_time DESC").Find(&cardProducts).Error
+	return cardProducts, err
+}
+
+// RecordPassUse 记录票卡的一次使用（待激活的票卡从本次乘车起生效）
+func RecordPassUse(db *gorm.DB, cardProductID uint, boardTime time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var cardProduct models.CardProduct
+		if err := tx.Preload("Product").First(&cardProduct, cardProductID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("票卡不存在: %d", cardProductID)
+			}
+			return err
+		}
+
+		updates := map[string]interface{}{"ride_count": gorm.Expr("ride_count + 1")}
+		if cardProduct.FirstUsedAt == nil {
+			updates["first_used_at"] = boardTime
+		}
+		if cardProduct.Status == CardProductPendingActivation {
+			validFrom, validUntil := passValidity(&cardProduct.Product, boardTime)
+			updates["status"] = CardProductActive
+			updates["valid_from"] = validFrom
+			updates["valid_until"] = validUntil
+		}
+		return tx.Model(&cardProduct).Updates(updates).Error
+	})
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"TapTransit-backend/config"
 	"TapTransit-backend/models"
 	"TapTransit-backend/utils"
 	"encoding/json"
@@ -14,13 +15,23 @@ import (
 type UploadService struct {
 	db          *gorm.DB
 	fareService *FareService
+
+	maxPassengers int // 单条记录最多乘客人数（含持卡人）
 }
 
+// defaultMaxPassengersPerRecord 单条记录默认最多乘客人数（含持卡人）
+const defaultMaxPassengersPerRecord = 10
+
 func NewUploadService(db *gorm.DB, fareService *FareService) *UploadService {
-	return &UploadService{
-		db:          db,
-		fareService: fareService,
+	s := &UploadService{
+		db:            db,
+		fareService:   fareService,
+		maxPassengers: defaultMaxPassengersPerRecord,
 	}
+	if config.AppConfig != nil && config.AppConfig.Fare.MaxPassengersPerRecord > 0 {
+		s.maxPassengers = config.AppConfig.Fare.MaxPassengersPerRecord
+	}
+	return s
 }
 
 // BatchRecordRequest 网关上传的批量记录请求
@@ -33,6 +44,52 @@ type BatchRecordRequest struct {
 	AlightStation string    `json:"alight_station"`
 	RouteID       uint      `json:"route_id"`
 	GatewayID     string    `json:"gateway_id"`
+	Direction     string    `json:"direction"` // 行驶方向（up/down，网关根据车辆运营方向上报，可选）
+
+	PassengerCount int              `json:"passenger_count"` // 乘客人数（含持卡人，可选，未提供passengers时其余乘客按普通成人计费）
+	Passengers     []PassengerGroup `json:"passengers"`      // 同行乘客类别与人数（不含持卡人，可选）
+}
+
+// groupPassengers 记录中的同行乘客（不含持卡人）
+func (r *BatchRecordRequest) groupPassengers() []PassengerGroup {
+	if len(r.Passengers) > 0 {
+		return r.Passengers
+	}
+	if r.PassengerCount > 1 {
+		return []PassengerGroup{{Category: PassengerAdult, Count: r.PassengerCount - 1}}
+	}
+	return nil
+}
+
+// validatePassengers 校验记录的乘客人数与同行乘客：人数须为正数、类别须可计费、
+// 同时上报passenger_count与passengers时两者须一致，合计人数不超过maxPassengers
+func (r *BatchRecordRequest) validatePassengers(maxPassengers int, knownCategory func(string) bool) error {
+	if r.PassengerCount < 0 {
+		return fmt.Errorf("乘客人数不能为负数: %d", r.PassengerCount)
+	}
+	total := 1
+	for _, group := range r.Passengers {
+		if group.Count <= 0 {
+			return fmt.Errorf("同行乘客%s的人数应为正数: %d", group.Category, group.Count)
+		}
+		if !knownCategory(group.Category) {
+			return fmt.Errorf("未知的同行乘客类别: %q", group.Category)
+		}
+		total += group.Count
+		if total > maxPassengers {
+			break
+		}
+	}
+	if len(r.Passengers) > 0 && r.PassengerCount > 0 && r.PassengerCount != total {
+		return fmt.Errorf("乘客人数%d与同行乘客合计%d人（含持卡人）不一致", r.PassengerCount, total)
+	}
+	if r.PassengerCount > total {
+		total = r.PassengerCount
+	}
+	if total > maxPassengers {
+		return fmt.Errorf("乘客人数超过单条记录上限%d人", maxPassengers)
+	}
+	return nil
 }
 
 // FlexibleTime supports unix seconds (number or string) and RFC3339.
@@ -82,6 +139,19 @@ func (t *FlexibleTime) parseString(value string) error {
 func (s *UploadService) UploadBatchRecords(records []BatchRecordRequest) (int, error) {
 	var successCount int
 
+	// 上传记录的网关视为在线
+	now := time.Now()
+	touched := make(map[string]bool)
+	for _, record := range records {
+		if record.GatewayID == "" || touched[record.GatewayID] {
+			continue
+		}
+		touched[record.GatewayID] = true
+		if _, err := touchDevice(s.db, record.GatewayID, now); err != nil {
+			fmt.Printf("更新网关 %s 在线状态失败: %v\n", record.GatewayID, err)
+		}
+	}
+
 	for _, record := range records {
 		if err := s.processSingleRecord(record); err != nil {
 			// 记录错误但继续处理下一条
@@ -99,6 +169,9 @@ func (s *UploadService) processSingleRecord(record BatchRecordRequest) error {
 	if record.BoardTime.IsZero() {
 		return fmt.Errorf("上车时间缺失")
 	}
+	if err := record.validatePassengers(s.maxPassengers, s.fareService.knownPassengerCategory); err != nil {
+		return err
+	}
 	// 解析站点信息（格式：线路ID-站点名称 或 站点ID）
 	startStationID, startStationName, err := s.parseStation(record.BoardStation)
 	if err != nil {
@@ -114,8 +187,16 @@ func (s *UploadService) processSingleRecord(record BatchRecordRequest) error {
 		}
 	}
 
-	// 如果没有路由ID，尝试从站点推断
+	// 如果没有路由ID，优先按网关所在车辆的排班确定线路与方向，其次从站点推断
 	routeID := record.RouteID
+	if routeID == 0 {
+		if assignment, ok := FindGatewayAssignment(s.db, record.GatewayID, record.BoardTime.Time); ok {
+			routeID = assignment.RouteID
+			if record.Direction == "" {
+				record.Direction = assignment.Direction
+			}
+		}
+	}
 	if routeID == 0 {
 		routeID, _ = s.inferRouteFromStation(startStationID)
 	}
@@ -193,6 +274,7 @@ func (s *UploadService) processSingleRecord(record BatchRecordRequest) error {
 		EndStationName:   endStationName,
 		BoardTime:        boardTime,
 		GatewayID:        record.GatewayID,
+		Direction:        record.Direction,
 		Status:           "pending",
 	}
 
@@ -208,9 +290,14 @@ func (s *UploadService) processSingleRecord(record BatchRecordRequest) error {
 
 // parseStation 解析站点信息（简化版，实际可能需要更复杂的解析逻辑）
 func (s *UploadService) parseStation(stationInfo string) (uint, string, error) {
+	return findStation(s.db, stationInfo)
+}
+
+// findStation 按站点编号或名称查找站点
+func findStation(db *gorm.DB, stationInfo string) (uint, string, error) {
 	// 尝试查找站点（通过station_id或name匹配）
 	var station models.Station
-	err := s.db.Where("station_id = ? OR name = ?", stationInfo, stationInfo).First(&station).Error
+	err := db.Where("station_id = ? OR name = ?", stationInfo, stationInfo).First(&station).Error
 	if err == nil {
 		return station.ID, station.Name, nil
 	}
@@ -235,15 +322,16 @@ func (s *UploadService) processSingleTapMode(record BatchRecordRequest, route mo
 		fmt.Printf("记录TapEvent失败: %v\n", err)
 	}
 
-	// 使用新的计费逻辑 CalculateFareV2
-	fareResult, err := s.fareService.CalculateFareV2(
-		record.CardID,
-		route.ID,
-		startStationID,
-		endStationPtr, // single_tap模式下可能为nil
-		boardTime,
-		false, // 不是罚款计费
-	)
+	// 通过计费流水线计算费用
+	fareResult, err := s.fareService.CalculateWithCampaignReservation(FareRequest{
+		CardID:         record.CardID,
+		RouteID:        route.ID,
+		StartStationID: startStationID,
+		EndStationID:   endStationPtr, // single_tap模式下可能为nil
+		BoardTime:      boardTime,
+		Direction:      record.Direction,
+		Passengers:     record.groupPassengers(),
+	})
 	if err != nil {
 		return fmt.Errorf("计算费用失败: %w", err)
 	}
@@ -253,6 +341,12 @@ func (s *UploadService) processSingleTapMode(record BatchRecordRequest, route mo
 	transaction.DiscountType = fareResult.DiscountType
 	transaction.DiscountAmount = fareResult.DiscountAmount
 	transaction.PenaltyFare = fareResult.PenaltyFare
+	transaction.FareTrace = fareResult.Trace
+	transaction.Direction = fareResult.Direction
+	transaction.JourneyID = fareResult.JourneyID
+	transaction.PassID = fareResult.PassID
+	transaction.PassengerCount = fareResult.PassengerCount
+	transaction.GroupComposition = fareResult.Passengers
 	transaction.Status = "completed"
 
 	// 更新数据库中的月度累计金额
@@ -265,8 +359,10 @@ func (s *UploadService) processSingleTapMode(record BatchRecordRequest, route mo
 
 	// 保存交易记录
 	if err := s.db.Create(&transaction).Error; err != nil {
+		s.releaseCampaign(fareResult)
 		return fmt.Errorf("保存交易记录失败: %w", err)
 	}
+	s.afterTransactionSaved(&transaction, fareResult)
 
 	return nil
 }
@@ -293,15 +389,16 @@ func (s *UploadService) processTapInOutMode(record BatchRecordRequest, route mod
 			pendingTransaction.EndStationName = endStationName
 			pendingTransaction.AlightTime = alightTime
 
-			// 使用新的计费逻辑 CalculateFareV2（使用pending交易的上车站点信息）
-			fareResult, err := s.fareService.CalculateFareV2(
-				record.CardID,
-				route.ID,
-				pendingTransaction.StartStation,
-				&endStationID,
-				pendingTransaction.BoardTime,
-				false, // 不是罚款计费（有下车站点）
-			)
+			// 通过计费流水线计算费用（使用pending交易的上车站点信息）
+			fareResult, err := s.fareService.CalculateWithCampaignReservation(FareRequest{
+				CardID:         record.CardID,
+				RouteID:        route.ID,
+				StartStationID: pendingTransaction.StartStation,
+				EndStationID:   &endStationID,
+				BoardTime:      pendingTransaction.BoardTime,
+				Direction:      tripDirection(pendingTransaction.Direction, record.Direction),
+				Passengers:     tripPassengers(pendingTransaction.GroupComposition, record),
+			})
 			if err != nil {
 				return fmt.Errorf("计算费用失败: %w", err)
 			}
@@ -311,6 +408,12 @@ func (s *UploadService) processTapInOutMode(record BatchRecordRequest, route mod
 			pendingTransaction.DiscountType = fareResult.DiscountType
 			pendingTransaction.DiscountAmount = fareResult.DiscountAmount
 			pendingTransaction.PenaltyFare = fareResult.PenaltyFare
+			pendingTransaction.FareTrace = fareResult.Trace
+			pendingTransaction.Direction = fareResult.Direction
+			pendingTransaction.JourneyID = fareResult.JourneyID
+			pendingTransaction.PassID = fareResult.PassID
+			pendingTransaction.PassengerCount = fareResult.PassengerCount
+			pendingTransaction.GroupComposition = fareResult.Passengers
 			pendingTransaction.Status = "completed"
 
 			// 更新数据库中的月度累计金额
@@ -322,8 +425,10 @@ func (s *UploadService) processTapInOutMode(record BatchRecordRequest, route mod
 
 			// 更新pending交易为完成状态
 			if err := s.db.Save(&pendingTransaction).Error; err != nil {
+				s.releaseCampaign(fareResult)
 				return fmt.Errorf("更新交易记录失败: %w", err)
 			}
+			s.afterTransactionSaved(&pendingTransaction, fareResult)
 
 			return nil
 		} else {
@@ -343,15 +448,16 @@ func (s *UploadService) processTapInOutMode(record BatchRecordRequest, route mod
 			transaction.EndStationName = endStationName
 			transaction.AlightTime = alightTime
 
-			// 使用新的计费逻辑 CalculateFareV2
-			fareResult, err := s.fareService.CalculateFareV2(
-				record.CardID,
-				route.ID,
-				startStationID,
-				&endStationID,
-				boardTime,
-				false, // 不是罚款计费（有下车站点）
-			)
+			// 通过计费流水线计算费用
+			fareResult, err := s.fareService.CalculateWithCampaignReservation(FareRequest{
+				CardID:         record.CardID,
+				RouteID:        route.ID,
+				StartStationID: startStationID,
+				EndStationID:   &endStationID,
+				BoardTime:      boardTime,
+				Direction:      record.Direction,
+				Passengers:     record.groupPassengers(),
+			})
 			if err != nil {
 				return fmt.Errorf("计算费用失败: %w", err)
 			}
@@ -361,6 +467,12 @@ func (s *UploadService) processTapInOutMode(record BatchRecordRequest, route mod
 			transaction.DiscountType = fareResult.DiscountType
 			transaction.DiscountAmount = fareResult.DiscountAmount
 			transaction.PenaltyFare = fareResult.PenaltyFare
+			transaction.FareTrace = fareResult.Trace
+			transaction.Direction = fareResult.Direction
+			transaction.JourneyID = fareResult.JourneyID
+			transaction.PassID = fareResult.PassID
+			transaction.PassengerCount = fareResult.PassengerCount
+			transaction.GroupComposition = fareResult.Passengers
 			transaction.Status = "completed"
 
 			// 更新数据库中的月度累计金额
@@ -372,8 +484,10 @@ func (s *UploadService) processTapInOutMode(record BatchRecordRequest, route mod
 
 			// 保存交易记录
 			if err := s.db.Create(&transaction).Error; err != nil {
+				s.releaseCampaign(fareResult)
 				return fmt.Errorf("保存交易记录失败: %w", err)
 			}
+			s.afterTransactionSaved(&transaction, fareResult)
 
 			return nil
 		}
@@ -388,6 +502,7 @@ func (s *UploadService) processTapInOutMode(record BatchRecordRequest, route mod
 		transaction.Status = "pending"
 		transaction.Fare = 0
 		transaction.ActualFare = 0
+		transaction.PassengerCount, transaction.GroupComposition = pendingGroup(record)
 
 		// 检查是否已有pending交易（同一张卡的pending交易）
 		var existingPending models.Transaction
@@ -412,6 +527,68 @@ func (s *UploadService) processTapInOutMode(record BatchRecordRequest, route mod
 	}
 }
 
+// afterTransactionSaved 交易完成后记录票卡使用、活动核销并更新所属行程的汇总（失败不影响交易记录）
+func (s *UploadService) afterTransactionSaved(transaction *models.Transaction, fareResult *FareCalculationResult) {
+	if fareResult.PassID != nil {
+		if err := RecordPassUse(s.db, *fareResult.PassID, transaction.BoardTime); err != nil {
+			fmt.Printf("记录票卡使用失败: %v\n", err)
+		}
+	}
+	if fareResult.CampaignID != nil {
+		if err := RecordCampaignRedemption(s.db, *fareResult.CampaignID, transaction, fareResult.CampaignDiscount); err != nil {
+			fmt.Printf("%v\n", err)
+		}
+	}
+	if err := RefreshJourney(s.db, transaction.JourneyID); err != nil {
+		fmt.Printf("更新行程失败: %v\n", err)
+	}
+}
+
+// releaseCampaign 交易未能保存时释放计费时预留的活动优惠
+func (s *UploadService) releaseCampaign(fareResult *FareCalculationResult) {
+	if fareResult.CampaignID == nil {
+		return
+	}
+	if err := ReleaseCampaignUsage(s.db, *fareResult.CampaignID, fareResult.CampaignDiscount); err != nil {
+		fmt.Printf("%v\n", err)
+	}
+}
+
+// pendingGroup 待完成交易记录的乘客人数与同行乘客构成（票价在下车刷卡计费时填写）
+func pendingGroup(record BatchRecordRequest) (int, models.GroupComposition) {
+	passengers := record.groupPassengers()
+	if len(passengers) == 0 {
+		return 1, nil
+	}
+	count := 1
+	composition := models.GroupComposition{{Category: PassengerCardholder, Count: 1}}
+	for _, group := range passengers {
+		count += group.Count
+		composition = append(composition, models.PassengerFare{
+			Category: group.Category,
+			CardType: passengerCardType(group.Category),
+			Count:    group.Count,
+		})
+	}
+	return count, composition
+}
+
+// tripPassengers 行程的同行乘客：优先使用下车刷卡时上报的同行乘客，其次使用上车刷卡时记录的同行乘客构成
+func tripPassengers(pendingComposition models.GroupComposition, record BatchRecordRequest) []PassengerGroup {
+	if passengers := record.groupPassengers(); len(passengers) > 0 {
+		return passengers
+	}
+	return passengersFromComposition(pendingComposition)
+}
+
+// tripDirection 行程的行驶方向：优先使用上车刷卡时网关上报的方向，其次使用下车刷卡时上报的方向
+func tripDirection(boardDirection, alightDirection string) string {
+	if boardDirection != "" {
+		return boardDirection
+	}
+	return alightDirection
+}
+
 // createTapEvent 创建TapEvent记录
 func (s *UploadService) createTapEvent(recordID string, cardID string, routeID uint, stationID uint, stationName string, tapType string, tapTime time.Time, gatewayID string) error {
 	tapEvent := models.TapEvent{
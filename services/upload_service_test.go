@@ -0,0 +1,39 @@
+package services
+
+import "testing"
+
+func TestValidatePassengers(t *testing.T) {
+	known := func(category string) bool {
+		switch category {
+		case PassengerAdult, PassengerCompanion, "student":
+			return true
+		}
+		return false
+	}
+	tests := []struct {
+		name    string
+		record  BatchRecordRequest
+		wantErr bool
+	}{
+		{name: "未上报同行乘客", record: BatchRecordRequest{}},
+		{name: "只上报乘客人数", record: BatchRecordRequest{PassengerCount: 3}},
+		{name: "只上报同行乘客", record: BatchRecordRequest{Passengers: []PassengerGroup{{Category: PassengerCompanion, Count: 1}, {Category: "student", Count: 2}}}},
+		{name: "人数与同行乘客一致", record: BatchRecordRequest{PassengerCount: 4, Passengers: []PassengerGroup{{Category: PassengerAdult, Count: 3}}}},
+		{name: "达到人数上限", record: BatchRecordRequest{PassengerCount: 5}},
+		{name: "乘客人数为负数", record: BatchRecordRequest{PassengerCount: -2}, wantErr: true},
+		{name: "同行乘客人数为0", record: BatchRecordRequest{Passengers: []PassengerGroup{{Category: PassengerAdult, Count: 0}}}, wantErr: true},
+		{name: "同行乘客人数为负数", record: BatchRecordRequest{Passengers: []PassengerGroup{{Category: PassengerAdult, Count: -1}}}, wantErr: true},
+		{name: "未知的乘客类别", record: BatchRecordRequest{Passengers: []PassengerGroup{{Category: "vip", Count: 1}}}, wantErr: true},
+		{name: "人数与同行乘客不一致", record: BatchRecordRequest{PassengerCount: 2, Passengers: []PassengerGroup{{Category: PassengerAdult, Count: 3}}}, wantErr: true},
+		{name: "乘客人数超过上限", record: BatchRecordRequest{PassengerCount: 6}, wantErr: true},
+		{name: "同行乘客合计超过上限", record: BatchRecordRequest{Passengers: []PassengerGroup{{Category: PassengerAdult, Count: 2}, {Category: "student", Count: 1 << 30}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.record.validatePassengers(5, known)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validatePassengers() error = %v，应%s", err, map[bool]string{true: "返回错误", false: "通过"}[tt.wantErr])
+			}
+		})
+	}
+}
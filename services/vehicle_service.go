@@ -0,0 +1,252 @@
+package services
+
+import (
+	"TapTransit-backend/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// VehicleService 车辆、网关设备绑定与车辆排班
+type VehicleService struct {
+	db *gorm.DB
+}
+
+func NewVehicleService(db *gorm.DB) *VehicleService {
+	return &VehicleService{db: db}
+}
+
+// VehicleRequest 新增/修改车辆请求
+type VehicleRequest struct {
+	VehicleNumber string `json:"vehicle_number" binding:"required"` // 车辆编号
+	PlateNumber   string `json:"plate_number"`                      // 车牌号
+	VehicleType   string `json:"vehicle_type"`                      // 车型
+	Capacity      int    `json:"capacity"`                          // 额定载客人数
+	Status        string `json:"status"`                            // 状态：active, inactive, maintenance（默认active）
+}
+
+// BindDeviceRequest 绑定网关设备请求
+type BindDeviceRequest struct {
+	DeviceID string `json:"device_id" binding:"required"` // 网关设备ID（即上传记录中的gateway_id，设备不存在时自动登记）
+}
+
+// AssignmentRequest 车辆排班请求
+type AssignmentRequest struct {
+	RouteID   uint          `json:"route_id" binding:"required"`   // 线路ID
+	Direction string        `json:"direction"`                     // 行驶方向：up, down（为空表示不限定）
+	StartTime FlexibleTime  `json:"start_time" binding:"required"` // 开始时间
+	EndTime   *FlexibleTime `json:"end_time"`                      // 结束时间（为空表示长期有效）
+}
+
+// EndAssignmentRequest 结束排班请求
+type EndAssignmentRequest struct {
+	EndTime *FlexibleTime `json:"end_time"` // 结束时间（为空表示当前时间）
+}
+
+// ListVehicles 查询车辆（status为空时返回全部）
+func (s *VehicleService) ListVehicles(status string) ([]models.Vehicle, error) {
+	var vehicles []models.Vehicle
+	query := s.db.Order("vehicle_number ASC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&vehicles).Error
+	return vehicles, err
+}
+
+// CreateVehicle 新增车辆
+func (s *VehicleService) CreateVehicle(req VehicleRequest) (*models.Vehicle, error) {
+	var vehicle models.Vehicle
+	if err := s.fillVehicle(&vehicle, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&vehicle).Error; err != nil {
+		return nil, fmt.Errorf("保存车辆失败: %w", err)
+	}
+	return &vehicle, nil
+}
+
+// UpdateVehicle 修改车辆（车辆编号变化时同步到已绑定的设备）
+func (s *VehicleService) UpdateVehicle(id uint, req VehicleRequest) (*models.Vehicle, error) {
+	var vehicle models.Vehicle
+	if err := s.db.First(&vehicle, id).Error; err != nil {
+		return nil, fmt.Errorf("车辆不存在")
+	}
+	if err := s.fillVehicle(&vehicle, req); err != nil {
+		return nil, err
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&vehicle).Error; err != nil {
+			return fmt.Errorf("保存车辆失败: %w", err)
+		}
+		return tx.Model(&models.Device{}).Where("vehicle_id = ?", vehicle.ID).
+			Update("vehicle_number", vehicle.VehicleNumber).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+// fillVehicle 校验并填写车辆字段
+func (s *VehicleService) fillVehicle(vehicle *models.Vehicle, req VehicleRequest) error {
+	req.VehicleNumber = strings.TrimSpace(req.VehicleNumber)
+	if req.VehicleNumber == "" {
+		return fmt.Errorf("车辆编号不能为空")
+	}
+	var count int64
+	s.db.Model(&models.Vehicle{}).Where("vehicle_number = ? AND id <> ?", req.VehicleNumber, vehicle.ID).Count(&count)
+	if count > 0 {
+		return fmt.Errorf("车辆编号已存在: %s", req.VehicleNumber)
+	}
+	status := defaultString(req.Status, "active")
+	if !containsString([]string{"active", "inactive", "maintenance"}, status) {
+		return fmt.Errorf("车辆状态错误: %s（应为active、inactive或maintenance）", status)
+	}
+	if req.Capacity < 0 {
+		return fmt.Errorf("额定载客人数不能为负数")
+	}
+
+	vehicle.VehicleNumber = req.VehicleNumber
+	vehicle.PlateNumber = req.PlateNumber
+	vehicle.VehicleType = req.VehicleType
+	vehicle.Capacity = req.Capacity
+	vehicle.Status = status
+	return nil
+}
+
+// BindDevice 将网关设备绑定到车辆（设备原绑定的车辆自动解除）
+func (s *VehicleService) BindDevice(vehicleID uint, req BindDeviceRequest) (*models.Device, error) {
+	var vehicle models.Vehicle
+	if err := s.db.First(&vehicle, vehicleID).Error; err != nil {
+		return nil, fmt.Errorf("车辆不存在")
+	}
+	deviceID := strings.TrimSpace(req.DeviceID)
+	if deviceID == "" {
+		return nil, fmt.Errorf("设备ID不能为空")
+	}
+
+	var device models.Device
+	err := s.db.Where("device_id = ?", deviceID).First(&device).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("查询设备失败: %w", err)
+	}
+	if err == gorm.ErrRecordNotFound {
+		device = models.Device{DeviceID: deviceID, DeviceType: "gateway", Status: "active"}
+	}
+	device.VehicleID = &vehicle.ID
+	device.VehicleNumber = vehicle.VehicleNumber
+	if err := s.db.Save(&device).Error; err != nil {
+		return nil, fmt.Errorf("保存设备失败: %w", err)
+	}
+	return &device, nil
+}
+
+// ListAssignments 查询车辆排班（按开始时间倒序）
+func (s *VehicleService) ListAssignments(vehicleID uint) ([]models.VehicleAssignment, error) {
+	var assignments []models.VehicleAssignment
+	err := s.db.Preload("Route").Where("vehicle_id = ?", vehicleID).
+		Order("start_time DESC").Find(&assignments).Error
+	return assignments, err
+}
+
+// CreateAssignment 新增车辆排班（同一车辆的排班时间段不能重叠）
+func (s *VehicleService) CreateAssignment(vehicleID uint, req AssignmentRequest) (*models.VehicleAssignment, error) {
+	var vehicle models.Vehicle
+	if err := s.db.First(&vehicle, vehicleID).Error; err != nil {
+		return nil, fmt.Errorf("车辆不存在")
+	}
+	var route models.Route
+	if err := s.db.First(&route, req.RouteID).Error; err != nil {
+		return nil, fmt.Errorf("线路不存在")
+	}
+	if route.Status != "active" {
+		return nil, fmt.Errorf("线路%s已停用", route.RouteID)
+	}
+	if req.Direction != "" && req.Direction != "up" && req.Direction != "down" {
+		return nil, fmt.Errorf("行驶方向错误: %s（应为up或down）", req.Direction)
+	}
+	if req.StartTime.IsZero() {
+		return nil, fmt.Errorf("开始时间不能为空")
+	}
+
+	assignment := models.VehicleAssignment{
+		VehicleID: vehicleID,
+		RouteID:   route.ID,
+		Direction: req.Direction,
+		StartTime: req.StartTime.Time,
+	}
+	if req.EndTime != nil && !req.EndTime.IsZero() {
+		endTime := req.EndTime.Time
+		if !endTime.After(assignment.StartTime) {
+			return nil, fmt.Errorf("结束时间必须晚于开始时间")
+		}
+		assignment.EndTime = &endTime
+	}
+	if err := s.checkAssignmentOverlap(&assignment); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&assignment).Error; err != nil {
+		return nil, fmt.Errorf("保存排班失败: %w", err)
+	}
+	assignment.Route = route
+	return &assignment, nil
+}
+
+// EndAssignment 结束车辆排班（结束时间不能早于开始时间，不能延长已结束的排班）
+func (s *VehicleService) EndAssignment(vehicleID, assignmentID uint, req EndAssignmentRequest) (*models.VehicleAssignment, error) {
+	var assignment models.VehicleAssignment
+	if err := s.db.Where("vehicle_id = ?", vehicleID).First(&assignment, assignmentID).Error; err != nil {
+		return nil, fmt.Errorf("排班不存在")
+	}
+	endTime := time.Now()
+	if req.EndTime != nil && !req.EndTime.IsZero() {
+		endTime = req.EndTime.Time
+	}
+	if !endTime.After(assignment.StartTime) {
+		return nil, fmt.Errorf("结束时间必须晚于开始时间")
+	}
+	if assignment.EndTime != nil && endTime.After(*assignment.EndTime) {
+		return nil, fmt.Errorf("排班已于%s结束", assignment.EndTime.Format(time.RFC3339))
+	}
+	assignment.EndTime = &endTime
+	if err := s.db.Save(&assignment).Error; err != nil {
+		return nil, fmt.Errorf("保存排班失败: %w", err)
+	}
+	return &assignment, nil
+}
+
+// checkAssignmentOverlap 检查排班与同一车辆的其他排班时间段是否重叠
+func (s *VehicleService) checkAssignmentOverlap(assignment *models.VehicleAssignment) error {
+	query := s.db.Model(&models.VehicleAssignment{}).
+		Where("vehicle_id = ? AND id <> ?", assignment.VehicleID, assignment.ID).
+		Where("end_time IS NULL OR end_time > ?", assignment.StartTime)
+	if assignment.EndTime != nil {
+		query = query.Where("start_time < ?", *assignment.EndTime)
+	}
+	var existing models.VehicleAssignment
+	if err := query.Order("start_time ASC").First(&existing).Error; err == nil {
+		return fmt.Errorf("与排班#%d的时间段重叠", existing.ID)
+	}
+	return nil
+}
+
+// FindGatewayAssignment 按网关设备 -> 车辆 -> 排班查找网关在指定时间运营的线路与方向
+func FindGatewayAssignment(db *gorm.DB, gatewayID string, at time.Time) (*models.VehicleAssignment, bool) {
+	if gatewayID == "" {
+		return nil, false
+	}
+	var device models.Device
+	if err := db.Where("device_id = ?", gatewayID).First(&device).Error; err != nil || device.VehicleID == nil {
+		return nil, false
+	}
+	var assignment models.VehicleAssignment
+	err := db.Where("vehicle_id = ? AND start_time <= ? AND (end_time IS NULL OR end_time > ?)", *device.VehicleID, at, at).
+		Order("start_time DESC").First(&assignment).Error
+	if err != nil {
+		return nil, false
+	}
+	return &assignment, true
+}
@@ -33,17 +33,45 @@ func InitDatabase(cfg *config.Config) (*gorm.DB, error) {
 		{"cards", &models.Card{}},
 		{"routes", &models.Route{}},
 		{"stations", &models.Station{}},
+		{"vehicles", &models.Vehicle{}},
 		{"devices", &models.Device{}},
 		{"users", &models.User{}},
+		{"user_sessions", &models.UserSession{}},
 		{"discount_policies", &models.DiscountPolicy{}},
+		{"discount_stacking_policies", &models.DiscountStackingPolicy{}},
+		{"products", &models.Product{}},
+		{"companion_rules", &models.CompanionRule{}},
 		// 第二阶段：关联表（依赖基础表         ）
+		{"route_groups", &models.RouteGroup{}},
+		{"route_group_members", &models.RouteGroupMember{}},
 		{"route_stations", &models.RouteStation{}},
+		{"vehicle_assignments", &models.VehicleAssignment{}},
+		{"device_enrollment_tokens", &models.DeviceEnrollmentToken{}},
+		{"device_credentials", &models.DeviceCredential{}},
 		{"fares", &models.Fare{}},
+		{"zone_fares", &models.ZoneFare{}},
 		{"transfers", &models.Transfer{}},
+		{"card_products", &models.CardProduct{}},
+		{"campaigns", &models.Campaign{}},
+		{"fare_change_requests", &models.FareChangeRequest{}},
+		{"fare_audit_logs", &models.FareAuditLog{}},
 		// 第三阶段：交易表和扩展表（依赖基础表）
 		{"transactions", &models.Transaction{}},
+		{"journeys", &models.Journey{}},
+		{"campaign_redemptions", &models.CampaignRedemption{}},
 		{"monthly_aggregates", &models.MonthlyAggregate{}},
 		{"tap_events", &models.TapEvent{}},
+		{"device_nonces", &models.DeviceNonce{}},
+	}
+
+	// 登录会话改为只保存令牌摘要：删除保存明文令牌的旧会话与字段（已登录用户需重新登录）
+	if db.Migrator().HasColumn(&models.UserSession{}, "token") {
+		if err := db.Exec("DELETE FROM user_sessions").Error; err != nil {
+			return nil, fmt.Errorf("清理旧登录会话失败: %w", err)
+		}
+		if err := db.Migrator().DropColumn(&models.UserSession{}, "token"); err != nil {
+			return nil, fmt.Errorf("删除登录会话明文令牌字段失败: %w", err)
+		}
 	}
 
 	// 逐个迁移表
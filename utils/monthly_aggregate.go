@@ -9,7 +9,7 @@ import (
 )
 
 // GetMonthlyAggregate 获取卡片月度累计金额（从数据库）
-func GetMonthlyAggregate(db *gorm.DB, cardID string, month string) (float64, error) {
+func GetMonthlyAggregate(db *gorm.DB, cardID string, month string) (models.Money, error) {
 	if month == "" {
 		month = time.Now().Format("2006-01")
 	}
@@ -27,7 +27,7 @@ func GetMonthlyAggregate(db *gorm.DB, cardID string, month string) (float64, err
 }
 
 // IncrementMonthlyAggregate 增加卡片月度累计金额（使用数据库）
-func IncrementMonthlyAggregate(db *gorm.DB, cardID string, amount float64) error {
+func IncrementMonthlyAggregate(db *gorm.DB, cardID string, amount models.Money) error {
 	month := time.Now().Format("2006-01")
 
 	// 使用ON CONFLICT UPDATE或先查询后更新
@@ -54,6 +54,6 @@ func IncrementMonthlyAggregate(db *gorm.DB, cardID string, amount float64) error
 }
 
 // GetCurrentMonthAggregate 获取当前月份累计金额
-func GetCurrentMonthAggregate(db *gorm.DB, cardID string) (float64, error) {
+func GetCurrentMonthAggregate(db *gorm.DB, cardID string) (models.Money, error) {
 	return GetMonthlyAggregate(db, cardID, "")
 }
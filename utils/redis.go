@@ -1,9 +1,12 @@
 package utils
 
 import (
-	"context"
 	"TapTransit-backend/config"
+	"TapTransit-backend/models"
+	"context"
 	"fmt"
+	"math"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -33,29 +36,81 @@ func InitRedis(cfg *config.Config) (*redis.Client, error) {
 	return rdb, nil
 }
 
-// GetCardMonthlyAmount 获取卡片当月累计金额
-func GetCardMonthlyAmount(cardID string) (float64, error) {
+// cardMonthlyKey 卡片当月累计金额的键（以分为单位的整数）
+func cardMonthlyKey(cardID string) string {
+	return fmt.Sprintf("card:monthly_cents:%s:%s", time.Now().Format("2006-01"), cardID)
+}
+
+// legacyCardMonthlyKey 旧版卡片当月累计金额的键（以元为单位的浮点数，由IncrByFloat写入）
+func legacyCardMonthlyKey(cardID string) string {
+	return fmt.Sprintf("card:monthly:%s:%s", time.Now().Format("2006-01"), cardID)
+}
+
+// parseLegacyMonthlyAmount 解析旧版以元为单位的浮点金额
+func parseLegacyMonthlyAmount(value string) (models.Money, error) {
+	yuan, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("旧版月度累计金额格式错误: %s", value)
+	}
+	return models.Money(math.Round(yuan * 100)), nil
+}
+
+// migrateCardMonthlyAmount 将旧版浮点键的金额迁移到以分为单位的新键（新键已存在时不处理）
+func migrateCardMonthlyAmount(ctx context.Context, cardID string) error {
+	legacy, err := RedisClient.Get(ctx, legacyCardMonthlyKey(cardID)).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	amount, err := parseLegacyMonthlyAmount(legacy)
+	if err != nil {
+		return err
+	}
+	// SetNX保证并发迁移或新键已写入时不覆盖
+	return RedisClient.SetNX(ctx, cardMonthlyKey(cardID), amount.Cents(), 32*24*time.Hour).Err()
+}
+
+// GetCardMonthlyAmount 获取卡片当月累计金额（Redis中以分为单位的整数保存，兼容旧版浮点键）
+func GetCardMonthlyAmount(cardID string) (models.Money, error) {
 	ctx := context.Background()
-	key := fmt.Sprintf("card:monthly:%s:%s", time.Now().Format("2006-01"), cardID)
-	val, err := RedisClient.Get(ctx, key).Float64()
+	val, err := RedisClient.Get(ctx, cardMonthlyKey(cardID)).Int64()
 	if err == redis.Nil {
-		return 0, nil
+		if err := migrateCardMonthlyAmount(ctx, cardID); err != nil {
+			return 0, err
+		}
+		val, err = RedisClient.Get(ctx, cardMonthlyKey(cardID)).Int64()
+		if err == redis.Nil {
+			return 0, nil
+		}
 	}
-	return val, err
+	return models.Money(val), err
 }
 
 // SetCardMonthlyAmount 设置卡片当月累计金额
-func SetCardMonthlyAmount(cardID string, amount float64) error {
+func SetCardMonthlyAmount(cardID string, amount models.Money) error {
 	ctx := context.Background()
-	key := fmt.Sprintf("card:monthly:%s:%s", time.Now().Format("2006-01"), cardID)
-	return RedisClient.Set(ctx, key, amount, 32*24*time.Hour).Err() // 保存32天
+	return RedisClient.Set(ctx, cardMonthlyKey(cardID), amount.Cents(), 32*24*time.Hour).Err() // 保存32天
 }
 
-// IncrementCardMonthlyAmount 增加卡片当月累计金额
-func IncrementCardMonthlyAmount(cardID string, amount float64) error {
+// IncrementCardMonthlyAmount 增加卡片当月累计金额（先迁移旧版浮点键，避免丢失已累计的金额）
+func IncrementCardMonthlyAmount(cardID string, amount models.Money) error {
 	ctx := context.Background()
-	key := fmt.Sprintf("card:monthly:%s:%s", time.Now().Format("2006-01"), cardID)
-	return RedisClient.IncrByFloat(ctx, key, amount).Err()
+	key := cardMonthlyKey(cardID)
+	exists, err := RedisClient.Exists(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		if err := migrateCardMonthlyAmount(ctx, cardID); err != nil {
+			return err
+		}
+	}
+	if err := RedisClient.IncrBy(ctx, key, amount.Cents()).Err(); err != nil {
+		return err
+	}
+	return RedisClient.Expire(ctx, key, 32*24*time.Hour).Err()
 }
 
 // GetCardOnboardInfo 获取卡片最近一次上车信息
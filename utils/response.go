@@ -11,6 +11,7 @@ type Response struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Message string      `json:"message,omitempty"`
+	Code    string      `json:"code,omitempty"` // 错误代码（需要客户端区分处理的错误才返回）
 }
 
 // Success 成功响应
@@ -38,6 +39,15 @@ func Error(c *gin.Context, code int, message string) {
 	})
 }
 
+// ErrorWithCode 错误响应（带错误代码）
+func ErrorWithCode(c *gin.Context, status int, code string, message string) {
+	c.JSON(status, Response{
+		Success: false,
+		Message: message,
+		Code:    code,
+	})
+}
+
 // BadRequest 400错误
 func BadRequest(c *gin.Context, message string) {
 	Error(c, http.StatusBadRequest, message)
@@ -48,6 +58,11 @@ func Unauthorized(c *gin.Context, message string) {
 	Error(c, http.StatusUnauthorized, message)
 }
 
+// Forbidden 403错误
+func Forbidden(c *gin.Context, message string) {
+	Error(c, http.StatusForbidden, message)
+}
+
 // NotFound 404错误
 func NotFound(c *gin.Context, message string) {
 	Error(c, http.StatusNotFound, message)